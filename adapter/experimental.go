@@ -6,6 +6,8 @@ import (
 	"encoding/binary"
 	"time"
 
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json/badoption"
 	"github.com/sagernet/sing/common/varbin"
 )
 
@@ -52,6 +54,17 @@ type CacheFile interface {
 	StoreGroupExpand(group string, expand bool) error
 	LoadRuleSet(tag string) *SavedBinary
 	SaveRuleSet(tag string, set *SavedBinary) error
+	StickySessionStore
+}
+
+type StickySessionStore interface {
+	LoadStickySessions(group string) map[string]SavedStickySession
+	StoreStickySession(group string, key string, session SavedStickySession) error
+}
+
+type SavedStickySession struct {
+	Outbound  string
+	ExpiresAt time.Time
 }
 
 type SavedBinary struct {
@@ -116,6 +129,98 @@ type URLTestGroup interface {
 	URLTest(ctx context.Context) (map[string]uint16, error)
 }
 
+type OutlierDetectionGroup interface {
+	OutboundGroup
+	OutlierStatus() map[string]OutlierStatus
+}
+
+type OutlierStatus struct {
+	Ejected      bool      `json:"ejected"`
+	EjectedUntil time.Time `json:"ejected_until,omitempty"`
+	Ejections    uint32    `json:"ejections"`
+	Requests     uint32    `json:"requests"`
+	Failures     uint32    `json:"failures"`
+}
+
+type LoadBalanceGroup interface {
+	OutboundGroup
+	LoadBalanceStatus() *LoadBalanceStatus
+	Tune(options option.LoadBalanceTuningOptions) error
+}
+
+type LoadBalanceStatus struct {
+	Strategy          string                    `json:"strategy"`
+	ActiveTier        string                    `json:"active_tier"`
+	PrimaryCandidates []string                  `json:"primary_candidates"`
+	BackupCandidates  []string                  `json:"backup_candidates"`
+	Hysteresis        LoadBalanceHysteresis     `json:"hysteresis"`
+	RingMembers       []string                  `json:"ring_members,omitempty"`
+	Members           []LoadBalanceMemberStatus `json:"members"`
+}
+
+type LoadBalanceHysteresis struct {
+	PrimaryFailures          uint32             `json:"primary_failures"`
+	PrimaryFailuresThreshold uint32             `json:"primary_failures_threshold"`
+	BackupActivatedAt        time.Time          `json:"backup_activated_at,omitempty"`
+	BackupHoldTime           badoption.Duration `json:"backup_hold_time"`
+}
+
+type LoadBalanceMemberStatus struct {
+	Name        string `json:"name"`
+	Tier        string `json:"tier"`
+	Candidate   bool   `json:"candidate"`
+	Delay       uint16 `json:"delay,omitempty"`
+	Connections int64  `json:"connections"`
+	Upload      int64  `json:"upload"`
+	Download    int64  `json:"download"`
+}
+
+type ConnectionPoolOutbound interface {
+	Outbound
+	// ConnectionPool returns nil if connection_pool is not configured
+	ConnectionPool() ConnectionPool
+}
+
+type ConnectionPool interface {
+	Status() ConnectionPoolStatus
+	Reset()
+}
+
+type ConnectionPoolStatus struct {
+	EnsureIdle        int64 `json:"ensure_idle"`
+	Idle              int64 `json:"idle"`
+	InUse             int64 `json:"in_use"`
+	Dialing           int64 `json:"dialing"`
+	Hits              int64 `json:"hits"`
+	Misses            int64 `json:"misses"`
+	Created           int64 `json:"created"`
+	CreateFailures    int64 `json:"create_failures"`
+	ClosedIdleTimeout int64 `json:"closed_idle_timeout"`
+	ClosedLifetime    int64 `json:"closed_lifetime"`
+	HeartbeatFailures int64 `json:"heartbeat_failures"`
+}
+
+type RouterInbound interface {
+	Inbound
+	RouterMetrics() *RouterMetrics
+}
+
+type RouterMetrics struct {
+	Routes   []RouteMetrics `json:"routes"`
+	Fallback RouteMetrics   `json:"fallback"`
+}
+
+type RouteMetrics struct {
+	Name     string `json:"name"`
+	Target   string `json:"target,omitempty"`
+	Requests int64  `json:"requests"`
+	Active   int64  `json:"active"`
+	Rejected int64  `json:"rejected"`
+	Failed   int64  `json:"failed"`
+	Upload   int64  `json:"upload"`
+	Download int64  `json:"download"`
+}
+
 func OutboundTag(detour Outbound) string {
 	if group, isGroup := detour.(OutboundGroup); isGroup {
 		return group.Now()
@@ -2,6 +2,7 @@ package adapter
 
 import (
 	"context"
+	"net/http"
 	"net/netip"
 	"time"
 
@@ -23,6 +24,15 @@ type TCPInjectableInbound interface {
 	ConnectionHandlerEx
 }
 
+// HTTPInjectableInbound is an inbound whose transport can serve individual
+// HTTP requests, so HTTP/2 and HTTP/3 servers can dispatch to it per request.
+// HTTPHandler reports false if requests of the HTTP major version can not be
+// served.
+type HTTPInjectableInbound interface {
+	Inbound
+	HTTPHandler(protoMajor int) (http.Handler, bool)
+}
+
 type UDPInjectableInbound interface {
 	Inbound
 	PacketConnectionHandlerEx
@@ -3,6 +3,7 @@ package adapter
 import (
 	"context"
 	"net"
+	"net/http"
 
 	N "github.com/sagernet/sing/common/network"
 )
@@ -14,6 +15,14 @@ type V2RayServerTransport interface {
 	Close() error
 }
 
+// V2RayHTTPServerTransport is a server transport that can serve requests
+// accepted by another HTTP server, without hijacking their connection
+type V2RayHTTPServerTransport interface {
+	V2RayServerTransport
+	http.Handler
+	SupportsHTTPVersion(protoMajor int) bool
+}
+
 type V2RayServerTransportHandler interface {
 	N.TCPConnectionHandlerEx
 }
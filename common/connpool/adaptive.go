@@ -0,0 +1,81 @@
+package connpool
+
+import "time"
+
+// adaptiveBuckets is the number of buckets the sliding window is split into.
+// The ensured idle count is adjusted each time the window slides by a bucket.
+const adaptiveBuckets = 10
+
+// minAdaptiveWindow keeps buckets at least a second long
+const minAdaptiveWindow = adaptiveBuckets * time.Second
+
+// AdaptiveConfig sizes the ensured idle connections from observed demand
+type AdaptiveConfig struct {
+	Min    int
+	Max    int
+	Window time.Duration
+}
+
+// adaptiveSizing counts connections taken from the pool and pool misses over
+// a sliding window. It is protected by the access of the pool.
+type adaptiveSizing struct {
+	min    int
+	max    int
+	bucket time.Duration
+	takes  [adaptiveBuckets]int
+	misses [adaptiveBuckets]int
+	index  int
+}
+
+func newAdaptiveSizing(config AdaptiveConfig) *adaptiveSizing {
+	window := config.Window
+	if window == 0 {
+		window = 5 * time.Minute
+	}
+	return &adaptiveSizing{
+		min:    config.Min,
+		max:    config.Max,
+		bucket: window / adaptiveBuckets,
+	}
+}
+
+func (a *adaptiveSizing) clamp(ensureIdle int) int {
+	if ensureIdle < a.min {
+		return a.min
+	}
+	if ensureIdle > a.max {
+		return a.max
+	}
+	return ensureIdle
+}
+
+func (a *adaptiveSizing) record(miss bool) {
+	a.takes[a.index]++
+	if miss {
+		a.misses[a.index]++
+	}
+}
+
+// next slides the window by a bucket and returns the new ensured idle count.
+// The pool should hold as many connections as the busiest bucket of the
+// window took. Misses of the last bucket grow the count right away, while it
+// shrinks by at most one per bucket to avoid oscillating.
+func (a *adaptiveSizing) next(ensureIdle int) int {
+	var peak int
+	for _, takes := range a.takes {
+		if takes > peak {
+			peak = takes
+		}
+	}
+	target := peak
+	if lastMisses := a.misses[a.index]; lastMisses > 0 && ensureIdle+lastMisses > target {
+		target = ensureIdle + lastMisses
+	}
+	if target < ensureIdle-1 {
+		target = ensureIdle - 1
+	}
+	a.index = (a.index + 1) % adaptiveBuckets
+	a.takes[a.index] = 0
+	a.misses[a.index] = 0
+	return a.clamp(target)
+}
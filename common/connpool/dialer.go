@@ -0,0 +1,92 @@
+package connpool
+
+import (
+	"context"
+	"net"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// New creates the pool configured by the connection_pool dial field, or
+// returns nil if it is not set. createConn dials the server, including TLS
+// and transport handshakes.
+func New(ctx context.Context, logger logger.ContextLogger, options option.DialerOptions, createConn func(ctx context.Context) (net.Conn, error)) (*Pool, error) {
+	poolOptions := options.ConnectionPool
+	if poolOptions == nil {
+		return nil, nil
+	}
+	// A TCP Fast Open connection is only established by its first write,
+	// which would defeat pre-dialing
+	if options.TCPFastOpen {
+		return nil, E.New("tcp_fast_open is not supported with connection_pool")
+	}
+	var adaptive *AdaptiveConfig
+	if adaptiveOptions := poolOptions.Adaptive; adaptiveOptions != nil {
+		if adaptiveOptions.MaxEnsureIdleSession <= 0 {
+			return nil, E.New("connection_pool: missing adaptive.max_ensure_idle_session")
+		}
+		if adaptiveOptions.MinEnsureIdleSession > adaptiveOptions.MaxEnsureIdleSession {
+			return nil, E.New("connection_pool: adaptive.min_ensure_idle_session is greater than adaptive.max_ensure_idle_session")
+		}
+		if window := adaptiveOptions.Window.Build(); window != 0 && window < minAdaptiveWindow {
+			return nil, E.New("connection_pool: adaptive.window must be at least ", minAdaptiveWindow)
+		}
+		adaptive = &AdaptiveConfig{
+			Min:    adaptiveOptions.MinEnsureIdleSession,
+			Max:    adaptiveOptions.MaxEnsureIdleSession,
+			Window: adaptiveOptions.Window.Build(),
+		}
+	}
+	return NewWithConfig(ctx, Config{
+		EnsureIdle:       poolOptions.EnsureIdleSession,
+		EnsureCreateRate: poolOptions.EnsureIdleSessionCreateRate,
+		MinIdle:          poolOptions.MinIdleSession,
+		MinIdleForAge:    poolOptions.MinIdleSessionForAge,
+		CheckInterval:    poolOptions.IdleSessionCheckInterval.Build(),
+		IdleTimeout:      poolOptions.IdleSessionTimeout.Build(),
+		MaxLifetime:      poolOptions.MaxConnectionLifetime.Build(),
+		LifetimeJitter:   poolOptions.ConnectionLifetimeJitter.Build(),
+		Heartbeat:        poolOptions.Heartbeat.Build(),
+		Adaptive:         adaptive,
+		CreateConn:       createConn,
+		Logger:           logger,
+	}), nil
+}
+
+var _ N.Dialer = (*Dialer)(nil)
+
+// Dialer serves TCP connections to the server from a pool, for outbounds
+// that dial through a N.Dialer. Other dials go to the upstream dialer.
+type Dialer struct {
+	N.Dialer
+	server M.Socksaddr
+	pool   *Pool
+}
+
+// NewDialer wraps upstream with the pool configured by the connection_pool
+// dial field. The pool is nil and upstream is returned as is if it is not
+// set.
+func NewDialer(ctx context.Context, logger logger.ContextLogger, upstream N.Dialer, server M.Socksaddr, options option.DialerOptions) (N.Dialer, *Pool, error) {
+	pool, err := New(ctx, logger, options, func(ctx context.Context) (net.Conn, error) {
+		return upstream.DialContext(ctx, N.NetworkTCP, server)
+	})
+	if err != nil || pool == nil {
+		return upstream, nil, err
+	}
+	return &Dialer{
+		Dialer: upstream,
+		server: server,
+		pool:   pool,
+	}, pool, nil
+}
+
+func (d *Dialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	if N.NetworkName(network) == N.NetworkTCP && destination == d.server {
+		return d.pool.GetConn(ctx)
+	}
+	return d.Dialer.DialContext(ctx, network, destination)
+}
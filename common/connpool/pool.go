@@ -0,0 +1,536 @@
+package connpool
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/common/x/list"
+	"github.com/sagernet/sing/service"
+	"github.com/sagernet/sing/service/pause"
+)
+
+// probeTimeout bounds the read used by the heartbeat to check whether an idle
+// connection was closed by the server
+const probeTimeout = 50 * time.Millisecond
+
+// Config contains configuration for the connection pool
+type Config struct {
+	EnsureIdle       int
+	EnsureCreateRate int
+	MinIdle          int
+	MinIdleForAge    int
+	CheckInterval    time.Duration
+	IdleTimeout      time.Duration
+	MaxLifetime      time.Duration
+	LifetimeJitter   time.Duration
+	Heartbeat        time.Duration
+	Adaptive         *AdaptiveConfig
+	CreateConn       func(ctx context.Context) (net.Conn, error)
+	Logger           logger.ContextLogger
+}
+
+var _ adapter.ConnectionPool = (*Pool)(nil)
+
+// Pool keeps pre-dialed connections to an outbound server, so that new
+// connections skip the TCP and TLS handshakes. A connection taken from the
+// pool belongs to the caller and never returns to it: the proxy protocol
+// handshake has been written to it.
+type Pool struct {
+	// Config
+	ensureIdle       int
+	ensureCreateRate int
+	minIdle          int
+	minIdleForAge    int
+	checkInterval    time.Duration
+	idleTimeout      time.Duration
+	maxLifetime      time.Duration
+	lifetimeJitter   time.Duration
+	heartbeat        time.Duration
+	adaptive         *adaptiveSizing // Adjusts ensureIdle if not nil
+	createConn       func(ctx context.Context) (net.Conn, error)
+	logger           logger.ContextLogger
+	pauseManager     pause.Manager
+	pauseCallback    *list.Element[pause.Callback]
+
+	// State
+	access    sync.Mutex
+	idleConns []*idleConn
+	creating  int
+	epoch     uint64 // Incremented by Reset, discards connections dialed before it
+	closed    bool
+	inUse     int64
+	metrics   poolMetrics
+
+	// Lifecycle
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// poolMetrics are counters since the pool was created, protected by access
+type poolMetrics struct {
+	hits              int64
+	misses            int64
+	created           int64
+	createFailures    int64
+	closedIdleTimeout int64
+	closedLifetime    int64
+	heartbeatFailures int64
+}
+
+// idleConn is a pre-dialed connection waiting in the pool
+type idleConn struct {
+	conn      net.Conn
+	createdAt time.Time
+	expiresAt time.Time // Lifetime deadline (with jitter), zero if unlimited
+	probing   bool      // Being checked by the heartbeat, not available
+}
+
+// NewWithConfig creates a connection pool and starts pre-dialing
+func NewWithConfig(ctx context.Context, config Config) *Pool {
+	poolCtx, cancel := context.WithCancel(ctx)
+	pool := &Pool{
+		ensureIdle:       config.EnsureIdle,
+		ensureCreateRate: config.EnsureCreateRate,
+		minIdle:          config.MinIdle,
+		minIdleForAge:    config.MinIdleForAge,
+		checkInterval:    config.CheckInterval,
+		idleTimeout:      config.IdleTimeout,
+		maxLifetime:      config.MaxLifetime,
+		lifetimeJitter:   config.LifetimeJitter,
+		heartbeat:        config.Heartbeat,
+		createConn:       config.CreateConn,
+		logger:           config.Logger,
+		pauseManager:     service.FromContext[pause.Manager](ctx),
+		ctx:              poolCtx,
+		cancel:           cancel,
+	}
+	if pool.checkInterval == 0 {
+		pool.checkInterval = 30 * time.Second
+	}
+	if pool.idleTimeout == 0 {
+		pool.idleTimeout = 5 * time.Minute
+	}
+	if pool.ensureCreateRate == 0 {
+		pool.ensureCreateRate = 1
+	}
+	if config.Adaptive != nil {
+		pool.adaptive = newAdaptiveSizing(*config.Adaptive)
+		pool.ensureIdle = pool.adaptive.clamp(pool.ensureIdle)
+	}
+	if pool.pauseManager != nil {
+		pool.pauseCallback = pool.pauseManager.RegisterCallback(pool.onPauseEvent)
+	}
+	go pool.loopMaintenance()
+	pool.fill()
+	return pool
+}
+
+// onPauseEvent drains the pool when the device or network is paused, since
+// connections do not survive the pause, and warms it up again on wake
+func (p *Pool) onPauseEvent(event int) {
+	switch event {
+	case pause.EventDevicePaused, pause.EventNetworkPause:
+		p.drain()
+	case pause.EventDeviceWake, pause.EventNetworkWake:
+		p.Reset()
+	}
+}
+
+func (p *Pool) isPaused() bool {
+	return p.pauseManager != nil && p.pauseManager.IsPaused()
+}
+
+// GetConn takes a pre-dialed connection from the pool, or dials a new one if
+// none is idle. Taking a connection triggers dialing a replacement.
+func (p *Pool) GetConn(ctx context.Context) (net.Conn, error) {
+	p.access.Lock()
+	if p.closed {
+		p.access.Unlock()
+		return nil, net.ErrClosed
+	}
+	now := time.Now()
+	var conn net.Conn
+	// Prefer the most recently dialed connection, which is least likely to
+	// have been dropped by the server or a middlebox
+	for i := len(p.idleConns) - 1; i >= 0; i-- {
+		pooled := p.idleConns[i]
+		if pooled.probing {
+			continue
+		}
+		p.idleConns = append(p.idleConns[:i], p.idleConns[i+1:]...)
+		if pooled.expired(now) {
+			pooled.conn.Close()
+			p.metrics.closedLifetime++
+			continue
+		}
+		conn = pooled.conn
+		break
+	}
+	if conn != nil {
+		p.metrics.hits++
+	} else {
+		p.metrics.misses++
+	}
+	if p.adaptive != nil {
+		p.adaptive.record(conn == nil)
+	}
+	p.access.Unlock()
+	p.fill()
+	if conn != nil {
+		// Clear any deadline left by the heartbeat
+		err := conn.SetReadDeadline(time.Time{})
+		if err != nil {
+			conn.Close()
+			conn = nil
+		}
+	}
+	if conn == nil {
+		var err error
+		conn, err = p.createConn(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+	p.access.Lock()
+	p.inUse++
+	p.access.Unlock()
+	return &usedConn{Conn: conn, pool: p}, nil
+}
+
+// usedConn counts a connection taken from the pool as in use until closed
+type usedConn struct {
+	net.Conn
+	pool      *Pool
+	closeOnce sync.Once
+}
+
+func (c *usedConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.pool.access.Lock()
+		c.pool.inUse--
+		c.pool.access.Unlock()
+	})
+	return c.Conn.Close()
+}
+
+func (c *usedConn) Upstream() any {
+	return c.Conn
+}
+
+func (c *usedConn) ReaderReplaceable() bool {
+	return true
+}
+
+func (c *usedConn) WriterReplaceable() bool {
+	return true
+}
+
+func (c *idleConn) expired(now time.Time) bool {
+	return !c.expiresAt.IsZero() && now.After(c.expiresAt)
+}
+
+// calculateExpiration calculates the expiration time with jitter
+func (p *Pool) calculateExpiration(createdAt time.Time) time.Time {
+	if p.maxLifetime == 0 {
+		return time.Time{}
+	}
+	lifetime := p.maxLifetime
+	if p.lifetimeJitter > 0 {
+		// Add random jitter: [-jitter, +jitter]
+		lifetime += time.Duration(rand.Int63n(int64(p.lifetimeJitter)*2) - int64(p.lifetimeJitter))
+	}
+	return createdAt.Add(lifetime)
+}
+
+// fill starts dialing connections until the idle and pending connections
+// reach ensure_idle_session, with at most ensure_idle_session_create_rate
+// dials in flight. Nothing is dialed while the device or network is paused.
+func (p *Pool) fill() {
+	if p.isPaused() {
+		return
+	}
+	p.access.Lock()
+	defer p.access.Unlock()
+	if p.closed {
+		return
+	}
+	needed := p.ensureIdle - len(p.idleConns) - p.creating
+	if available := p.ensureCreateRate - p.creating; needed > available {
+		needed = available
+	}
+	for i := 0; i < needed; i++ {
+		p.creating++
+		go p.createIdleConn(p.epoch)
+	}
+}
+
+func (p *Pool) createIdleConn(epoch uint64) {
+	ctx, cancel := context.WithTimeout(p.ctx, C.TCPTimeout)
+	conn, err := p.createConn(ctx)
+	cancel()
+	p.access.Lock()
+	p.creating--
+	if err != nil {
+		p.metrics.createFailures++
+		p.access.Unlock()
+		if !p.isClosed() {
+			p.logger.Warn("failed to create pool connection: ", err)
+		}
+		return
+	}
+	if p.closed {
+		p.access.Unlock()
+		conn.Close()
+		return
+	}
+	if p.epoch != epoch {
+		p.access.Unlock()
+		conn.Close()
+		p.fill()
+		return
+	}
+	now := time.Now()
+	p.metrics.created++
+	p.idleConns = append(p.idleConns, &idleConn{
+		conn:      conn,
+		createdAt: now,
+		expiresAt: p.calculateExpiration(now),
+	})
+	p.access.Unlock()
+	// A dial slot is free again, continue warming up
+	p.fill()
+}
+
+func (p *Pool) isClosed() bool {
+	p.access.Lock()
+	defer p.access.Unlock()
+	return p.closed
+}
+
+func (p *Pool) loopMaintenance() {
+	checkTicker := time.NewTicker(p.checkInterval)
+	defer checkTicker.Stop()
+	var heartbeat <-chan time.Time
+	if p.heartbeat > 0 {
+		heartbeatTicker := time.NewTicker(p.heartbeat)
+		defer heartbeatTicker.Stop()
+		heartbeat = heartbeatTicker.C
+	}
+	var adapt <-chan time.Time
+	if p.adaptive != nil {
+		adaptTicker := time.NewTicker(p.adaptive.bucket)
+		defer adaptTicker.Stop()
+		adapt = adaptTicker.C
+	}
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-checkTicker.C:
+			p.performMaintenance()
+		case <-heartbeat:
+			p.performHeartbeat()
+		case <-adapt:
+			p.performAdaptation()
+		}
+	}
+}
+
+// performMaintenance closes connections idle for longer than
+// idle_session_timeout or older than their lifetime, keeping at least
+// min_idle_session and min_idle_session_for_age connections, then refills
+// the pool
+func (p *Pool) performMaintenance() {
+	p.access.Lock()
+	if p.closed {
+		p.access.Unlock()
+		return
+	}
+	minIdleForAge := p.minIdleForAge
+	if minIdleForAge == 0 {
+		minIdleForAge = p.minIdle
+	}
+	now := time.Now()
+	// Oldest connections come first and are closed first
+	for i := 0; i < len(p.idleConns); i++ {
+		pooled := p.idleConns[i]
+		if pooled.probing {
+			continue
+		}
+		var closeConn bool
+		if p.idleTimeout > 0 && now.Sub(pooled.createdAt) > p.idleTimeout && len(p.idleConns) > p.minIdle {
+			closeConn = true
+			p.metrics.closedIdleTimeout++
+		} else if pooled.expired(now) && len(p.idleConns) > minIdleForAge {
+			closeConn = true
+			p.metrics.closedLifetime++
+		}
+		if closeConn {
+			pooled.conn.Close()
+			p.idleConns = append(p.idleConns[:i], p.idleConns[i+1:]...)
+			i--
+		}
+	}
+	p.access.Unlock()
+	p.fill()
+}
+
+// performAdaptation resizes the pool from the demand of the sliding window.
+// When it shrinks, the oldest idle connections above the new size are closed.
+func (p *Pool) performAdaptation() {
+	p.access.Lock()
+	if p.closed {
+		p.access.Unlock()
+		return
+	}
+	ensureIdle := p.adaptive.next(p.ensureIdle)
+	if ensureIdle != p.ensureIdle {
+		p.logger.Debug("connection pool size adjusted from ", p.ensureIdle, " to ", ensureIdle)
+		p.ensureIdle = ensureIdle
+	}
+	for i := 0; i < len(p.idleConns) && len(p.idleConns) > ensureIdle && len(p.idleConns) > p.minIdle; i++ {
+		pooled := p.idleConns[i]
+		if pooled.probing {
+			continue
+		}
+		pooled.conn.Close()
+		p.idleConns = append(p.idleConns[:i], p.idleConns[i+1:]...)
+		i--
+	}
+	p.access.Unlock()
+	p.fill()
+}
+
+// performHeartbeat checks that idle connections are still open. Servers do
+// not send anything before the client's request, so a read that does not
+// time out means the connection was closed or is unusable.
+func (p *Pool) performHeartbeat() {
+	if p.isPaused() {
+		return
+	}
+	p.access.Lock()
+	if p.closed {
+		p.access.Unlock()
+		return
+	}
+	var probing []*idleConn
+	for _, pooled := range p.idleConns {
+		if !pooled.probing {
+			pooled.probing = true
+			probing = append(probing, pooled)
+		}
+	}
+	p.access.Unlock()
+	var wg sync.WaitGroup
+	for _, pooled := range probing {
+		wg.Add(1)
+		go func(pooled *idleConn) {
+			defer wg.Done()
+			alive := probeConn(pooled.conn)
+			p.access.Lock()
+			pooled.probing = false
+			if !alive && p.removeIdleConn(pooled) {
+				pooled.conn.Close()
+				p.metrics.heartbeatFailures++
+				p.logger.Debug("closed dead pool connection")
+			}
+			p.access.Unlock()
+		}(pooled)
+	}
+	wg.Wait()
+	p.fill()
+}
+
+func probeConn(conn net.Conn) bool {
+	err := conn.SetReadDeadline(time.Now().Add(probeTimeout))
+	if err != nil {
+		// Deadlines are not supported by the transport, nothing to check
+		return true
+	}
+	var buffer [1]byte
+	n, err := conn.Read(buffer[:])
+	if n > 0 || !E.IsTimeout(err) {
+		return false
+	}
+	return conn.SetReadDeadline(time.Time{}) == nil
+}
+
+// removeIdleConn removes pooled from the idle connections, it must be called
+// with access held
+func (p *Pool) removeIdleConn(pooled *idleConn) bool {
+	for i, it := range p.idleConns {
+		if it == pooled {
+			p.idleConns = append(p.idleConns[:i], p.idleConns[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Pool) closeIdleConns() {
+	for _, pooled := range p.idleConns {
+		pooled.conn.Close()
+	}
+	p.idleConns = nil
+}
+
+// Status returns the current size of the pool and its counters
+func (p *Pool) Status() adapter.ConnectionPoolStatus {
+	p.access.Lock()
+	defer p.access.Unlock()
+	return adapter.ConnectionPoolStatus{
+		EnsureIdle:        int64(p.ensureIdle),
+		Idle:              int64(len(p.idleConns)),
+		InUse:             p.inUse,
+		Dialing:           int64(p.creating),
+		Hits:              p.metrics.hits,
+		Misses:            p.metrics.misses,
+		Created:           p.metrics.created,
+		CreateFailures:    p.metrics.createFailures,
+		ClosedIdleTimeout: p.metrics.closedIdleTimeout,
+		ClosedLifetime:    p.metrics.closedLifetime,
+		HeartbeatFailures: p.metrics.heartbeatFailures,
+	}
+}
+
+// Reset closes all idle connections and dials new ones, for example after
+// the network changed. Connections being dialed are discarded.
+func (p *Pool) Reset() {
+	p.drain()
+	p.fill()
+}
+
+// drain closes all idle connections and discards connections being dialed
+func (p *Pool) drain() {
+	p.access.Lock()
+	p.epoch++
+	p.closeIdleConns()
+	p.access.Unlock()
+}
+
+// Close closes all idle connections and stops the pool. Connections already
+// taken are not affected.
+func (p *Pool) Close() error {
+	p.access.Lock()
+	if p.closed {
+		p.access.Unlock()
+		return nil
+	}
+	p.closed = true
+	p.cancel()
+	p.closeIdleConns()
+	p.access.Unlock()
+	// Pause callbacks are called with the lock of the manager held and take
+	// access, so unregister without holding it
+	if p.pauseCallback != nil {
+		p.pauseManager.UnregisterCallback(p.pauseCallback)
+	}
+	return nil
+}
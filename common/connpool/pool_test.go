@@ -0,0 +1,1065 @@
+package connpool
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json/badoption"
+	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/service"
+	"github.com/sagernet/sing/service/pause"
+)
+
+// mockConn implements net.Conn for testing
+type mockConn struct {
+	net.Conn
+	closed              atomic.Bool
+	readCalls           atomic.Int32
+	writeCalls          atomic.Int32
+	closeCalls          atomic.Int32
+	readDeadline        atomic.Value
+	closeFunc           func() error
+	readFunc            func(b []byte) (int, error)
+	setReadDeadlineFunc func(t time.Time) error
+}
+
+func newMockConn() *mockConn {
+	m := &mockConn{}
+	m.closeFunc = func() error {
+		m.closeCalls.Add(1)
+		m.closed.Store(true)
+		return nil
+	}
+	m.setReadDeadlineFunc = func(t time.Time) error {
+		m.readDeadline.Store(t)
+		return nil
+	}
+	return m
+}
+
+func (m *mockConn) Read(b []byte) (n int, err error) {
+	m.readCalls.Add(1)
+	if m.readFunc != nil {
+		return m.readFunc(b)
+	}
+	if m.closed.Load() {
+		return 0, io.EOF
+	}
+	time.Sleep(10 * time.Millisecond) // Simulate some delay
+	return len(b), nil
+}
+
+func (m *mockConn) Write(b []byte) (n int, err error) {
+	m.writeCalls.Add(1)
+	if m.closed.Load() {
+		return 0, net.ErrClosed
+	}
+	return len(b), nil
+}
+
+func (m *mockConn) Close() error {
+	return m.closeFunc()
+}
+
+func (m *mockConn) LocalAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 12345}
+}
+
+func (m *mockConn) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 54321}
+}
+
+func (m *mockConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+func (m *mockConn) SetReadDeadline(t time.Time) error {
+	return m.setReadDeadlineFunc(t)
+}
+
+func (m *mockConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+// mockLogger is a no-op logger for testing
+type mockLogger struct{}
+
+func (m *mockLogger) Trace(args ...any)                             {}
+func (m *mockLogger) Debug(args ...any)                             {}
+func (m *mockLogger) Info(args ...any)                              {}
+func (m *mockLogger) Warn(args ...any)                              {}
+func (m *mockLogger) Error(args ...any)                             {}
+func (m *mockLogger) Fatal(args ...any)                             {}
+func (m *mockLogger) Panic(args ...any)                             {}
+func (m *mockLogger) TraceContext(ctx context.Context, args ...any) {}
+func (m *mockLogger) DebugContext(ctx context.Context, args ...any) {}
+func (m *mockLogger) InfoContext(ctx context.Context, args ...any)  {}
+func (m *mockLogger) WarnContext(ctx context.Context, args ...any)  {}
+func (m *mockLogger) ErrorContext(ctx context.Context, args ...any) {}
+func (m *mockLogger) FatalContext(ctx context.Context, args ...any) {}
+func (m *mockLogger) PanicContext(ctx context.Context, args ...any) {}
+
+var _ logger.ContextLogger = (*mockLogger)(nil)
+
+// blockingRead makes Read wait for the read deadline, like an open connection
+// the server sends nothing on
+func (m *mockConn) blockingRead(b []byte) (int, error) {
+	deadline, _ := m.readDeadline.Load().(time.Time)
+	if deadline.IsZero() {
+		deadline = time.Now().Add(time.Second)
+	}
+	time.Sleep(time.Until(deadline))
+	if m.closed.Load() {
+		return 0, io.EOF
+	}
+	return 0, os.ErrDeadlineExceeded
+}
+
+// idleCount returns the number of idle connections in the pool
+func idleCount(pool *Pool) int {
+	pool.access.Lock()
+	defer pool.access.Unlock()
+	return len(pool.idleConns)
+}
+
+// TestConnectionPool_GetConn tests that taken connections are not reused
+func TestConnectionPool_GetConn(t *testing.T) {
+	ctx := context.Background()
+	createCount := atomic.Int32{}
+	var conns []*mockConn
+
+	config := Config{
+		EnsureIdle: 0, // Don't pre-create
+		CreateConn: func(ctx context.Context) (net.Conn, error) {
+			createCount.Add(1)
+			mc := newMockConn()
+			conns = append(conns, mc)
+			return mc, nil
+		},
+		Logger: &mockLogger{},
+	}
+
+	pool := NewWithConfig(ctx, config)
+	defer pool.Close()
+
+	// First GetConn should create a new connection
+	conn1, err := pool.GetConn(ctx)
+	if err != nil {
+		t.Fatalf("GetConn failed: %v", err)
+	}
+	if createCount.Load() != 1 {
+		t.Errorf("Expected 1 connection created, got %d", createCount.Load())
+	}
+
+	// Close closes the connection, which carries a finished session
+	conn1.Close()
+	if !conns[0].closed.Load() {
+		t.Error("Expected taken connection to be closed")
+	}
+
+	// Second GetConn should create another connection
+	conn2, err := pool.GetConn(ctx)
+	if err != nil {
+		t.Fatalf("GetConn failed: %v", err)
+	}
+	if createCount.Load() != 2 {
+		t.Errorf("Expected 2 connections created, got %d", createCount.Load())
+	}
+
+	conn2.Close()
+}
+
+// TestConnectionPool_Concurrency tests concurrent access to the pool
+func TestConnectionPool_Concurrency(t *testing.T) {
+	ctx := context.Background()
+	createCount := atomic.Int32{}
+
+	config := Config{
+		EnsureIdle:       2,
+		EnsureCreateRate: 2,
+		CreateConn: func(ctx context.Context) (net.Conn, error) {
+			createCount.Add(1)
+			time.Sleep(10 * time.Millisecond) // Simulate slow connection
+			return newMockConn(), nil
+		},
+		Logger: &mockLogger{},
+	}
+
+	pool := NewWithConfig(ctx, config)
+	defer pool.Close()
+
+	const numGoroutines = 10
+	var wg sync.WaitGroup
+	wg.Add(numGoroutines)
+
+	for i := 0; i < numGoroutines; i++ {
+		go func() {
+			defer wg.Done()
+
+			conn, err := pool.GetConn(ctx)
+			if err != nil {
+				t.Errorf("GetConn failed: %v", err)
+				return
+			}
+
+			// Simulate some work
+			time.Sleep(50 * time.Millisecond)
+
+			conn.Close()
+		}()
+	}
+
+	wg.Wait()
+
+	// Every goroutine got its own connection
+	if createCount.Load() < numGoroutines {
+		t.Errorf("Expected at least %d connections created, got %d", numGoroutines, createCount.Load())
+	}
+}
+
+// TestConnectionPool_IdleTimeout tests idle connection cleanup
+func TestConnectionPool_IdleTimeout(t *testing.T) {
+	ctx := context.Background()
+	createCount := atomic.Int32{}
+	closeCount := atomic.Int32{}
+
+	config := Config{
+		EnsureIdle:    1,
+		MinIdle:       0,
+		IdleTimeout:   100 * time.Millisecond,
+		CheckInterval: 50 * time.Millisecond,
+		CreateConn: func(ctx context.Context) (net.Conn, error) {
+			createCount.Add(1)
+			mc := newMockConn()
+			// Track closes
+			originalClose := mc.closeFunc
+			mc.closeFunc = func() error {
+				closeCount.Add(1)
+				return originalClose()
+			}
+			return mc, nil
+		},
+		Logger: &mockLogger{},
+	}
+
+	pool := NewWithConfig(ctx, config)
+	defer pool.Close()
+
+	// Wait for idle timeout + maintenance interval
+	time.Sleep(250 * time.Millisecond)
+
+	// Connection should be closed and replaced
+	if closeCount.Load() == 0 {
+		t.Error("Expected idle connection to be closed")
+	}
+	if createCount.Load() < 2 {
+		t.Errorf("Expected idle connection to be replaced, got %d created", createCount.Load())
+	}
+}
+
+// TestConnectionPool_MaxLifetime tests age-based connection rotation
+func TestConnectionPool_MaxLifetime(t *testing.T) {
+	ctx := context.Background()
+	createCount := atomic.Int32{}
+	closeCount := atomic.Int32{}
+
+	config := Config{
+		EnsureIdle:     1,
+		MinIdle:        0,
+		MinIdleForAge:  0,
+		IdleTimeout:    time.Hour,
+		MaxLifetime:    100 * time.Millisecond,
+		CheckInterval:  50 * time.Millisecond,
+		LifetimeJitter: 0, // No jitter for predictable testing
+		CreateConn: func(ctx context.Context) (net.Conn, error) {
+			createCount.Add(1)
+			mc := newMockConn()
+			originalClose := mc.closeFunc
+			mc.closeFunc = func() error {
+				closeCount.Add(1)
+				return originalClose()
+			}
+			return mc, nil
+		},
+		Logger: &mockLogger{},
+	}
+
+	pool := NewWithConfig(ctx, config)
+	defer pool.Close()
+
+	// Wait for lifetime expiration + maintenance interval
+	time.Sleep(250 * time.Millisecond)
+
+	// Connection should be closed due to age
+	if closeCount.Load() == 0 {
+		t.Error("Expected old connection to be closed")
+	}
+}
+
+// TestConnectionPool_EnsureIdle tests pre-connection creation
+func TestConnectionPool_EnsureIdle(t *testing.T) {
+	ctx := context.Background()
+	createCount := atomic.Int32{}
+
+	config := Config{
+		EnsureIdle:       3,
+		EnsureCreateRate: 10, // Allow multiple creations
+		CheckInterval:    100 * time.Millisecond,
+		CreateConn: func(ctx context.Context) (net.Conn, error) {
+			createCount.Add(1)
+			return newMockConn(), nil
+		},
+		Logger: &mockLogger{},
+	}
+
+	pool := NewWithConfig(ctx, config)
+	defer pool.Close()
+
+	// Wait for pre-connections to be created
+	time.Sleep(200 * time.Millisecond)
+
+	if count := idleCount(pool); count != 3 {
+		t.Errorf("Expected 3 idle connections, got %d", count)
+	}
+
+	// Taking a connection dials a replacement without waiting for maintenance
+	conn, err := pool.GetConn(ctx)
+	if err != nil {
+		t.Fatalf("GetConn failed: %v", err)
+	}
+	conn.Close()
+	time.Sleep(20 * time.Millisecond)
+	if count := idleCount(pool); count != 3 {
+		t.Errorf("Expected taken connection to be replaced, got %d idle", count)
+	}
+	if createCount.Load() != 4 {
+		t.Errorf("Expected 4 connections created, got %d", createCount.Load())
+	}
+}
+
+// TestConnectionPool_MinIdle tests minimum idle protection
+func TestConnectionPool_MinIdle(t *testing.T) {
+	ctx := context.Background()
+	createCount := atomic.Int32{}
+	closeCount := atomic.Int32{}
+
+	config := Config{
+		EnsureIdle:    3,
+		MinIdle:       2,
+		IdleTimeout:   100 * time.Millisecond,
+		CheckInterval: 50 * time.Millisecond,
+		CreateConn: func(ctx context.Context) (net.Conn, error) {
+			createCount.Add(1)
+			mc := newMockConn()
+			originalClose := mc.closeFunc
+			mc.closeFunc = func() error {
+				closeCount.Add(1)
+				return originalClose()
+			}
+			return mc, nil
+		},
+		Logger: &mockLogger{},
+	}
+
+	pool := NewWithConfig(ctx, config)
+	defer pool.Close()
+
+	// Wait for pre-connections
+	time.Sleep(300 * time.Millisecond)
+
+	// Wait for idle timeout to trigger
+	time.Sleep(200 * time.Millisecond)
+
+	// Should maintain at least MinIdle connections
+	if count := idleCount(pool); count < config.MinIdle {
+		t.Errorf("Expected at least %d idle connections, got %d", config.MinIdle, count)
+	}
+}
+
+// TestConnectionPool_Reset tests network interface change handling
+func TestConnectionPool_Reset(t *testing.T) {
+	ctx := context.Background()
+	createCount := atomic.Int32{}
+	closeCount := atomic.Int32{}
+
+	config := Config{
+		EnsureIdle:       2,
+		EnsureCreateRate: 2,
+		CreateConn: func(ctx context.Context) (net.Conn, error) {
+			createCount.Add(1)
+			mc := newMockConn()
+			originalClose := mc.closeFunc
+			mc.closeFunc = func() error {
+				closeCount.Add(1)
+				return originalClose()
+			}
+			return mc, nil
+		},
+		Logger: &mockLogger{},
+	}
+
+	pool := NewWithConfig(ctx, config)
+	defer pool.Close()
+
+	// Wait for initial connections
+	time.Sleep(100 * time.Millisecond)
+
+	initialCreate := createCount.Load()
+
+	// Reset should close all connections
+	pool.Reset()
+
+	if closeCount.Load() != initialCreate {
+		t.Errorf("Expected %d connections to be closed after reset, got %d", initialCreate, closeCount.Load())
+	}
+
+	// Should recreate connections
+	time.Sleep(100 * time.Millisecond)
+	if createCount.Load() <= initialCreate {
+		t.Error("Expected new connections to be created after reset")
+	}
+	if count := idleCount(pool); count != config.EnsureIdle {
+		t.Errorf("Expected %d connections after reset, got %d", config.EnsureIdle, count)
+	}
+}
+
+// TestConnectionPool_ResetDiscardsPending tests that connections dialed
+// before a reset are not pooled
+func TestConnectionPool_ResetDiscardsPending(t *testing.T) {
+	ctx := context.Background()
+	release := make(chan struct{})
+	var stale atomic.Pointer[mockConn]
+
+	config := Config{
+		EnsureIdle: 1,
+		CreateConn: func(ctx context.Context) (net.Conn, error) {
+			mc := newMockConn()
+			if stale.CompareAndSwap(nil, mc) {
+				<-release
+			}
+			return mc, nil
+		},
+		Logger: &mockLogger{},
+	}
+
+	pool := NewWithConfig(ctx, config)
+	defer pool.Close()
+
+	time.Sleep(20 * time.Millisecond)
+	pool.Reset()
+	close(release)
+	time.Sleep(50 * time.Millisecond)
+
+	if !stale.Load().closed.Load() {
+		t.Error("Expected connection dialed before reset to be closed")
+	}
+	if count := idleCount(pool); count != 1 {
+		t.Errorf("Expected 1 idle connection, got %d", count)
+	}
+}
+
+// TestConnectionPool_Close tests graceful shutdown
+func TestConnectionPool_Close(t *testing.T) {
+	ctx := context.Background()
+	closeCount := atomic.Int32{}
+
+	config := Config{
+		EnsureIdle: 2,
+		CreateConn: func(ctx context.Context) (net.Conn, error) {
+			mc := newMockConn()
+			originalClose := mc.closeFunc
+			mc.closeFunc = func() error {
+				closeCount.Add(1)
+				return originalClose()
+			}
+			return mc, nil
+		},
+		Logger: &mockLogger{},
+	}
+
+	pool := NewWithConfig(ctx, config)
+
+	// Wait for connections to be created
+	time.Sleep(200 * time.Millisecond)
+
+	// Close pool
+	err := pool.Close()
+	if err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Should have closed all connections
+	if closeCount.Load() == 0 {
+		t.Error("Expected connections to be closed")
+	}
+
+	// GetConn should fail after close
+	_, err = pool.GetConn(ctx)
+	if err != net.ErrClosed {
+		t.Errorf("Expected ErrClosed, got %v", err)
+	}
+
+	// Close should be idempotent
+	err = pool.Close()
+	if err != nil {
+		t.Errorf("Second close should not error, got %v", err)
+	}
+}
+
+// TestTakenConn tests that pre-dialed connections are handed out and leave
+// the pool
+func TestTakenConn(t *testing.T) {
+	ctx := context.Background()
+	created := make(chan *mockConn, 2)
+
+	config := Config{
+		EnsureIdle: 1,
+		CreateConn: func(ctx context.Context) (net.Conn, error) {
+			mc := newMockConn()
+			created <- mc
+			return mc, nil
+		},
+		Logger: &mockLogger{},
+	}
+
+	pool := NewWithConfig(ctx, config)
+	defer pool.Close()
+
+	preDialed := <-created
+	time.Sleep(20 * time.Millisecond)
+
+	// Get connection
+	conn, err := pool.GetConn(ctx)
+	if err != nil {
+		t.Fatalf("GetConn failed: %v", err)
+	}
+	if conn.(*usedConn).Conn != net.Conn(preDialed) {
+		t.Error("Expected the pre-dialed connection")
+	}
+
+	// The replacement is a different connection
+	if replacement := <-created; replacement == preDialed {
+		t.Error("Expected a new connection to replace the taken one")
+	}
+
+	// Closing the taken connection does not affect the pool
+	conn.Close()
+	time.Sleep(20 * time.Millisecond)
+	if count := idleCount(pool); count != 1 {
+		t.Errorf("Expected 1 idle connection in pool, got %d", count)
+	}
+}
+
+// TestLifetimeJitter tests that jitter is applied correctly
+func TestLifetimeJitter(t *testing.T) {
+	ctx := context.Background()
+
+	config := Config{
+		EnsureIdle:     0,
+		MaxLifetime:    1 * time.Hour,
+		LifetimeJitter: 10 * time.Minute,
+		CreateConn: func(ctx context.Context) (net.Conn, error) {
+			return newMockConn(), nil
+		},
+		Logger: &mockLogger{},
+	}
+
+	pool := NewWithConfig(ctx, config)
+	defer pool.Close()
+
+	now := time.Now()
+	uniqueExpirations := make(map[int64]bool)
+	for i := 0; i < 20; i++ {
+		expiresAt := pool.calculateExpiration(now)
+		lifetime := expiresAt.Sub(now)
+		if lifetime < 50*time.Minute || lifetime > 70*time.Minute {
+			t.Errorf("Expected lifetime within jitter, got %v", lifetime)
+		}
+		uniqueExpirations[expiresAt.Unix()] = true
+	}
+
+	// Should have at least 2 different expiration times with jitter
+	if len(uniqueExpirations) < 2 {
+		t.Errorf("Expected variation in expiration times due to jitter, got %d unique times", len(uniqueExpirations))
+	}
+}
+
+// TestHeartbeat tests that heartbeat drops connections closed by the server
+func TestHeartbeat(t *testing.T) {
+	ctx := context.Background()
+	deadlineSet := atomic.Bool{}
+	createCount := atomic.Int32{}
+
+	config := Config{
+		EnsureIdle:       2,
+		EnsureCreateRate: 2,
+		Heartbeat:        100 * time.Millisecond,
+		CreateConn: func(ctx context.Context) (net.Conn, error) {
+			mc := newMockConn()
+			originalSetReadDeadline := mc.setReadDeadlineFunc
+			mc.setReadDeadlineFunc = func(t time.Time) error {
+				if !t.IsZero() {
+					deadlineSet.Store(true)
+				}
+				return originalSetReadDeadline(t)
+			}
+			if createCount.Add(1) == 1 {
+				// Closed by the server
+				mc.readFunc = func(b []byte) (int, error) {
+					return 0, io.EOF
+				}
+			} else {
+				mc.readFunc = mc.blockingRead
+			}
+			return mc, nil
+		},
+		Logger: &mockLogger{},
+	}
+
+	pool := NewWithConfig(ctx, config)
+	defer pool.Close()
+
+	// Wait for heartbeat to trigger
+	time.Sleep(250 * time.Millisecond)
+
+	// Should have set read deadline
+	if !deadlineSet.Load() {
+		t.Error("Expected heartbeat to set read deadline")
+	}
+
+	// The dead connection is replaced, the open one is kept
+	if createCount.Load() != 3 {
+		t.Errorf("Expected 3 connections created, got %d", createCount.Load())
+	}
+	if count := idleCount(pool); count != 2 {
+		t.Errorf("Expected 2 idle connections, got %d", count)
+	}
+}
+
+// TestEnsureCreateRate tests that creation rate limiting works
+func TestEnsureCreateRate(t *testing.T) {
+	ctx := context.Background()
+	createCount := atomic.Int32{}
+	inFlight := atomic.Int32{}
+	maxInFlight := atomic.Int32{}
+
+	config := Config{
+		EnsureIdle:       10,
+		EnsureCreateRate: 2, // Limit to 2 dials in flight
+		CheckInterval:    100 * time.Millisecond,
+		CreateConn: func(ctx context.Context) (net.Conn, error) {
+			createCount.Add(1)
+			current := inFlight.Add(1)
+			for {
+				observed := maxInFlight.Load()
+				if current <= observed || maxInFlight.CompareAndSwap(observed, current) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			inFlight.Add(-1)
+			return newMockConn(), nil
+		},
+		Logger: &mockLogger{},
+	}
+
+	pool := NewWithConfig(ctx, config)
+	defer pool.Close()
+
+	// Should eventually reach EnsureIdle
+	time.Sleep(300 * time.Millisecond)
+
+	if maxInFlight.Load() > int32(config.EnsureCreateRate) {
+		t.Errorf("Expected at most %d dials in flight, got %d", config.EnsureCreateRate, maxInFlight.Load())
+	}
+	if count := idleCount(pool); count != config.EnsureIdle {
+		t.Errorf("Expected %d idle connections, got %d", config.EnsureIdle, count)
+	}
+	if createCount.Load() != int32(config.EnsureIdle) {
+		t.Errorf("Expected %d connections created, got %d", config.EnsureIdle, createCount.Load())
+	}
+}
+
+// TestConnectionPool_Read tests that taking a connection clears the deadline
+// left by the heartbeat
+func TestConnectionPool_Read(t *testing.T) {
+	ctx := context.Background()
+	deadlineCleared := atomic.Bool{}
+
+	config := Config{
+		EnsureIdle: 1,
+		CreateConn: func(ctx context.Context) (net.Conn, error) {
+			mc := newMockConn()
+			originalSetReadDeadline := mc.setReadDeadlineFunc
+			mc.setReadDeadlineFunc = func(t time.Time) error {
+				if t.IsZero() {
+					deadlineCleared.Store(true)
+				}
+				return originalSetReadDeadline(t)
+			}
+			return mc, nil
+		},
+		Logger: &mockLogger{},
+	}
+
+	pool := NewWithConfig(ctx, config)
+	defer pool.Close()
+
+	time.Sleep(20 * time.Millisecond)
+	conn, err := pool.GetConn(ctx)
+	if err != nil {
+		t.Fatalf("GetConn failed: %v", err)
+	}
+
+	if !deadlineCleared.Load() {
+		t.Error("Expected GetConn to clear deadline")
+	}
+
+	conn.Close()
+}
+
+// TestConnectionPoolDefaults tests that default values are applied correctly
+func TestConnectionPoolDefaults(t *testing.T) {
+	ctx := context.Background()
+
+	config := Config{
+		// Only set required fields, let defaults apply
+		CreateConn: func(ctx context.Context) (net.Conn, error) {
+			return newMockConn(), nil
+		},
+		Logger: &mockLogger{},
+	}
+
+	pool := NewWithConfig(ctx, config)
+	defer pool.Close()
+
+	// Verify defaults were applied
+	if pool.checkInterval != 30*time.Second {
+		t.Errorf("Expected default checkInterval=30s, got %v", pool.checkInterval)
+	}
+
+	if pool.idleTimeout != 5*time.Minute {
+		t.Errorf("Expected default idleTimeout=5m, got %v", pool.idleTimeout)
+	}
+
+	if pool.ensureCreateRate != 1 {
+		t.Errorf("Expected default ensureCreateRate=1, got %d", pool.ensureCreateRate)
+	}
+}
+
+// TestNewAdaptiveOptions tests the validation of the adaptive options
+func TestNewAdaptiveOptions(t *testing.T) {
+	createConn := func(ctx context.Context) (net.Conn, error) {
+		return newMockConn(), nil
+	}
+	for _, adaptive := range []option.ConnectionPoolAdaptiveOptions{
+		{},
+		{MinEnsureIdleSession: 2, MaxEnsureIdleSession: 1},
+		{MaxEnsureIdleSession: 1, Window: badoption.Duration(time.Nanosecond)},
+		{MaxEnsureIdleSession: 1, Window: badoption.Duration(-time.Minute)},
+	} {
+		_, err := New(context.Background(), &mockLogger{}, option.DialerOptions{
+			ConnectionPool: &option.ConnectionPoolOptions{Adaptive: &adaptive},
+		}, createConn)
+		if err == nil {
+			t.Errorf("Expected invalid adaptive options %+v to be rejected", adaptive)
+		}
+	}
+	pool, err := New(context.Background(), &mockLogger{}, option.DialerOptions{
+		ConnectionPool: &option.ConnectionPoolOptions{Adaptive: &option.ConnectionPoolAdaptiveOptions{
+			MaxEnsureIdleSession: 1,
+			Window:               badoption.Duration(minAdaptiveWindow),
+		}},
+	}, createConn)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	pool.Close()
+}
+
+// TestNewDialerOptions tests the pool outbounds create from their dial fields
+func TestNewDialerOptions(t *testing.T) {
+	ctx := context.Background()
+	createCount := atomic.Int32{}
+	createConn := func(ctx context.Context) (net.Conn, error) {
+		createCount.Add(1)
+		return newMockConn(), nil
+	}
+
+	pool, err := New(ctx, &mockLogger{}, option.DialerOptions{}, createConn)
+	if err != nil || pool != nil {
+		t.Fatalf("Expected no pool without connection_pool, got %v and %v", pool, err)
+	}
+
+	pool, err = New(ctx, &mockLogger{}, option.DialerOptions{
+		ConnectionPool: &option.ConnectionPoolOptions{SessionPoolOptions: option.SessionPoolOptions{EnsureIdleSession: 1}},
+	}, createConn)
+	if err != nil {
+		t.Fatalf("New failed: %v", err)
+	}
+	defer pool.Close()
+	deadline := time.Now().Add(time.Second)
+	for idleCount(pool) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Expected a pre-dialed connection")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The pre-dialed connection is taken from the pool
+	conn, err := pool.GetConn(ctx)
+	if err != nil {
+		t.Fatalf("GetConn failed: %v", err)
+	}
+	defer conn.Close()
+	status := pool.Status()
+	if status.Hits != 1 || status.Misses != 0 {
+		t.Errorf("Expected 1 hit and no miss, got %d and %d", status.Hits, status.Misses)
+	}
+	if createCount.Load() == 0 {
+		t.Error("Expected the connection to be created by createConn")
+	}
+}
+
+// TestConnectionPool_Status tests the pool counters
+func TestConnectionPool_Status(t *testing.T) {
+	ctx := context.Background()
+	dead := atomic.Bool{}
+
+	config := Config{
+		EnsureIdle:    1,
+		IdleTimeout:   time.Hour,
+		CheckInterval: time.Hour,
+		Heartbeat:     50 * time.Millisecond,
+		CreateConn: func(ctx context.Context) (net.Conn, error) {
+			mc := newMockConn()
+			mc.readFunc = func(b []byte) (int, error) {
+				if dead.Load() {
+					return 0, io.EOF
+				}
+				return mc.blockingRead(b)
+			}
+			return mc, nil
+		},
+		Logger: &mockLogger{},
+	}
+
+	pool := NewWithConfig(ctx, config)
+	defer pool.Close()
+	time.Sleep(20 * time.Millisecond)
+
+	// Hit, then miss while the replacement is being dialed
+	conn1, err := pool.GetConn(ctx)
+	if err != nil {
+		t.Fatalf("GetConn failed: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	conn2, err := pool.GetConn(ctx)
+	if err != nil {
+		t.Fatalf("GetConn failed: %v", err)
+	}
+	conn3, err := pool.GetConn(ctx)
+	if err != nil {
+		t.Fatalf("GetConn failed: %v", err)
+	}
+	conn1.Close()
+	conn1.Close()
+
+	status := pool.Status()
+	if status.Hits != 2 || status.Misses != 1 {
+		t.Errorf("Expected 2 hits and 1 miss, got %d and %d", status.Hits, status.Misses)
+	}
+	if status.InUse != 2 {
+		t.Errorf("Expected 2 connections in use, got %d", status.InUse)
+	}
+	conn2.Close()
+	conn3.Close()
+
+	// The heartbeat drops the idle connection
+	time.Sleep(20 * time.Millisecond)
+	dead.Store(true)
+	time.Sleep(100 * time.Millisecond)
+	status = pool.Status()
+	if status.HeartbeatFailures == 0 {
+		t.Error("Expected heartbeat failures to be counted")
+	}
+	if status.InUse != 0 {
+		t.Errorf("Expected no connections in use, got %d", status.InUse)
+	}
+	if status.Created < 3 {
+		t.Errorf("Expected at least 3 connections created, got %d", status.Created)
+	}
+}
+
+// TestAdaptiveSizing tests sizing from the demand of the sliding window
+func TestAdaptiveSizing(t *testing.T) {
+	sizing := newAdaptiveSizing(AdaptiveConfig{Min: 1, Max: 8, Window: time.Second})
+	if ensureIdle := sizing.clamp(20); ensureIdle != 8 {
+		t.Errorf("Expected initial size to be clamped to 8, got %d", ensureIdle)
+	}
+
+	// Misses grow the size right away
+	for i := 0; i < 3; i++ {
+		sizing.record(true)
+	}
+	ensureIdle := sizing.next(2)
+	if ensureIdle != 5 {
+		t.Errorf("Expected size 5 after 3 misses, got %d", ensureIdle)
+	}
+
+	// Growth is bounded by max
+	for i := 0; i < 10; i++ {
+		sizing.record(true)
+	}
+	ensureIdle = sizing.next(ensureIdle)
+	if ensureIdle != 8 {
+		t.Errorf("Expected size to be bounded by 8, got %d", ensureIdle)
+	}
+
+	// Without demand it shrinks by one per bucket once the peak leaves the window
+	for i := 0; i < adaptiveBuckets-1; i++ {
+		ensureIdle = sizing.next(ensureIdle)
+		if ensureIdle != 8 {
+			t.Fatalf("Expected size to hold while the peak is in the window, got %d", ensureIdle)
+		}
+	}
+	for _, expected := range []int{7, 6, 5, 4, 3, 2, 1, 1} {
+		ensureIdle = sizing.next(ensureIdle)
+		if ensureIdle != expected {
+			t.Fatalf("Expected size %d, got %d", expected, ensureIdle)
+		}
+	}
+
+	// Hits keep the size at the peak demand
+	sizing.record(false)
+	sizing.record(false)
+	ensureIdle = sizing.next(ensureIdle)
+	if ensureIdle != 2 {
+		t.Errorf("Expected size 2 from peak demand, got %d", ensureIdle)
+	}
+}
+
+// TestConnectionPool_Adaptive tests that the pool follows the adaptive size
+func TestConnectionPool_Adaptive(t *testing.T) {
+	ctx := context.Background()
+	config := Config{
+		EnsureIdle:       1,
+		EnsureCreateRate: 10,
+		Adaptive:         &AdaptiveConfig{Min: 1, Max: 4, Window: time.Hour},
+		CreateConn: func(ctx context.Context) (net.Conn, error) {
+			return newMockConn(), nil
+		},
+		Logger: &mockLogger{},
+	}
+	pool := NewWithConfig(ctx, config)
+	defer pool.Close()
+	time.Sleep(20 * time.Millisecond)
+
+	// Taking connections faster than they are replaced produces misses
+	var conns []net.Conn
+	for i := 0; i < 4; i++ {
+		conn, err := pool.GetConn(ctx)
+		if err != nil {
+			t.Fatalf("GetConn failed: %v", err)
+		}
+		conns = append(conns, conn)
+	}
+	for _, conn := range conns {
+		conn.Close()
+	}
+	if pool.Status().Misses == 0 {
+		t.Fatal("Expected pool misses")
+	}
+
+	pool.performAdaptation()
+	time.Sleep(20 * time.Millisecond)
+	if status := pool.Status(); status.EnsureIdle != 4 || status.Idle != 4 {
+		t.Errorf("Expected pool to grow to 4 idle connections, got %d of %d", status.Idle, status.EnsureIdle)
+	}
+
+	// Shrinking closes idle connections above the new size
+	pool.access.Lock()
+	for i := range pool.adaptive.takes {
+		pool.adaptive.takes[i] = 0
+		pool.adaptive.misses[i] = 0
+	}
+	pool.access.Unlock()
+	pool.performAdaptation()
+	if status := pool.Status(); status.EnsureIdle != 3 || status.Idle != 3 {
+		t.Errorf("Expected pool to shrink to 3 idle connections, got %d of %d", status.Idle, status.EnsureIdle)
+	}
+}
+
+// TestConnectionPool_Pause tests that the pool is drained while paused and
+// warmed up again on wake
+func TestConnectionPool_Pause(t *testing.T) {
+	ctx := pause.WithDefaultManager(context.Background())
+	pauseManager := service.FromContext[pause.Manager](ctx)
+	var conns []*mockConn
+	var connsMu sync.Mutex
+	config := Config{
+		EnsureIdle:       2,
+		EnsureCreateRate: 10,
+		CreateConn: func(ctx context.Context) (net.Conn, error) {
+			conn := newMockConn()
+			connsMu.Lock()
+			conns = append(conns, conn)
+			connsMu.Unlock()
+			return conn, nil
+		},
+		Logger: &mockLogger{},
+	}
+	pool := NewWithConfig(ctx, config)
+	defer pool.Close()
+	time.Sleep(20 * time.Millisecond)
+	if count := idleCount(pool); count != 2 {
+		t.Fatalf("Expected 2 idle connections, got %d", count)
+	}
+
+	pauseManager.DevicePause()
+	if count := idleCount(pool); count != 0 {
+		t.Errorf("Expected pool to be drained on pause, got %d idle", count)
+	}
+	connsMu.Lock()
+	for _, conn := range conns {
+		if !conn.closed.Load() {
+			t.Error("Expected idle connections to be closed on pause")
+		}
+	}
+	connsMu.Unlock()
+
+	// Taking a connection while paused dials on demand without pre-dialing
+	conn, err := pool.GetConn(ctx)
+	if err != nil {
+		t.Fatalf("GetConn failed: %v", err)
+	}
+	conn.Close()
+	pool.performMaintenance()
+	time.Sleep(20 * time.Millisecond)
+	if status := pool.Status(); status.Idle != 0 || status.Dialing != 0 {
+		t.Errorf("Expected no pre-dialing while paused, got %d idle and %d dialing", status.Idle, status.Dialing)
+	}
+
+	pauseManager.DeviceWake()
+	time.Sleep(20 * time.Millisecond)
+	if count := idleCount(pool); count != 2 {
+		t.Errorf("Expected pool to be warmed up on wake, got %d idle", count)
+	}
+	connsMu.Lock()
+	if len(conns) != 5 {
+		t.Errorf("Expected 5 connections created, got %d", len(conns))
+	}
+	connsMu.Unlock()
+
+	// The callback is unregistered on close
+	pool.Close()
+	pauseManager.DevicePause()
+	pauseManager.DeviceWake()
+}
@@ -20,7 +20,11 @@ func New(channelSize int) *Listener {
 }
 
 func (l *Listener) Serve(conn net.Conn) {
-	l.pipe <- conn
+	select {
+	case l.pipe <- conn:
+	case <-l.done:
+		conn.Close()
+	}
 }
 
 func (l *Listener) Accept() (net.Conn, error) {
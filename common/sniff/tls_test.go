@@ -0,0 +1,37 @@
+package sniff_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/sagernet/sing-box/common/sniff"
+
+	"github.com/stretchr/testify/require"
+)
+
+func captureClientHello(t *testing.T, config *tls.Config) []byte {
+	client, server := net.Pipe()
+	go tls.Client(client, config).Handshake()
+	defer client.Close()
+	defer server.Close()
+	buffer := make([]byte, 4096)
+	n, err := server.Read(buffer)
+	require.NoError(t, err)
+	return buffer[:n]
+}
+
+func TestReadClientHello(t *testing.T) {
+	t.Parallel()
+	pkt := captureClientHello(t, &tls.Config{ServerName: "www.google.com", NextProtos: []string{"h2", "http/1.1"}})
+	clientHello, err := sniff.ReadClientHello(context.Background(), bytes.NewReader(pkt))
+	require.NoError(t, err)
+	require.Equal(t, "www.google.com", clientHello.ServerName)
+	require.Equal(t, []string{"h2", "http/1.1"}, clientHello.SupportedProtos)
+
+	_, err = sniff.ReadClientHello(context.Background(), bytes.NewReader(pkt[:len(pkt)/2]))
+	require.True(t, errors.Is(err, sniff.ErrNeedMoreData))
+}
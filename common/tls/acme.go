@@ -122,6 +122,18 @@ func startACME(ctx context.Context, logger logger.Logger, options option.Inbound
 			solver.DNSProvider = &cloudflare.Provider{
 				APIToken: dnsOptions.CloudflareOptions.APIToken,
 			}
+		case C.DNSProviderRFC2136:
+			provider, err := newRFC2136Provider(dnsOptions.RFC2136Options)
+			if err != nil {
+				return nil, nil, err
+			}
+			solver.DNSProvider = provider
+		case C.DNSProviderWebhook:
+			provider, err := newWebhookProvider(dnsOptions.WebhookOptions)
+			if err != nil {
+				return nil, nil, err
+			}
+			solver.DNSProvider = provider
 		default:
 			return nil, nil, E.New("unsupported ACME DNS01 provider type: " + dnsOptions.Provider)
 		}
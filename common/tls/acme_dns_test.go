@@ -0,0 +1,148 @@
+//go:build with_acme
+
+package tls
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/json/badoption"
+
+	"github.com/libdns/libdns"
+	"github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func testChallengeRecord() []libdns.Record {
+	return []libdns.Record{libdns.RR{
+		Name: "_acme-challenge",
+		TTL:  time.Minute,
+		Type: "TXT",
+		Data: "challenge-token",
+	}}
+}
+
+func TestRFC2136Provider(t *testing.T) {
+	t.Parallel()
+	secret := base64.StdEncoding.EncodeToString([]byte("test secret"))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	var (
+		access  sync.Mutex
+		records = make(map[string]bool)
+	)
+	server := &dns.Server{
+		Listener:   listener,
+		TsigSecret: map[string]string{"acme.": secret},
+		// The default accept func rejects updates
+		MsgAcceptFunc: func(dns.Header) dns.MsgAcceptAction {
+			return dns.MsgAccept
+		},
+		Handler: dns.HandlerFunc(func(w dns.ResponseWriter, request *dns.Msg) {
+			response := new(dns.Msg)
+			response.SetReply(request)
+			if request.IsTsig() == nil || w.TsigStatus() != nil {
+				response.Rcode = dns.RcodeNotAuth
+			} else {
+				access.Lock()
+				for _, rr := range request.Ns {
+					txt, isTXT := rr.(*dns.TXT)
+					if !isTXT {
+						continue
+					}
+					key := txt.Hdr.Name + " " + txt.Txt[0]
+					if txt.Hdr.Class == dns.ClassNONE {
+						delete(records, key)
+					} else {
+						records[key] = true
+					}
+				}
+				access.Unlock()
+			}
+			response.SetTsig(request.IsTsig().Hdr.Name, request.IsTsig().Algorithm, 300, time.Now().Unix())
+			w.WriteMsg(response)
+		}),
+	}
+	go server.ActivateAndServe()
+	defer server.Shutdown()
+
+	provider, err := newRFC2136Provider(option.ACMEDNS01RFC2136Options{
+		Server:      listener.Addr().String(),
+		TSIGKeyName: "acme",
+		TSIGSecret:  secret,
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+	_, err = provider.AppendRecords(ctx, "example.com.", testChallengeRecord())
+	require.NoError(t, err)
+	access.Lock()
+	require.Equal(t, map[string]bool{"_acme-challenge.example.com. challenge-token": true}, records)
+	access.Unlock()
+	_, err = provider.DeleteRecords(ctx, "example.com.", testChallengeRecord())
+	require.NoError(t, err)
+	access.Lock()
+	require.Empty(t, records)
+	access.Unlock()
+
+	provider, err = newRFC2136Provider(option.ACMEDNS01RFC2136Options{
+		Server:      listener.Addr().String(),
+		TSIGKeyName: "acme",
+		TSIGSecret:  base64.StdEncoding.EncodeToString([]byte("wrong secret")),
+	})
+	require.NoError(t, err)
+	_, err = provider.AppendRecords(ctx, "example.com.", testChallengeRecord())
+	require.Error(t, err)
+}
+
+func TestWebhookProvider(t *testing.T) {
+	t.Parallel()
+	requests := make(chan webhookRequest, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var request webhookRequest
+		err := json.NewDecoder(r.Body).Decode(&request)
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		requests <- request
+	}))
+	defer server.Close()
+
+	provider, err := newWebhookProvider(option.ACMEDNS01WebhookOptions{
+		URL:     server.URL,
+		Headers: badoption.HTTPHeader{"Authorization": {"Bearer token"}},
+	})
+	require.NoError(t, err)
+	ctx := context.Background()
+	_, err = provider.AppendRecords(ctx, "example.com.", testChallengeRecord())
+	require.NoError(t, err)
+	require.Equal(t, webhookRequest{
+		Action: "present",
+		Zone:   "example.com.",
+		Name:   "_acme-challenge",
+		FQDN:   "_acme-challenge.example.com.",
+		Type:   "TXT",
+		Value:  "challenge-token",
+		TTL:    60,
+	}, <-requests)
+	_, err = provider.DeleteRecords(ctx, "example.com.", testChallengeRecord())
+	require.NoError(t, err)
+	require.Equal(t, "cleanup", (<-requests).Action)
+
+	provider, err = newWebhookProvider(option.ACMEDNS01WebhookOptions{URL: server.URL})
+	require.NoError(t, err)
+	_, err = provider.AppendRecords(ctx, "example.com.", testChallengeRecord())
+	require.Error(t, err)
+}
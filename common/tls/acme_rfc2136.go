@@ -0,0 +1,144 @@
+//go:build with_acme
+
+package tls
+
+import (
+	"context"
+	"encoding/base64"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/libdns/libdns"
+	"github.com/miekg/dns"
+)
+
+var rfc2136TSIGAlgorithms = []string{
+	dns.HmacSHA1,
+	dns.HmacSHA224,
+	dns.HmacSHA256,
+	dns.HmacSHA384,
+	dns.HmacSHA512,
+	dns.HmacMD5,
+}
+
+// rfc2136Provider presents DNS-01 challenges with RFC 2136 dynamic updates,
+// optionally signed with TSIG, as supported by BIND, PowerDNS and Knot
+type rfc2136Provider struct {
+	server        string
+	tsigKeyName   string
+	tsigAlgorithm string
+	tsigSecret    string
+	timeout       time.Duration
+}
+
+func newRFC2136Provider(options option.ACMEDNS01RFC2136Options) (*rfc2136Provider, error) {
+	if options.Server == "" {
+		return nil, E.New("rfc2136: missing server")
+	}
+	server := options.Server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "53")
+	}
+	provider := &rfc2136Provider{
+		server:  server,
+		timeout: time.Duration(options.Timeout),
+	}
+	if provider.timeout == 0 {
+		provider.timeout = 10 * time.Second
+	}
+	if options.TSIGKeyName != "" || options.TSIGSecret != "" {
+		if options.TSIGKeyName == "" || options.TSIGSecret == "" {
+			return nil, E.New("rfc2136: tsig_key_name and tsig_secret must be set together")
+		}
+		_, err := base64.StdEncoding.DecodeString(options.TSIGSecret)
+		if err != nil {
+			return nil, E.Cause(err, "rfc2136: decode tsig_secret")
+		}
+		provider.tsigKeyName = dns.CanonicalName(options.TSIGKeyName)
+		provider.tsigSecret = options.TSIGSecret
+		provider.tsigAlgorithm = dns.HmacSHA256
+		if options.TSIGAlgorithm != "" {
+			provider.tsigAlgorithm = dns.CanonicalName(options.TSIGAlgorithm)
+			if !common.Contains(rfc2136TSIGAlgorithms, provider.tsigAlgorithm) {
+				return nil, E.New("rfc2136: unsupported tsig_algorithm: ", options.TSIGAlgorithm)
+			}
+		}
+	}
+	return provider, nil
+}
+
+func (p *rfc2136Provider) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	resourceRecords, err := rfc2136ResourceRecords(zone, records)
+	if err != nil {
+		return nil, err
+	}
+	message := new(dns.Msg)
+	message.SetUpdate(dns.Fqdn(zone))
+	message.Insert(resourceRecords)
+	err = p.exchange(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (p *rfc2136Provider) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	resourceRecords, err := rfc2136ResourceRecords(zone, records)
+	if err != nil {
+		return nil, err
+	}
+	message := new(dns.Msg)
+	message.SetUpdate(dns.Fqdn(zone))
+	message.Remove(resourceRecords)
+	err = p.exchange(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (p *rfc2136Provider) exchange(ctx context.Context, message *dns.Msg) error {
+	client := &dns.Client{
+		Net:     "tcp",
+		Timeout: p.timeout,
+	}
+	if p.tsigKeyName != "" {
+		client.TsigSecret = map[string]string{p.tsigKeyName: p.tsigSecret}
+		message.SetTsig(p.tsigKeyName, p.tsigAlgorithm, 300, time.Now().Unix())
+	}
+	response, _, err := client.ExchangeContext(ctx, message, p.server)
+	if err != nil {
+		return E.Cause(err, "rfc2136: update ", p.server)
+	}
+	if response.Rcode != dns.RcodeSuccess {
+		return E.New("rfc2136: update ", p.server, ": ", dns.RcodeToString[response.Rcode])
+	}
+	return nil
+}
+
+func rfc2136ResourceRecords(zone string, records []libdns.Record) ([]dns.RR, error) {
+	resourceRecords := make([]dns.RR, 0, len(records))
+	for _, record := range records {
+		rr := record.RR()
+		header := dns.RR_Header{
+			Name:   libdns.AbsoluteName(rr.Name, zone),
+			Rrtype: dns.StringToType[strings.ToUpper(rr.Type)],
+			Class:  dns.ClassINET,
+			Ttl:    uint32(rr.TTL.Seconds()),
+		}
+		switch header.Rrtype {
+		case dns.TypeTXT:
+			resourceRecords = append(resourceRecords, &dns.TXT{Hdr: header, Txt: []string{rr.Data}})
+		case dns.TypeCNAME:
+			resourceRecords = append(resourceRecords, &dns.CNAME{Hdr: header, Target: dns.Fqdn(rr.Data)})
+		default:
+			return nil, E.New("rfc2136: unsupported record type: ", rr.Type)
+		}
+	}
+	return resourceRecords, nil
+}
@@ -0,0 +1,110 @@
+//go:build with_acme
+
+package tls
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/libdns/libdns"
+)
+
+// webhookProvider presents DNS-01 challenges by posting each record to a
+// user provided endpoint, which updates the zone by any means
+type webhookProvider struct {
+	url     string
+	headers http.Header
+	client  *http.Client
+}
+
+// webhookRequest is the JSON body posted to the webhook
+type webhookRequest struct {
+	Action string `json:"action"` // present or cleanup
+	Zone   string `json:"zone"`
+	Name   string `json:"name"`
+	FQDN   string `json:"fqdn"`
+	Type   string `json:"type"`
+	Value  string `json:"value"`
+	TTL    int64  `json:"ttl"`
+}
+
+func newWebhookProvider(options option.ACMEDNS01WebhookOptions) (*webhookProvider, error) {
+	if options.URL == "" {
+		return nil, E.New("webhook: missing url")
+	}
+	webhookURL, err := url.Parse(options.URL)
+	if err != nil {
+		return nil, E.Cause(err, "webhook: parse url")
+	}
+	if (webhookURL.Scheme != "http" && webhookURL.Scheme != "https") || webhookURL.Host == "" {
+		return nil, E.New("webhook: url must be an absolute http or https URL: ", options.URL)
+	}
+	timeout := time.Duration(options.Timeout)
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+	return &webhookProvider{
+		url:     options.URL,
+		headers: options.Headers.Build(),
+		client:  &http.Client{Timeout: timeout},
+	}, nil
+}
+
+func (p *webhookProvider) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	for _, record := range records {
+		err := p.post(ctx, "present", zone, record.RR())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+func (p *webhookProvider) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	for _, record := range records {
+		err := p.post(ctx, "cleanup", zone, record.RR())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+func (p *webhookProvider) post(ctx context.Context, action string, zone string, rr libdns.RR) error {
+	body, err := json.Marshal(webhookRequest{
+		Action: action,
+		Zone:   zone,
+		Name:   rr.Name,
+		FQDN:   libdns.AbsoluteName(rr.Name, zone),
+		Type:   rr.Type,
+		Value:  rr.Data,
+		TTL:    int64(rr.TTL.Seconds()),
+	})
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header = p.headers.Clone()
+	request.Header.Set("Content-Type", "application/json")
+	response, err := p.client.Do(request)
+	if err != nil {
+		return E.Cause(err, "webhook: ", action)
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		message, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return E.New("webhook: ", action, ": unexpected status ", response.Status, ": ", string(bytes.TrimSpace(message)))
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sagernet/fswatch"
@@ -16,21 +17,27 @@ import (
 	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/ntp"
+
+	"github.com/fsnotify/fsnotify"
 )
 
 var errInsecureUnused = E.New("tls: insecure unused")
 
 type STDServerConfig struct {
-	access          sync.RWMutex
-	config          *tls.Config
-	logger          log.Logger
-	acmeService     adapter.SimpleLifecycle
-	certificate     []byte
-	key             []byte
-	certificatePath string
-	keyPath         string
-	echKeyPath      string
-	watcher         *fswatch.Watcher
+	access                   sync.RWMutex
+	config                   *tls.Config
+	logger                   log.Logger
+	acmeService              adapter.SimpleLifecycle
+	reloadAccess             sync.Mutex
+	certificates             atomic.Pointer[[]tls.Certificate]
+	certificate              []byte
+	key                      []byte
+	certificatePath          string
+	keyPath                  string
+	certificateDirectoryPath string
+	echKeyPath               string
+	watcher                  *fswatch.Watcher
+	directoryWatcher         *fsnotify.Watcher
 }
 
 func (c *STDServerConfig) ServerName() string {
@@ -100,6 +107,12 @@ func (c *STDServerConfig) Start() error {
 }
 
 func (c *STDServerConfig) startWatcher() error {
+	if c.certificateDirectoryPath != "" {
+		err := c.startDirectoryWatcher()
+		if err != nil {
+			return err
+		}
+	}
 	var watchPath []string
 	if c.certificatePath != "" {
 		watchPath = append(watchPath, c.certificatePath)
@@ -135,6 +148,8 @@ func (c *STDServerConfig) startWatcher() error {
 
 func (c *STDServerConfig) certificateUpdated(path string) error {
 	if path == c.certificatePath || path == c.keyPath {
+		c.reloadAccess.Lock()
+		defer c.reloadAccess.Unlock()
 		if path == c.certificatePath {
 			certificate, err := os.ReadFile(c.certificatePath)
 			if err != nil {
@@ -148,15 +163,10 @@ func (c *STDServerConfig) certificateUpdated(path string) error {
 			}
 			c.key = key
 		}
-		keyPair, err := tls.X509KeyPair(c.certificate, c.key)
+		err := c.loadCertificates()
 		if err != nil {
 			return E.Cause(err, "reload key pair")
 		}
-		c.access.Lock()
-		config := c.config.Clone()
-		config.Certificates = []tls.Certificate{keyPair}
-		c.config = config
-		c.access.Unlock()
 		c.logger.Info("reloaded TLS certificate")
 	} else if path == c.echKeyPath {
 		echKey, err := os.ReadFile(c.echKeyPath)
@@ -176,10 +186,7 @@ func (c *STDServerConfig) Close() error {
 	if c.acmeService != nil {
 		return c.acmeService.Close()
 	}
-	if c.watcher != nil {
-		return c.watcher.Close()
-	}
-	return nil
+	return common.Close(common.PtrOrNil(c.watcher), common.PtrOrNil(c.directoryWatcher))
 }
 
 func NewSTDServer(ctx context.Context, logger log.Logger, options option.InboundTLSOptions) (ServerConfig, error) {
@@ -236,6 +243,9 @@ func NewSTDServer(ctx context.Context, logger log.Logger, options option.Inbound
 	}
 	var certificate []byte
 	var key []byte
+	if acmeService != nil && options.CertificateDirectoryPath != "" {
+		return nil, E.New("certificate_directory_path can not be used with ACME")
+	}
 	if acmeService == nil {
 		if len(options.Certificate) > 0 {
 			certificate = []byte(strings.Join(options.Certificate, "\n"))
@@ -255,7 +265,7 @@ func NewSTDServer(ctx context.Context, logger log.Logger, options option.Inbound
 			}
 			key = content
 		}
-		if certificate == nil && key == nil && options.Insecure {
+		if certificate == nil && key == nil && options.CertificateDirectoryPath == "" && options.Insecure {
 			timeFunc := ntp.TimeFuncFromContext(ctx)
 			if timeFunc == nil {
 				timeFunc = time.Now
@@ -264,17 +274,11 @@ func NewSTDServer(ctx context.Context, logger log.Logger, options option.Inbound
 				return GenerateKeyPair(nil, nil, timeFunc, info.ServerName)
 			}
 		} else {
-			if certificate == nil {
+			if certificate == nil && key != nil {
 				return nil, E.New("missing certificate")
-			} else if key == nil {
+			} else if key == nil && certificate != nil {
 				return nil, E.New("missing key")
 			}
-
-			keyPair, err := tls.X509KeyPair(certificate, key)
-			if err != nil {
-				return nil, E.Cause(err, "parse x509 key pair")
-			}
-			tlsConfig.Certificates = []tls.Certificate{keyPair}
 		}
 	}
 	var echKeyPath string
@@ -294,6 +298,14 @@ func NewSTDServer(ctx context.Context, logger log.Logger, options option.Inbound
 		keyPath:         options.KeyPath,
 		echKeyPath:      echKeyPath,
 	}
+	if acmeService == nil && tlsConfig.GetCertificate == nil {
+		serverConfig.certificateDirectoryPath = options.CertificateDirectoryPath
+		err = serverConfig.loadCertificates()
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.GetCertificate = serverConfig.getCertificate
+	}
 	serverConfig.config.GetConfigForClient = func(info *tls.ClientHelloInfo) (*tls.Config, error) {
 		serverConfig.access.Lock()
 		defer serverConfig.access.Unlock()
@@ -0,0 +1,158 @@
+package tls
+
+import (
+	"crypto/tls"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sagernet/fswatch"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// loadCertificates parses the configured key pair and the key pairs of the
+// certificate directory. They are swapped in at once, so handshakes never see
+// a partially reloaded set. Reloads must hold reloadAccess.
+func (c *STDServerConfig) loadCertificates() error {
+	var certificates []tls.Certificate
+	if c.certificate != nil || c.key != nil {
+		keyPair, err := tls.X509KeyPair(c.certificate, c.key)
+		if err != nil {
+			return E.Cause(err, "parse x509 key pair")
+		}
+		certificates = append(certificates, keyPair)
+	}
+	if c.certificateDirectoryPath != "" {
+		directoryCertificates, err := readCertificateDirectory(c.certificateDirectoryPath)
+		if err != nil {
+			return err
+		}
+		certificates = append(certificates, directoryCertificates...)
+	}
+	if len(certificates) == 0 {
+		return E.New("missing certificate")
+	}
+	c.certificates.Store(&certificates)
+	return nil
+}
+
+// readCertificateDirectory reads the key pairs of path, named <name>.key with
+// the certificate in <name>.crt or <name>.pem
+func readCertificateDirectory(path string) ([]tls.Certificate, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, E.Cause(err, "read certificate directory")
+	}
+	var certificates []tls.Certificate
+	for _, entry := range entries {
+		keyName := entry.Name()
+		if entry.IsDir() || filepath.Ext(keyName) != ".key" {
+			continue
+		}
+		name := strings.TrimSuffix(keyName, ".key")
+		certificate, err := os.ReadFile(filepath.Join(path, name+".crt"))
+		if os.IsNotExist(err) {
+			certificate, err = os.ReadFile(filepath.Join(path, name+".pem"))
+		}
+		if err != nil {
+			return nil, E.Cause(err, "read certificate of ", keyName)
+		}
+		key, err := os.ReadFile(filepath.Join(path, keyName))
+		if err != nil {
+			return nil, E.Cause(err, "read key ", keyName)
+		}
+		keyPair, err := tls.X509KeyPair(certificate, key)
+		if err != nil {
+			return nil, E.Cause(err, "parse x509 key pair ", name)
+		}
+		certificates = append(certificates, keyPair)
+	}
+	if len(certificates) == 0 {
+		return nil, E.New("no key pairs found in certificate directory: ", path)
+	}
+	return certificates, nil
+}
+
+// getCertificate selects the first certificate supported by the client,
+// matching its server name, or the first certificate otherwise
+func (c *STDServerConfig) getCertificate(info *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	certificates := *c.certificates.Load()
+	for i := range certificates {
+		if info.SupportsCertificate(&certificates[i]) == nil {
+			return &certificates[i], nil
+		}
+	}
+	return &certificates[0], nil
+}
+
+// startDirectoryWatcher reloads certificates when files of the certificate
+// directory change. fswatch only reports the paths it was given, so the
+// directory is watched with fsnotify to also catch new key pairs.
+func (c *STDServerConfig) startDirectoryWatcher() error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	err = watcher.Add(c.certificateDirectoryPath)
+	if err != nil {
+		watcher.Close()
+		return err
+	}
+	c.directoryWatcher = watcher
+	go c.loopDirectoryUpdate(watcher)
+	return nil
+}
+
+func (c *STDServerConfig) loopDirectoryUpdate(watcher *fsnotify.Watcher) {
+	var (
+		timerAccess sync.Mutex
+		timer       *time.Timer
+	)
+	// Close closes the watcher, so a pending reload must not fire afterwards
+	defer func() {
+		timerAccess.Lock()
+		if timer != nil {
+			timer.Stop()
+		}
+		timerAccess.Unlock()
+	}()
+	for {
+		select {
+		case event, loaded := <-watcher.Events:
+			if !loaded {
+				return
+			}
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) && !event.Has(fsnotify.Remove) && !event.Has(fsnotify.Rename) {
+				continue
+			}
+			timerAccess.Lock()
+			if timer != nil {
+				timer.Reset(fswatch.DefaultWaitTimeout)
+			} else {
+				timer = time.AfterFunc(fswatch.DefaultWaitTimeout, func() {
+					timerAccess.Lock()
+					timer = nil
+					timerAccess.Unlock()
+					c.reloadAccess.Lock()
+					err := c.loadCertificates()
+					c.reloadAccess.Unlock()
+					if err != nil {
+						c.logger.Error(E.Cause(err, "reload certificates from ", c.certificateDirectoryPath))
+					} else {
+						c.logger.Info("reloaded TLS certificates from ", c.certificateDirectoryPath)
+					}
+				})
+			}
+			timerAccess.Unlock()
+		case err, loaded := <-watcher.Errors:
+			if !loaded {
+				return
+			}
+			c.logger.Error("watch certificate directory: ", err)
+		}
+	}
+}
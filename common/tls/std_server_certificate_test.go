@@ -0,0 +1,72 @@
+package tls
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/logger"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeTestKeyPair(t *testing.T, directory string, name string, certificateExt string, serverName string) {
+	key, certificate, err := GenerateCertificate(nil, nil, time.Now, serverName, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(directory, name+certificateExt), certificate, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(directory, name+".key"), key, 0o600))
+}
+
+func testServedName(t *testing.T, config ServerConfig, serverName string) string {
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+	go func() {
+		conn, err := config.Server(serverConn)
+		if err == nil {
+			conn.HandshakeContext(context.Background())
+		}
+	}()
+	tlsConn := tls.Client(clientConn, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+	})
+	require.NoError(t, tlsConn.HandshakeContext(context.Background()))
+	return tlsConn.ConnectionState().PeerCertificates[0].Subject.CommonName
+}
+
+func TestCertificateDirectory(t *testing.T) {
+	t.Parallel()
+	directory := t.TempDir()
+	writeTestKeyPair(t, directory, "a", ".crt", "a.example.com")
+	writeTestKeyPair(t, directory, "b", ".pem", "b.example.com")
+	config, err := NewSTDServer(context.Background(), logger.NOP(), option.InboundTLSOptions{
+		Enabled:                  true,
+		CertificateDirectoryPath: directory,
+	})
+	require.NoError(t, err)
+	require.NoError(t, config.Start())
+	defer config.Close()
+
+	// Key pairs are selected by the server name of the client
+	require.Equal(t, "a.example.com", testServedName(t, config, "a.example.com"))
+	require.Equal(t, "b.example.com", testServedName(t, config, "b.example.com"))
+
+	// New key pairs are picked up by the watcher
+	writeTestKeyPair(t, directory, "c", ".crt", "c.example.com")
+	require.Eventually(t, func() bool {
+		return testServedName(t, config, "c.example.com") == "c.example.com"
+	}, 5*time.Second, 50*time.Millisecond)
+
+	// A failed reload keeps the previous key pairs
+	require.NoError(t, os.WriteFile(filepath.Join(directory, "d.key"), []byte("invalid"), 0o600))
+	time.Sleep(500 * time.Millisecond)
+	require.Equal(t, "a.example.com", testServedName(t, config, "a.example.com"))
+	require.Equal(t, "c.example.com", testServedName(t, config, "c.example.com"))
+	require.Len(t, *config.(*STDServerConfig).certificates.Load(), 3)
+}
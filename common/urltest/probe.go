@@ -0,0 +1,300 @@
+package urltest
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"net"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/ntp"
+
+	mDNS "github.com/miekg/dns"
+)
+
+const (
+	ProbeTypeHTTP    = "http"
+	ProbeTypeTCP     = "tcp"
+	ProbeTypeTLS     = "tls"
+	ProbeTypeDNS     = "dns"
+	ProbeTypeUDPEcho = "udp_echo"
+	ProbeTypeSTUN    = "stun"
+)
+
+var (
+	defaultDNSProbeServer  = M.ParseSocksaddrHostPort("1.1.1.1", 53)
+	defaultSTUNProbeServer = M.ParseSocksaddrHostPort("stun.l.google.com", 19302)
+)
+
+const (
+	defaultDNSProbeDomain = "www.google.com"
+	stunMagicCookie       = 0x2112A442
+	stunBindingRequest    = 0x0001
+	stunBindingResponse   = 0x0101
+)
+
+// Prober measures the delay of an outbound. Probers are safe for concurrent use.
+type Prober interface {
+	// Network returns the network the probe is sent over.
+	Network() string
+	Probe(ctx context.Context, detour N.Dialer) (uint16, error)
+}
+
+// NewProber creates a prober from options. link is the URL used by the http
+// probe, which is also used when options is nil.
+func NewProber(options *option.HealthCheckProbeOptions, link string) (Prober, error) {
+	if options == nil || options.Type == "" || options.Type == ProbeTypeHTTP {
+		return &httpProber{link: link}, nil
+	}
+	server := M.ParseSocksaddrHostPort(options.Server, options.ServerPort)
+	switch options.Type {
+	case ProbeTypeTCP:
+		if !server.IsValid() || server.Port == 0 {
+			return nil, E.New("probe: missing server or server_port for tcp probe")
+		}
+		return &tcpProber{server: server}, nil
+	case ProbeTypeTLS:
+		if !server.IsValid() {
+			return nil, E.New("probe: missing server for tls probe")
+		}
+		if server.Port == 0 {
+			server.Port = 443
+		}
+		serverName := options.ServerName
+		if serverName == "" {
+			if !server.IsFqdn() {
+				return nil, E.New("probe: missing server_name for tls probe to an IP address")
+			}
+			serverName = server.Fqdn
+		}
+		return &tlsProber{server: server, serverName: serverName}, nil
+	case ProbeTypeDNS:
+		if !server.IsValid() {
+			server = defaultDNSProbeServer
+		} else if server.Port == 0 {
+			server.Port = 53
+		}
+		domain := options.Domain
+		if domain == "" {
+			domain = defaultDNSProbeDomain
+		}
+		return &dnsProber{server: server, domain: mDNS.Fqdn(domain)}, nil
+	case ProbeTypeUDPEcho:
+		if !server.IsValid() || server.Port == 0 {
+			return nil, E.New("probe: missing server or server_port for udp_echo probe")
+		}
+		payload := []byte(options.Payload)
+		if len(payload) == 0 {
+			payload = []byte("sing-box")
+		}
+		return &udpEchoProber{server: server, payload: payload}, nil
+	case ProbeTypeSTUN:
+		if !server.IsValid() {
+			server = defaultSTUNProbeServer
+		} else if server.Port == 0 {
+			server.Port = 3478
+		}
+		return &stunProber{server: server}, nil
+	default:
+		return nil, E.New("probe: unknown type: ", options.Type)
+	}
+}
+
+type httpProber struct {
+	link string
+}
+
+func (p *httpProber) Network() string {
+	return N.NetworkTCP
+}
+
+func (p *httpProber) Probe(ctx context.Context, detour N.Dialer) (uint16, error) {
+	return URLTest(ctx, p.link, detour)
+}
+
+type tcpProber struct {
+	server M.Socksaddr
+}
+
+func (p *tcpProber) Network() string {
+	return N.NetworkTCP
+}
+
+func (p *tcpProber) Probe(ctx context.Context, detour N.Dialer) (uint16, error) {
+	start := time.Now()
+	conn, err := detour.DialContext(ctx, N.NetworkTCP, p.server)
+	if err != nil {
+		return 0, err
+	}
+	conn.Close()
+	return delaySince(start), nil
+}
+
+type tlsProber struct {
+	server     M.Socksaddr
+	serverName string
+}
+
+func (p *tlsProber) Network() string {
+	return N.NetworkTCP
+}
+
+func (p *tlsProber) Probe(ctx context.Context, detour N.Dialer) (uint16, error) {
+	start := time.Now()
+	conn, err := detour.DialContext(ctx, N.NetworkTCP, p.server)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	if earlyConn, isEarlyConn := common.Cast[N.EarlyConn](conn); isEarlyConn && earlyConn.NeedHandshake() {
+		start = time.Now()
+	}
+	tlsConn := tls.Client(conn, &tls.Config{
+		ServerName: p.serverName,
+		Time:       ntp.TimeFuncFromContext(ctx),
+		RootCAs:    adapter.RootPoolFromContext(ctx),
+	})
+	err = tlsConn.HandshakeContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return delaySince(start), nil
+}
+
+type dnsProber struct {
+	server M.Socksaddr
+	domain string
+}
+
+func (p *dnsProber) Network() string {
+	return N.NetworkUDP
+}
+
+func (p *dnsProber) Probe(ctx context.Context, detour N.Dialer) (uint16, error) {
+	message := new(mDNS.Msg)
+	message.SetQuestion(p.domain, mDNS.TypeA)
+	request, err := message.Pack()
+	if err != nil {
+		return 0, err
+	}
+	return exchangePacket(ctx, detour, p.server, request, func(response []byte) error {
+		var responseMessage mDNS.Msg
+		err := responseMessage.Unpack(response)
+		if err != nil {
+			return err
+		}
+		if responseMessage.Id != message.Id {
+			return E.New("dns probe: unexpected message id")
+		}
+		return nil
+	})
+}
+
+type udpEchoProber struct {
+	server  M.Socksaddr
+	payload []byte
+}
+
+func (p *udpEchoProber) Network() string {
+	return N.NetworkUDP
+}
+
+func (p *udpEchoProber) Probe(ctx context.Context, detour N.Dialer) (uint16, error) {
+	return exchangePacket(ctx, detour, p.server, p.payload, func(response []byte) error {
+		if !bytes.Equal(response, p.payload) {
+			return E.New("udp echo probe: unexpected response")
+		}
+		return nil
+	})
+}
+
+type stunProber struct {
+	server M.Socksaddr
+}
+
+func (p *stunProber) Network() string {
+	return N.NetworkUDP
+}
+
+func (p *stunProber) Probe(ctx context.Context, detour N.Dialer) (uint16, error) {
+	request := make([]byte, 20)
+	binary.BigEndian.PutUint16(request[0:], stunBindingRequest)
+	binary.BigEndian.PutUint32(request[4:], stunMagicCookie)
+	_, err := rand.Read(request[8:])
+	if err != nil {
+		return 0, err
+	}
+	return exchangePacket(ctx, detour, p.server, request, func(response []byte) error {
+		if len(response) < 20 ||
+			binary.BigEndian.Uint16(response[0:]) != stunBindingResponse ||
+			binary.BigEndian.Uint32(response[4:]) != stunMagicCookie ||
+			!bytes.Equal(response[8:20], request[8:]) {
+			return E.New("stun probe: unexpected response")
+		}
+		return nil
+	})
+}
+
+// exchangePacket sends request to server through the UDP path of detour and
+// waits for a response accepted by check.
+func exchangePacket(ctx context.Context, detour N.Dialer, server M.Socksaddr, request []byte, check func(response []byte) error) (uint16, error) {
+	packetConn, err := detour.ListenPacket(ctx, server)
+	if err != nil {
+		return 0, err
+	}
+	// Plain UDP sockets only accept *net.UDPAddr destinations
+	var destination net.Addr = server
+	if server.IsIP() {
+		destination = server.UDPAddr()
+	}
+	conn := bufio.NewBindPacketConn(packetConn, destination)
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() {
+		conn.Close()
+	})
+	defer stop()
+	if deadline, loaded := ctx.Deadline(); loaded {
+		conn.SetDeadline(deadline)
+	}
+	start := time.Now()
+	_, err = conn.Write(request)
+	if err != nil {
+		return 0, probeError(ctx, err)
+	}
+	response := make([]byte, 1500)
+	for {
+		n, err := conn.Read(response)
+		if err != nil {
+			return 0, probeError(ctx, err)
+		}
+		err = check(response[:n])
+		if err == nil {
+			return delaySince(start), nil
+		}
+	}
+}
+
+func probeError(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// delaySince never returns 0, which is treated as a failed test by clients.
+func delaySince(start time.Time) uint16 {
+	delay := time.Since(start) / time.Millisecond
+	if delay == 0 {
+		delay = 1
+	}
+	return uint16(delay)
+}
@@ -0,0 +1,183 @@
+package urltest
+
+import (
+	"context"
+	stdTLS "crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing-box/option"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func startPacketServer(t *testing.T, handler func(request []byte) []byte) uint16 {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	go func() {
+		buffer := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			response := handler(buffer[:n])
+			if response != nil {
+				conn.WriteTo(response, addr)
+			}
+		}
+	}()
+	return uint16(conn.LocalAddr().(*net.UDPAddr).Port)
+}
+
+func testProbe(t *testing.T, options option.HealthCheckProbeOptions) error {
+	return testProbeContext(t, context.Background(), options)
+}
+
+func testProbeContext(t *testing.T, ctx context.Context, options option.HealthCheckProbeOptions) error {
+	prober, err := NewProber(&options, "")
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(ctx, 500*time.Millisecond)
+	defer cancel()
+	delay, err := prober.Probe(ctx, N.SystemDialer)
+	if err == nil {
+		require.NotZero(t, delay)
+	}
+	return err
+}
+
+func TestProbeTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	port := uint16(listener.Addr().(*net.TCPAddr).Port)
+	require.NoError(t, testProbe(t, option.HealthCheckProbeOptions{Type: ProbeTypeTCP, Server: "127.0.0.1", ServerPort: port}))
+	listener.Close()
+	require.Error(t, testProbe(t, option.HealthCheckProbeOptions{Type: ProbeTypeTCP, Server: "127.0.0.1", ServerPort: port}))
+}
+
+type testCertificateStore struct {
+	adapter.LifecycleService
+	pool *x509.CertPool
+}
+
+func (s *testCertificateStore) Pool() *x509.CertPool {
+	return s.pool
+}
+
+func TestProbeTLS(t *testing.T) {
+	certificate, err := tls.GenerateKeyPair(nil, nil, time.Now, "example.com")
+	require.NoError(t, err)
+	listener, err := stdTLS.Listen("tcp", "127.0.0.1:0", &stdTLS.Config{
+		Certificates: []stdTLS.Certificate{*certificate},
+	})
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				conn.(*stdTLS.Conn).Handshake()
+				conn.Close()
+			}()
+		}
+	}()
+	port := uint16(listener.Addr().(*net.TCPAddr).Port)
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+	require.NoError(t, err)
+	pool := x509.NewCertPool()
+	pool.AddCert(leaf)
+	ctx := service.ContextWith[adapter.CertificateStore](context.Background(), &testCertificateStore{pool: pool})
+
+	require.NoError(t, testProbeContext(t, ctx, option.HealthCheckProbeOptions{Type: ProbeTypeTLS, Server: "127.0.0.1", ServerPort: port, ServerName: "example.com"}))
+	// The server certificate is verified against server_name
+	require.Error(t, testProbeContext(t, ctx, option.HealthCheckProbeOptions{Type: ProbeTypeTLS, Server: "127.0.0.1", ServerPort: port, ServerName: "example.org"}))
+
+	// An IP address can not be used as the server name
+	_, err = NewProber(&option.HealthCheckProbeOptions{Type: ProbeTypeTLS, Server: "127.0.0.1", ServerPort: port}, "")
+	require.Error(t, err)
+}
+
+func TestProbeUDPEcho(t *testing.T) {
+	port := startPacketServer(t, func(request []byte) []byte {
+		return request
+	})
+	require.NoError(t, testProbe(t, option.HealthCheckProbeOptions{Type: ProbeTypeUDPEcho, Server: "127.0.0.1", ServerPort: port}))
+
+	silentPort := startPacketServer(t, func(request []byte) []byte {
+		return nil
+	})
+	require.Error(t, testProbe(t, option.HealthCheckProbeOptions{Type: ProbeTypeUDPEcho, Server: "127.0.0.1", ServerPort: silentPort}))
+}
+
+func TestProbeDNS(t *testing.T) {
+	port := startPacketServer(t, func(request []byte) []byte {
+		var message mDNS.Msg
+		if message.Unpack(request) != nil {
+			return nil
+		}
+		response := new(mDNS.Msg)
+		response.SetReply(&message)
+		responseBytes, _ := response.Pack()
+		return responseBytes
+	})
+	require.NoError(t, testProbe(t, option.HealthCheckProbeOptions{Type: ProbeTypeDNS, Server: "127.0.0.1", ServerPort: port}))
+}
+
+func TestProbeSTUN(t *testing.T) {
+	port := startPacketServer(t, func(request []byte) []byte {
+		if len(request) < 20 || binary.BigEndian.Uint16(request) != stunBindingRequest {
+			return nil
+		}
+		response := make([]byte, 20)
+		binary.BigEndian.PutUint16(response, stunBindingResponse)
+		copy(response[4:], request[4:20])
+		return response
+	})
+	require.NoError(t, testProbe(t, option.HealthCheckProbeOptions{Type: ProbeTypeSTUN, Server: "127.0.0.1", ServerPort: port}))
+
+	// Responses with a different transaction ID are ignored
+	badPort := startPacketServer(t, func(request []byte) []byte {
+		response := make([]byte, 20)
+		binary.BigEndian.PutUint16(response, stunBindingResponse)
+		binary.BigEndian.PutUint32(response[4:], stunMagicCookie)
+		return response
+	})
+	require.Error(t, testProbe(t, option.HealthCheckProbeOptions{Type: ProbeTypeSTUN, Server: "127.0.0.1", ServerPort: badPort}))
+}
+
+func TestProbeOptions(t *testing.T) {
+	_, err := NewProber(&option.HealthCheckProbeOptions{Type: "bad"}, "")
+	require.Error(t, err)
+	_, err = NewProber(&option.HealthCheckProbeOptions{Type: ProbeTypeTCP}, "")
+	require.Error(t, err)
+	prober, err := NewProber(nil, "")
+	require.NoError(t, err)
+	require.Equal(t, N.NetworkTCP, prober.Network())
+	prober, err = NewProber(&option.HealthCheckProbeOptions{Type: ProbeTypeSTUN}, "")
+	require.NoError(t, err)
+	require.Equal(t, N.NetworkUDP, prober.Network())
+}
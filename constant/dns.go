@@ -33,4 +33,6 @@ const (
 const (
 	DNSProviderAliDNS     = "alidns"
 	DNSProviderCloudflare = "cloudflare"
+	DNSProviderRFC2136    = "rfc2136"
+	DNSProviderWebhook    = "webhook"
 )
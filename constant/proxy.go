@@ -35,6 +35,7 @@ const (
 	TypeSelector    = "selector"
 	TypeURLTest     = "urltest"
 	TypeLoadBalance = "loadbalance"
+	TypeFallback    = "fallback"
 )
 
 func ProxyDisplayName(proxyType string) string {
@@ -93,6 +94,8 @@ func ProxyDisplayName(proxyType string) string {
 		return "URLTest"
 	case TypeLoadBalance:
 		return "LoadBalance"
+	case TypeFallback:
+		return "Fallback"
 	default:
 		return "Unknown"
 	}
@@ -30,6 +30,7 @@ var (
 		string(bucketMode),
 		string(bucketRuleSet),
 		string(bucketRDRC),
+		string(bucketStickySession),
 	}
 
 	cacheIDDefault = []byte("default")
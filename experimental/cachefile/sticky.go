@@ -0,0 +1,77 @@
+package cachefile
+
+import (
+	"encoding/binary"
+	"time"
+
+	"github.com/sagernet/bbolt"
+	"github.com/sagernet/sing-box/adapter"
+)
+
+var bucketStickySession = []byte("sticky_session")
+
+func (c *CacheFile) LoadStickySessions(group string) map[string]adapter.SavedStickySession {
+	sessions := make(map[string]adapter.SavedStickySession)
+	var expiredKeys [][]byte
+	now := time.Now()
+	c.DB.View(func(tx *bbolt.Tx) error {
+		bucket := c.bucket(tx, bucketStickySession)
+		if bucket == nil {
+			return nil
+		}
+		bucket = bucket.Bucket([]byte(group))
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(key, content []byte) error {
+			if len(content) <= 8 {
+				expiredKeys = append(expiredKeys, append([]byte(nil), key...))
+				return nil
+			}
+			expiresAt := time.Unix(int64(binary.BigEndian.Uint64(content)), 0)
+			if now.After(expiresAt) {
+				expiredKeys = append(expiredKeys, append([]byte(nil), key...))
+				return nil
+			}
+			sessions[string(key)] = adapter.SavedStickySession{
+				Outbound:  string(content[8:]),
+				ExpiresAt: expiresAt,
+			}
+			return nil
+		})
+	})
+	if len(expiredKeys) > 0 {
+		c.DB.Batch(func(tx *bbolt.Tx) error {
+			bucket := c.bucket(tx, bucketStickySession)
+			if bucket == nil {
+				return nil
+			}
+			bucket = bucket.Bucket([]byte(group))
+			if bucket == nil {
+				return nil
+			}
+			for _, key := range expiredKeys {
+				bucket.Delete(key)
+			}
+			return nil
+		})
+	}
+	return sessions
+}
+
+func (c *CacheFile) StoreStickySession(group string, key string, session adapter.SavedStickySession) error {
+	return c.DB.Batch(func(tx *bbolt.Tx) error {
+		bucket, err := c.createBucket(tx, bucketStickySession)
+		if err != nil {
+			return err
+		}
+		bucket, err = bucket.CreateBucketIfNotExists([]byte(group))
+		if err != nil {
+			return err
+		}
+		content := make([]byte, 8+len(session.Outbound))
+		binary.BigEndian.PutUint64(content, uint64(session.ExpiresAt.Unix()))
+		copy(content[8:], session.Outbound)
+		return bucket.Put([]byte(key), content)
+	})
+}
@@ -29,6 +29,7 @@ func (s *Server) setupMetaAPI(r chi.Router) {
 	}
 	r.Get("/memory", memory(s.trafficManager))
 	r.Mount("/group", groupRouter(s))
+	r.Mount("/router", routerInboundRouter(s))
 	r.Mount("/upgrade", upgradeRouter(s))
 }
 
@@ -0,0 +1,51 @@
+package clashapi
+
+import (
+	"net/http"
+
+	"github.com/sagernet/sing-box/adapter"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+func routerInboundRouter(server *Server) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", getRouterInbounds(server))
+	r.Get("/{tag}", getRouterInbound(server))
+	return r
+}
+
+func getRouterInbounds(server *Server) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		routers := make(map[string]*adapter.RouterMetrics)
+		if server.inbound != nil {
+			for _, inbound := range server.inbound.Inbounds() {
+				if routerInbound, isRouter := inbound.(adapter.RouterInbound); isRouter {
+					routers[inbound.Tag()] = routerInbound.RouterMetrics()
+				}
+			}
+		}
+		render.JSON(w, r, render.M{
+			"routers": routers,
+		})
+	}
+}
+
+func getRouterInbound(server *Server) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tag := getEscapeParam(r, "tag")
+		var routerInbound adapter.RouterInbound
+		if server.inbound != nil {
+			if inbound, loaded := server.inbound.Get(tag); loaded {
+				routerInbound, _ = inbound.(adapter.RouterInbound)
+			}
+		}
+		if routerInbound == nil {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, ErrNotFound)
+			return
+		}
+		render.JSON(w, r, routerInbound.RouterMetrics())
+	}
+}
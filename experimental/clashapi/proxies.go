@@ -29,6 +29,8 @@ func proxyRouter(server *Server, router adapter.Router) http.Handler {
 		r.Use(parseProxyName, findProxyByName(server))
 		r.Get("/", getProxy(server))
 		r.Get("/delay", getProxyDelay(server))
+		r.Get("/pool", getProxyConnectionPool)
+		r.Post("/pool/reset", resetProxyConnectionPool)
 		r.Put("/", updateProxy)
 	})
 	return r
@@ -80,6 +82,11 @@ func proxyInfo(server *Server, detour adapter.Outbound) *badjson.JSONObject {
 		info.Put("now", group.Now())
 		info.Put("all", group.All())
 	}
+	if group, isGroup := detour.(adapter.OutlierDetectionGroup); isGroup {
+		if outlierStatus := group.OutlierStatus(); outlierStatus != nil {
+			info.Put("outlier", outlierStatus)
+		}
+	}
 	return &info
 }
 
@@ -184,6 +191,36 @@ func updateProxy(w http.ResponseWriter, r *http.Request) {
 	render.NoContent(w, r)
 }
 
+func proxyConnectionPool(r *http.Request) adapter.ConnectionPool {
+	proxy := r.Context().Value(CtxKeyProxy).(adapter.Outbound)
+	poolOutbound, isPoolOutbound := proxy.(adapter.ConnectionPoolOutbound)
+	if !isPoolOutbound {
+		return nil
+	}
+	return poolOutbound.ConnectionPool()
+}
+
+func getProxyConnectionPool(w http.ResponseWriter, r *http.Request) {
+	pool := proxyConnectionPool(r)
+	if pool == nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, ErrNotFound)
+		return
+	}
+	render.JSON(w, r, pool.Status())
+}
+
+func resetProxyConnectionPool(w http.ResponseWriter, r *http.Request) {
+	pool := proxyConnectionPool(r)
+	if pool == nil {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, ErrNotFound)
+		return
+	}
+	pool.Reset()
+	render.NoContent(w, r)
+}
+
 func getProxyDelay(server *Server) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		query := r.URL.Query()
@@ -17,4 +17,7 @@ const (
 	CommandConnections
 	CommandCloseConnection
 	CommandGetDeprecatedNotes
+	CommandTuneLoadBalance
+	CommandGetConnectionPoolStatus
+	CommandResetConnectionPool
 )
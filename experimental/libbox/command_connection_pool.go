@@ -0,0 +1,128 @@
+package libbox
+
+import (
+	"encoding/binary"
+	"net"
+
+	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/varbin"
+)
+
+type ConnectionPoolStatus struct {
+	EnsureIdle        int64
+	Idle              int64
+	InUse             int64
+	Dialing           int64
+	Hits              int64
+	Misses            int64
+	Created           int64
+	CreateFailures    int64
+	ClosedIdleTimeout int64
+	ClosedLifetime    int64
+	HeartbeatFailures int64
+}
+
+func (c *CommandClient) GetConnectionPoolStatus(outboundTag string) (*ConnectionPoolStatus, error) {
+	conn, err := c.directConnect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	err = binary.Write(conn, binary.BigEndian, uint8(CommandGetConnectionPoolStatus))
+	if err != nil {
+		return nil, err
+	}
+	err = varbin.Write(conn, binary.BigEndian, outboundTag)
+	if err != nil {
+		return nil, err
+	}
+	err = readError(conn)
+	if err != nil {
+		return nil, err
+	}
+	var status adapter.ConnectionPoolStatus
+	err = binary.Read(conn, binary.BigEndian, &status)
+	if err != nil {
+		return nil, err
+	}
+	return &ConnectionPoolStatus{
+		EnsureIdle:        status.EnsureIdle,
+		Idle:              status.Idle,
+		InUse:             status.InUse,
+		Dialing:           status.Dialing,
+		Hits:              status.Hits,
+		Misses:            status.Misses,
+		Created:           status.Created,
+		CreateFailures:    status.CreateFailures,
+		ClosedIdleTimeout: status.ClosedIdleTimeout,
+		ClosedLifetime:    status.ClosedLifetime,
+		HeartbeatFailures: status.HeartbeatFailures,
+	}, nil
+}
+
+func (s *CommandServer) handleGetConnectionPoolStatus(conn net.Conn) error {
+	pool, err := s.readConnectionPool(conn)
+	if err != nil {
+		return err
+	}
+	if pool == nil {
+		return nil
+	}
+	err = writeError(conn, nil)
+	if err != nil {
+		return err
+	}
+	return binary.Write(conn, binary.BigEndian, pool.Status())
+}
+
+func (c *CommandClient) ResetConnectionPool(outboundTag string) error {
+	conn, err := c.directConnect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = binary.Write(conn, binary.BigEndian, uint8(CommandResetConnectionPool))
+	if err != nil {
+		return err
+	}
+	err = varbin.Write(conn, binary.BigEndian, outboundTag)
+	if err != nil {
+		return err
+	}
+	return readError(conn)
+}
+
+func (s *CommandServer) handleResetConnectionPool(conn net.Conn) error {
+	pool, err := s.readConnectionPool(conn)
+	if err != nil {
+		return err
+	}
+	if pool == nil {
+		return nil
+	}
+	pool.Reset()
+	return writeError(conn, nil)
+}
+
+// readConnectionPool reads an outbound tag and looks up its connection pool.
+// If it is not found, the error is written to conn and the pool is nil.
+func (s *CommandServer) readConnectionPool(conn net.Conn) (adapter.ConnectionPool, error) {
+	outboundTag, err := varbin.ReadValue[string](conn, binary.BigEndian)
+	if err != nil {
+		return nil, err
+	}
+	service := s.service
+	if service == nil {
+		return nil, writeError(conn, E.New("service not ready"))
+	}
+	outbound, isLoaded := service.instance.Outbound().Outbound(outboundTag)
+	if !isLoaded {
+		return nil, writeError(conn, E.New("outbound not found: ", outboundTag))
+	}
+	poolOutbound, isPoolOutbound := outbound.(adapter.ConnectionPoolOutbound)
+	if !isPoolOutbound || poolOutbound.ConnectionPool() == nil {
+		return nil, writeError(conn, E.New("connection pool not enabled: ", outboundTag))
+	}
+	return poolOutbound.ConnectionPool(), nil
+}
@@ -0,0 +1,64 @@
+package libbox
+
+import (
+	"encoding/binary"
+	"net"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/varbin"
+)
+
+// TuneLoadBalance changes the parameters of a running load balance group.
+// options is a JSON object with the optional fields top_n, strategy,
+// key_parts and hysteresis.
+func (c *CommandClient) TuneLoadBalance(groupTag string, options string) error {
+	conn, err := c.directConnect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = binary.Write(conn, binary.BigEndian, uint8(CommandTuneLoadBalance))
+	if err != nil {
+		return err
+	}
+	err = varbin.Write(conn, binary.BigEndian, groupTag)
+	if err != nil {
+		return err
+	}
+	err = varbin.Write(conn, binary.BigEndian, options)
+	if err != nil {
+		return err
+	}
+	return readError(conn)
+}
+
+func (s *CommandServer) handleTuneLoadBalance(conn net.Conn) error {
+	groupTag, err := varbin.ReadValue[string](conn, binary.BigEndian)
+	if err != nil {
+		return err
+	}
+	optionsContent, err := varbin.ReadValue[string](conn, binary.BigEndian)
+	if err != nil {
+		return err
+	}
+	service := s.service
+	if service == nil {
+		return writeError(conn, E.New("service not ready"))
+	}
+	outboundGroup, isLoaded := service.instance.Outbound().Outbound(groupTag)
+	if !isLoaded {
+		return writeError(conn, E.New("load balance group not found: ", groupTag))
+	}
+	loadBalanceGroup, isLoadBalance := outboundGroup.(adapter.LoadBalanceGroup)
+	if !isLoadBalance {
+		return writeError(conn, E.New("outbound is not a load balance group: ", groupTag))
+	}
+	options, err := json.UnmarshalExtended[option.LoadBalanceTuningOptions]([]byte(optionsContent))
+	if err != nil {
+		return writeError(conn, E.Cause(err, "decode options"))
+	}
+	return writeError(conn, loadBalanceGroup.Tune(options))
+}
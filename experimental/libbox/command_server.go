@@ -176,6 +176,12 @@ func (s *CommandServer) handleConnection(conn net.Conn) error {
 		return s.handleCloseConnection(conn)
 	case CommandGetDeprecatedNotes:
 		return s.handleGetDeprecatedNotes(conn)
+	case CommandTuneLoadBalance:
+		return s.handleTuneLoadBalance(conn)
+	case CommandGetConnectionPoolStatus:
+		return s.handleGetConnectionPoolStatus(conn)
+	case CommandResetConnectionPool:
+		return s.handleResetConnectionPool(conn)
 	default:
 		return E.New("unknown command: ", command)
 	}
@@ -5,6 +5,7 @@ package include
 import (
 	"context"
 	"io"
+	"net"
 	"net/http"
 
 	"github.com/sagernet/sing-box/adapter"
@@ -17,6 +18,7 @@ import (
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-box/protocol/naive"
+	"github.com/sagernet/sing-box/protocol/router"
 	"github.com/sagernet/sing-box/transport/v2ray"
 	"github.com/sagernet/sing/common/logger"
 	M "github.com/sagernet/sing/common/metadata"
@@ -47,6 +49,9 @@ func registerQUICInbounds(registry *inbound.Registry) {
 	naive.ConfigureHTTP3ListenerFunc = func(listener *listener.Listener, handler http.Handler, tlsConfig tls.ServerConfig, logger logger.Logger) (io.Closer, error) {
 		return nil, C.ErrQUICNotIncluded
 	}
+	router.ConfigureHTTP3ListenerFunc = func(udpConn net.PacketConn, handler http.Handler, tlsConfig tls.ServerConfig, logger logger.Logger) (io.Closer, error) {
+		return nil, C.ErrQUICNotIncluded
+	}
 }
 
 func registerQUICOutbounds(registry *outbound.Registry) {
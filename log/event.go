@@ -1,6 +1,8 @@
 package log
 
 import (
+	"time"
+
 	"github.com/miekg/dns"
 	M "github.com/sagernet/sing/common/metadata"
 )
@@ -67,6 +69,19 @@ type RouterMatchEvent struct {
 	Action      string `json:"action"`
 	Outbound    string `json:"outbound,omitempty"`
 	Matched     bool   `json:"matched"`
+
+	// Request details, set by the router inbound access log
+	Inbound       string `json:"inbound,omitempty"`
+	Target        string `json:"target,omitempty"`
+	Source        string `json:"source,omitempty"`
+	SourcePort    uint16 `json:"source_port,omitempty"`
+	Method        string `json:"method,omitempty"`
+	Host          string `json:"host,omitempty"`
+	Path          string `json:"path,omitempty"`
+	Status        int    `json:"status,omitempty"`
+	UploadBytes   int64  `json:"upload_bytes,omitempty"`
+	DownloadBytes int64  `json:"download_bytes,omitempty"`
+	DurationMs    int64  `json:"duration_ms,omitempty"`
 }
 
 // TransferEvent represents data transfer progress
@@ -261,6 +276,60 @@ func (e *RouterMatchEvent) WithMatched(matched bool) *RouterMatchEvent {
 	return e
 }
 
+// WithInbound sets the inbound that evaluated the rule
+func (e *RouterMatchEvent) WithInbound(tag string) *RouterMatchEvent {
+	e.Inbound = tag
+	return e
+}
+
+// WithTarget sets the inbound the request was forwarded to
+func (e *RouterMatchEvent) WithTarget(target string) *RouterMatchEvent {
+	if target != "" {
+		e.Target = target
+	}
+	return e
+}
+
+// WithSource sets the source address
+func (e *RouterMatchEvent) WithSource(addr M.Socksaddr) *RouterMatchEvent {
+	if addr.IsValid() {
+		e.Source = addr.Addr.String()
+		e.SourcePort = addr.Port
+	}
+	return e
+}
+
+// WithRequest sets the HTTP request line, or the TLS server name as host
+func (e *RouterMatchEvent) WithRequest(method, host, path string) *RouterMatchEvent {
+	e.Method = method
+	e.Host = host
+	e.Path = path
+	return e
+}
+
+// WithStatus sets the HTTP response status
+func (e *RouterMatchEvent) WithStatus(status int) *RouterMatchEvent {
+	e.Status = status
+	return e
+}
+
+// WithTransferStats sets transfer statistics
+func (e *RouterMatchEvent) WithTransferStats(upload, download int64) *RouterMatchEvent {
+	if upload > 0 {
+		e.UploadBytes = upload
+	}
+	if download > 0 {
+		e.DownloadBytes = download
+	}
+	return e
+}
+
+// WithDuration sets how long the request took
+func (e *RouterMatchEvent) WithDuration(duration time.Duration) *RouterMatchEvent {
+	e.DurationMs = duration.Milliseconds()
+	return e
+}
+
 // WithBytes sets the transfer bytes
 func (e *TransferEvent) WithBytes(bytes int64) *TransferEvent {
 	e.Bytes = bytes
@@ -369,6 +438,37 @@ func (e *RouterMatchEvent) ToMap() map[string]interface{} {
 		m["outbound"] = e.Outbound
 	}
 	m["matched"] = e.Matched
+	if e.Inbound != "" {
+		m["inbound"] = e.Inbound
+	}
+	if e.Target != "" {
+		m["target"] = e.Target
+	}
+	if e.Source != "" {
+		m["source"] = e.Source
+		m["source_port"] = e.SourcePort
+	}
+	if e.Method != "" {
+		m["method"] = e.Method
+	}
+	if e.Host != "" {
+		m["host"] = e.Host
+	}
+	if e.Path != "" {
+		m["path"] = e.Path
+	}
+	if e.Status != 0 {
+		m["status"] = e.Status
+	}
+	if e.UploadBytes > 0 {
+		m["upload_bytes"] = e.UploadBytes
+	}
+	if e.DownloadBytes > 0 {
+		m["download_bytes"] = e.DownloadBytes
+	}
+	if e.DurationMs > 0 {
+		m["duration_ms"] = e.DurationMs
+	}
 	return m
 }
 
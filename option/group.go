@@ -4,8 +4,9 @@ import "github.com/sagernet/sing/common/json/badoption"
 
 type SelectorOutboundOptions struct {
 	Outbounds                 []string `json:"outbounds"`
-	Default                   string   `json:"default,omitempty"`
-	InterruptExistConnections bool     `json:"interrupt_exist_connections,omitempty"`
+	Default                   string                   `json:"default,omitempty"`
+	OutlierDetection          *OutlierDetectionOptions `json:"outlier_detection,omitempty"`
+	InterruptExistConnections bool                     `json:"interrupt_exist_connections,omitempty"`
 }
 
 type URLTestOutboundOptions struct {
@@ -13,8 +14,25 @@ type URLTestOutboundOptions struct {
 	URL                       string             `json:"url,omitempty"`
 	Interval                  badoption.Duration `json:"interval,omitempty"`
 	Tolerance                 uint16             `json:"tolerance,omitempty"`
-	IdleTimeout               badoption.Duration `json:"idle_timeout,omitempty"`
-	InterruptExistConnections bool               `json:"interrupt_exist_connections,omitempty"`
+	IdleTimeout               badoption.Duration       `json:"idle_timeout,omitempty"`
+	Probe                     *HealthCheckProbeOptions `json:"probe,omitempty"`
+	UDPProbe                  *HealthCheckProbeOptions `json:"udp_probe,omitempty"`
+	OutlierDetection          *OutlierDetectionOptions `json:"outlier_detection,omitempty"`
+	Retry                     *GroupRetryOptions       `json:"retry,omitempty"`
+	InterruptExistConnections bool                     `json:"interrupt_exist_connections,omitempty"`
+}
+
+type FallbackOutboundOptions struct {
+	Outbounds                 []string                 `json:"outbounds"`
+	URL                       string                   `json:"url,omitempty"`
+	Interval                  badoption.Duration       `json:"interval,omitempty"`
+	IdleTimeout               badoption.Duration       `json:"idle_timeout,omitempty"`
+	PreemptDelay              badoption.Duration       `json:"preempt_delay,omitempty"`
+	Probe                     *HealthCheckProbeOptions `json:"probe,omitempty"`
+	UDPProbe                  *HealthCheckProbeOptions `json:"udp_probe,omitempty"`
+	OutlierDetection          *OutlierDetectionOptions `json:"outlier_detection,omitempty"`
+	Retry                     *GroupRetryOptions       `json:"retry,omitempty"`
+	InterruptExistConnections bool                     `json:"interrupt_exist_connections,omitempty"`
 }
 
 type LoadBalanceOutboundOptions struct {
@@ -24,14 +42,38 @@ type LoadBalanceOutboundOptions struct {
 	Interval                  badoption.Duration               `json:"interval,omitempty"`
 	Timeout                   badoption.Duration               `json:"timeout,omitempty"`
 	IdleTimeout               badoption.Duration               `json:"idle_timeout,omitempty"`
+	Probe                     *HealthCheckProbeOptions         `json:"probe,omitempty"`
+	UDPProbe                  *HealthCheckProbeOptions         `json:"udp_probe,omitempty"`
 	TopN                      LoadBalanceTopNOptions           `json:"top_n"`
 	Strategy                  string                           `json:"strategy"`
+	Weights                   map[string]uint32                `json:"weights,omitempty"`
 	Hash                      *LoadBalanceHashOptions          `json:"hash,omitempty"`
+	LatencyWeighted           *LoadBalanceLatencyWeightedOptions `json:"latency_weighted,omitempty"`
 	Hysteresis                *LoadBalanceHysteresisOptions    `json:"hysteresis,omitempty"`
 	EmptyPoolAction           string                           `json:"empty_pool_action,omitempty"`
+	OutlierDetection          *OutlierDetectionOptions         `json:"outlier_detection,omitempty"`
+	Retry                     *GroupRetryOptions               `json:"retry,omitempty"`
 	InterruptExistConnections bool                             `json:"interrupt_exist_connections,omitempty"`
 }
 
+// HealthCheckProbeOptions selects how group members are health checked.
+//
+// Supported types:
+//   - "http": HTTP HEAD request to url (default)
+//   - "tcp": TCP connect to server:server_port
+//   - "tls": TLS handshake with server:server_port (default port 443), using server_name as SNI
+//   - "dns": A query for domain sent to server:server_port (default 1.1.1.1:53) over UDP
+//   - "udp_echo": payload sent to server:server_port over UDP, which must be echoed back
+//   - "stun": STUN binding request sent to server:server_port (default stun.l.google.com:19302)
+type HealthCheckProbeOptions struct {
+	Type       string `json:"type,omitempty"`
+	Server     string `json:"server,omitempty"`
+	ServerPort uint16 `json:"server_port,omitempty"`
+	ServerName string `json:"server_name,omitempty"`
+	Domain     string `json:"domain,omitempty"`
+	Payload    string `json:"payload,omitempty"`
+}
+
 type LoadBalanceTopNOptions struct {
 	Primary int `json:"primary"`
 	Backup  int `json:"backup,omitempty"`
@@ -87,13 +129,72 @@ type LoadBalanceTopNOptions struct {
 //   - Port numbers stripped (example.com:443 → example.com)
 //   - IP addresses return "-" (not applicable)
 type LoadBalanceHashOptions struct {
-	KeyParts     []string `json:"key_parts,omitempty"`
-	VirtualNodes int      `json:"virtual_nodes,omitempty"`
-	OnEmptyKey   string   `json:"on_empty_key,omitempty"`
-	KeySalt      string   `json:"key_salt,omitempty"`
+	KeyParts     []string                  `json:"key_parts,omitempty"`
+	VirtualNodes int                       `json:"virtual_nodes,omitempty"`
+	OnEmptyKey   string                    `json:"on_empty_key,omitempty"`
+	KeySalt      string                    `json:"key_salt,omitempty"`
+	Sticky       *LoadBalanceStickyOptions `json:"sticky,omitempty"`
+}
+
+// LoadBalanceStickyOptions pins hash keys to the outbound they were first
+// assigned to. An assignment is kept while that outbound stays in the candidate
+// pool and the key is seen again within ttl; otherwise the key is rehashed.
+type LoadBalanceStickyOptions struct {
+	TTL        badoption.Duration `json:"ttl,omitempty"`
+	MaxEntries uint32             `json:"max_entries,omitempty"`
+	Persist    bool               `json:"persist,omitempty"`
+}
+
+// LoadBalanceLatencyWeightedOptions configures the latency_weighted strategy.
+//
+// Each candidate is picked with probability proportional to 1 / delay^exponent,
+// where delay is an EWMA of recent probe results. smoothing is the weight of the
+// newest sample (1 disables smoothing).
+type LoadBalanceLatencyWeightedOptions struct {
+	Exponent  float64 `json:"exponent,omitempty"`
+	Smoothing float64 `json:"smoothing,omitempty"`
 }
 
 type LoadBalanceHysteresisOptions struct {
 	PrimaryFailures uint32             `json:"primary_failures,omitempty"`
 	BackupHoldTime  badoption.Duration `json:"backup_hold_time,omitempty"`
 }
+
+// LoadBalanceTuningOptions changes the parameters of a running load balance
+// group through the Clash API or libbox. Omitted fields keep their current value.
+type LoadBalanceTuningOptions struct {
+	TopN       *LoadBalanceTopNOptions       `json:"top_n,omitempty"`
+	Strategy   string                        `json:"strategy,omitempty"`
+	KeyParts   []string                      `json:"key_parts,omitempty"`
+	Hysteresis *LoadBalanceHysteresisOptions `json:"hysteresis,omitempty"`
+}
+
+// OutlierDetectionOptions configures passive health checking from real traffic.
+//
+// A member is ejected when it fails `failures` dials within `interval`, or when
+// `failure_rate` percent of at least `minimum_requests` dials within `interval`
+// fail. Ejection lasts base_ejection_time multiplied by the number of
+// consecutive ejections, capped at max_ejection_time.
+type OutlierDetectionOptions struct {
+	Failures         uint32             `json:"failures,omitempty"`
+	Interval         badoption.Duration `json:"interval,omitempty"`
+	FailureRate      uint8              `json:"failure_rate,omitempty"`
+	MinimumRequests  uint32             `json:"minimum_requests,omitempty"`
+	BaseEjectionTime badoption.Duration `json:"base_ejection_time,omitempty"`
+	MaxEjectionTime  badoption.Duration `json:"max_ejection_time,omitempty"`
+}
+
+// GroupRetryOptions configures transparent re-dialing through other group
+// members when a dial fails before any payload has been written.
+//
+// Supported retry_on values:
+//   - "any": Any error except cancellation by the caller (default)
+//   - "timeout": Dial timeouts, including attempt_timeout expiry
+//   - "connection_refused": Connection refused by the server
+//   - "connection_reset": Connection reset during the dial
+//   - "network_unreachable": Network or host unreachable
+type GroupRetryOptions struct {
+	MaxAttempts    uint32                     `json:"max_attempts,omitempty"`
+	AttemptTimeout badoption.Duration         `json:"attempt_timeout,omitempty"`
+	RetryOn        badoption.Listable[string] `json:"retry_on,omitempty"`
+}
@@ -86,11 +86,37 @@ type DialerOptions struct {
 	NetworkType         badoption.Listable[InterfaceType] `json:"network_type,omitempty"`
 	FallbackNetworkType badoption.Listable[InterfaceType] `json:"fallback_network_type,omitempty"`
 	FallbackDelay       badoption.Duration                `json:"fallback_delay,omitempty"`
+	ConnectionPool      *ConnectionPoolOptions            `json:"connection_pool,omitempty"`
 
 	// Deprecated: migrated to domain resolver
 	DomainStrategy DomainStrategy `json:"domain_strategy,omitempty"`
 }
 
+type ConnectionPoolOptions struct {
+	SessionPoolOptions
+	Adaptive *ConnectionPoolAdaptiveOptions `json:"adaptive,omitempty"`
+}
+
+// SessionPoolOptions are the pool fields shared with the session pool of the
+// anytls outbound
+type SessionPoolOptions struct {
+	EnsureIdleSession           int                `json:"ensure_idle_session,omitempty"`
+	EnsureIdleSessionCreateRate int                `json:"ensure_idle_session_create_rate,omitempty"`
+	MinIdleSession              int                `json:"min_idle_session,omitempty"`
+	MinIdleSessionForAge        int                `json:"min_idle_session_for_age,omitempty"`
+	IdleSessionCheckInterval    badoption.Duration `json:"idle_session_check_interval,omitempty"`
+	IdleSessionTimeout          badoption.Duration `json:"idle_session_timeout,omitempty"`
+	MaxConnectionLifetime       badoption.Duration `json:"max_connection_lifetime,omitempty"`
+	ConnectionLifetimeJitter    badoption.Duration `json:"connection_lifetime_jitter,omitempty"`
+	Heartbeat                   badoption.Duration `json:"heartbeat,omitempty"`
+}
+
+type ConnectionPoolAdaptiveOptions struct {
+	MinEnsureIdleSession int                `json:"min_ensure_idle_session,omitempty"`
+	MaxEnsureIdleSession int                `json:"max_ensure_idle_session,omitempty"`
+	Window               badoption.Duration `json:"window,omitempty"`
+}
+
 type _DomainResolveOptions struct {
 	Server       string                `json:"server"`
 	Strategy     DomainStrategy        `json:"strategy,omitempty"`
@@ -1,6 +1,9 @@
 package option
 
 import (
+	"net/netip"
+
+	"github.com/sagernet/sing/common/auth"
 	"github.com/sagernet/sing/common/json/badoption"
 )
 
@@ -9,18 +12,53 @@ type RouterInboundOptions struct {
 	ListenOptions
 	InboundTLSOptionsContainer
 	Routes             []RouteRule      `json:"routes"`
+	RoutesPath         string           `json:"routes_path,omitempty"` // JSON or YAML file with more routes, reloaded on change
 	Fallback           *FallbackOptions `json:"fallback,omitempty"`
 	Timeout            *TimeoutOptions  `json:"timeout,omitempty"`
 	MaxRequestBodySize int64            `json:"max_request_body_size,omitempty"` // In bytes
+	HTTP2              bool             `json:"http2,omitempty"`                 // Negotiate h2 with TLS ALPN
+	HTTP3              bool             `json:"http3,omitempty"`                 // Also serve HTTP/3 on the UDP port
+	// Read PROXY protocol v1/v2 headers on the listener
+	AcceptProxyProtocol  bool                             `json:"accept_proxy_protocol,omitempty"`
+	ProxyProtocolTrusted badoption.Listable[netip.Prefix] `json:"proxy_protocol_trusted,omitempty"` // Sources allowed to send headers, loopback only if empty
 }
 
 // RouteRule defines a single routing rule
 type RouteRule struct {
-	Name            string     `json:"name"`
-	Match           RouteMatch `json:"match"`
-	Target          string     `json:"target"`           // Target inbound tag (must be internal-only inbound)
-	StripPathPrefix string     `json:"strip_path_prefix,omitempty"`
-	Priority        int        `json:"priority,omitempty"` // Higher = evaluated first
+	Name            string                 `json:"name"`
+	Match           RouteMatch             `json:"match"`
+	Target          string                 `json:"target"` // Target inbound tag (must be internal-only inbound)
+	StripPathPrefix string                 `json:"strip_path_prefix,omitempty"`
+	Priority        int                    `json:"priority,omitempty"` // Higher = evaluated first
+	Auth            *RouteAuthOptions      `json:"auth,omitempty"`
+	RateLimit       *RouteRateLimitOptions `json:"rate_limit,omitempty"`
+	Rewrite         *RouteRewriteOptions   `json:"rewrite,omitempty"`
+}
+
+// RouteAuthOptions requires credentials before a route is forwarded
+type RouteAuthOptions struct {
+	Users        []auth.User `json:"users,omitempty"`         // Basic authentication
+	BearerTokens []string    `json:"bearer_tokens,omitempty"` // Authorization: Bearer <token>
+	Realm        string      `json:"realm,omitempty"`
+	StatusCode   int         `json:"status_code,omitempty"` // Default 401
+}
+
+// RouteRateLimitOptions limits requests and connections per source IP
+type RouteRateLimitOptions struct {
+	Rate           float64 `json:"rate,omitempty"` // Requests per second
+	Burst          int     `json:"burst,omitempty"`
+	MaxConnections int     `json:"max_connections,omitempty"` // Concurrent connections
+	StatusCode     int     `json:"status_code,omitempty"`     // Default 429
+}
+
+// RouteRewriteOptions modifies a request before it is forwarded
+type RouteRewriteOptions struct {
+	PathRegex    string                                `json:"path_regex,omitempty"`
+	PathReplace  string                                `json:"path_replace,omitempty"` // Replacement of path_regex matches, may reference groups as $1
+	Host         string                                `json:"host,omitempty"`
+	SetHeader    map[string]string                     `json:"set_header,omitempty"`
+	AddHeader    map[string]badoption.Listable[string] `json:"add_header,omitempty"`
+	RemoveHeader badoption.Listable[string]            `json:"remove_header,omitempty"`
 }
 
 // RouteMatch defines matching criteria
@@ -30,15 +68,22 @@ type RouteMatch struct {
 	Host       []string            `json:"host,omitempty"`
 	Header     map[string][]string `json:"header,omitempty"`
 	Method     []string            `json:"method,omitempty"`
+	SNI        []string            `json:"sni,omitempty"`  // TLS server name, matched before TLS termination
+	ALPN       []string            `json:"alpn,omitempty"` // TLS ALPN offered by the client
 }
 
 // FallbackOptions defines fallback behavior
 type FallbackOptions struct {
-	Type       string   `json:"type"`                  // static, drop, reject, inbound
+	Type       string   `json:"type"`                  // static, drop, reject, inbound, proxy
 	Webroot    string   `json:"webroot,omitempty"`     // For static type
 	Index      []string `json:"index,omitempty"`       // Default index files
 	StatusCode int      `json:"status_code,omitempty"` // For reject type
 	Target     string   `json:"target,omitempty"`      // For inbound type
+	URL        string   `json:"url,omitempty"`         // For proxy type: upstream base URL
+	Detour     string   `json:"detour,omitempty"`      // For proxy type: outbound used to reach the upstream
+	Host       string   `json:"host,omitempty"`        // For proxy type: Host header sent upstream
+	// For proxy type: PROXY protocol version (1 or 2) sent to the upstream
+	ProxyProtocol uint8 `json:"proxy_protocol,omitempty"`
 }
 
 // TimeoutOptions for HTTP server
@@ -14,9 +14,11 @@ type InboundTLSOptions struct {
 	CertificatePath string                     `json:"certificate_path,omitempty"`
 	Key             badoption.Listable[string] `json:"key,omitempty"`
 	KeyPath         string                     `json:"key_path,omitempty"`
-	ACME            *InboundACMEOptions        `json:"acme,omitempty"`
-	ECH             *InboundECHOptions         `json:"ech,omitempty"`
-	Reality         *InboundRealityOptions     `json:"reality,omitempty"`
+	// Key pairs selected by SNI, named <name>.key and <name>.crt or <name>.pem
+	CertificateDirectoryPath string                 `json:"certificate_directory_path,omitempty"`
+	ACME                     *InboundACMEOptions    `json:"acme,omitempty"`
+	ECH                      *InboundECHOptions     `json:"ech,omitempty"`
+	Reality                  *InboundRealityOptions `json:"reality,omitempty"`
 }
 
 type InboundTLSOptionsContainer struct {
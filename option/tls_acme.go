@@ -31,6 +31,8 @@ type _ACMEDNS01ChallengeOptions struct {
 	Provider          string                     `json:"provider,omitempty"`
 	AliDNSOptions     ACMEDNS01AliDNSOptions     `json:"-"`
 	CloudflareOptions ACMEDNS01CloudflareOptions `json:"-"`
+	RFC2136Options    ACMEDNS01RFC2136Options    `json:"-"`
+	WebhookOptions    ACMEDNS01WebhookOptions    `json:"-"`
 }
 
 type ACMEDNS01ChallengeOptions _ACMEDNS01ChallengeOptions
@@ -42,6 +44,10 @@ func (o ACMEDNS01ChallengeOptions) MarshalJSON() ([]byte, error) {
 		v = o.AliDNSOptions
 	case C.DNSProviderCloudflare:
 		v = o.CloudflareOptions
+	case C.DNSProviderRFC2136:
+		v = o.RFC2136Options
+	case C.DNSProviderWebhook:
+		v = o.WebhookOptions
 	case "":
 		return nil, E.New("missing provider type")
 	default:
@@ -61,6 +67,10 @@ func (o *ACMEDNS01ChallengeOptions) UnmarshalJSON(bytes []byte) error {
 		v = &o.AliDNSOptions
 	case C.DNSProviderCloudflare:
 		v = &o.CloudflareOptions
+	case C.DNSProviderRFC2136:
+		v = &o.RFC2136Options
+	case C.DNSProviderWebhook:
+		v = &o.WebhookOptions
 	default:
 		return E.New("unknown provider type: " + o.Provider)
 	}
@@ -80,3 +90,17 @@ type ACMEDNS01AliDNSOptions struct {
 type ACMEDNS01CloudflareOptions struct {
 	APIToken string `json:"api_token,omitempty"`
 }
+
+type ACMEDNS01RFC2136Options struct {
+	Server        string             `json:"server,omitempty"`
+	TSIGKeyName   string             `json:"tsig_key_name,omitempty"`
+	TSIGAlgorithm string             `json:"tsig_algorithm,omitempty"`
+	TSIGSecret    string             `json:"tsig_secret,omitempty"`
+	Timeout       badoption.Duration `json:"timeout,omitempty"`
+}
+
+type ACMEDNS01WebhookOptions struct {
+	URL     string               `json:"url,omitempty"`
+	Headers badoption.HTTPHeader `json:"headers,omitempty"`
+	Timeout badoption.Duration   `json:"timeout,omitempty"`
+}
@@ -105,16 +105,19 @@ type V2RayHTTPUpgradeOptions struct {
 }
 
 type V2RayXHTTPOptions struct {
-	Host                 string                  `json:"host,omitempty"`
-	Path                 string                  `json:"path,omitempty"`
-	Mode                 string                  `json:"mode,omitempty"`
-	Headers              badoption.HTTPHeader    `json:"headers,omitempty"`
-	XPaddingBytes        *V2RayXHTTPRangeConfig  `json:"x_padding_bytes,omitempty"`
-	ScMaxEachPostBytes   *V2RayXHTTPRangeConfig  `json:"sc_max_each_post_bytes,omitempty"`
-	ScMinPostsIntervalMs *V2RayXHTTPRangeConfig  `json:"sc_min_posts_interval_ms,omitempty"`
-	ScMaxBufferedPosts   int32                   `json:"sc_max_buffered_posts,omitempty"`
-	NoGRPCHeader         bool                    `json:"no_grpc_header,omitempty"`
-	Xmux                 *V2RayXHTTPXmuxConfig   `json:"xmux,omitempty"`
+	Host                   string                 `json:"host,omitempty"`
+	Path                   string                 `json:"path,omitempty"`
+	Mode                   string                 `json:"mode,omitempty"`
+	Headers                badoption.HTTPHeader   `json:"headers,omitempty"`
+	XPaddingBytes          *V2RayXHTTPRangeConfig `json:"x_padding_bytes,omitempty"`
+	ScMaxEachPostBytes     *V2RayXHTTPRangeConfig `json:"sc_max_each_post_bytes,omitempty"`
+	ScMinPostsIntervalMs   *V2RayXHTTPRangeConfig `json:"sc_min_posts_interval_ms,omitempty"`
+	ScMaxBufferedPosts     int32                  `json:"sc_max_buffered_posts,omitempty"`
+	ScMaxConcurrentUploads int32                  `json:"sc_max_concurrent_uploads,omitempty"`
+	ScMaxIdleSessions      int32                  `json:"sc_max_idle_sessions,omitempty"`
+	ScSessionIdleTimeout   badoption.Duration     `json:"sc_session_idle_timeout,omitempty"`
+	NoGRPCHeader           bool                   `json:"no_grpc_header,omitempty"`
+	Xmux                   *V2RayXHTTPXmuxConfig  `json:"xmux,omitempty"`
 }
 
 type V2RayXHTTPRangeConfig struct {
@@ -4,6 +4,8 @@ import (
 	"context"
 	"net"
 	"os"
+	"sync"
+	"sync/atomic"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/outbound"
@@ -17,6 +19,9 @@ import (
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/common/uot"
+	"github.com/sagernet/sing/common/x/list"
+	"github.com/sagernet/sing/service"
+	"github.com/sagernet/sing/service/pause"
 
 	anytls "github.com/anytls/sing-anytls"
 )
@@ -27,19 +32,27 @@ func RegisterOutbound(registry *outbound.Registry) {
 
 type Outbound struct {
 	outbound.Adapter
-	dialer    N.Dialer
-	server    M.Socksaddr
-	tlsConfig tls.Config
-	client    *anytls.Client
-	uotClient *uot.Client
-	logger    log.ContextLogger
+	ctx           context.Context
+	dialer        N.Dialer
+	server        M.Socksaddr
+	tlsConfig     tls.Config
+	clientConfig  anytls.ClientConfig
+	access        sync.Mutex
+	client        atomic.Pointer[anytls.Client]
+	closed        bool
+	uotClient     *uot.Client
+	logger        log.ContextLogger
+	pauseManager  pause.Manager
+	pauseCallback *list.Element[pause.Callback]
 }
 
 func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.AnyTLSOutboundOptions) (adapter.Outbound, error) {
 	outbound := &Outbound{
-		Adapter: outbound.NewAdapterWithDialerOptions(C.TypeAnyTLS, tag, []string{N.NetworkTCP, N.NetworkUDP}, options.DialerOptions),
-		server:  options.ServerOptions.Build(),
-		logger:  logger,
+		Adapter:      outbound.NewAdapterWithDialerOptions(C.TypeAnyTLS, tag, []string{N.NetworkTCP, N.NetworkUDP}, options.DialerOptions),
+		ctx:          ctx,
+		server:       options.ServerOptions.Build(),
+		logger:       logger,
+		pauseManager: service.FromContext[pause.Manager](ctx),
 	}
 	if options.TLS == nil || !options.TLS.Enabled {
 		return nil, C.ErrTLSRequired
@@ -51,6 +64,11 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 	if options.DialerOptions.TCPFastOpen {
 		return nil, E.New("tcp_fast_open is not supported with anytls outbound")
 	}
+	// Sessions are already pooled by the client, configured by the same
+	// fields at the top level of the outbound
+	if options.DialerOptions.ConnectionPool != nil {
+		return nil, E.New("connection_pool is not supported with anytls outbound")
+	}
 
 	tlsConfig, err := tls.NewClient(ctx, options.Server, common.PtrValueOrDefault(options.TLS))
 	if err != nil {
@@ -68,7 +86,7 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 	}
 	outbound.dialer = outboundDialer
 
-	client, err := anytls.NewClient(ctx, anytls.ClientConfig{
+	outbound.clientConfig = anytls.ClientConfig{
 		Password:                    options.Password,
 		IdleSessionCheckInterval:    options.IdleSessionCheckInterval.Build(),
 		IdleSessionTimeout:          options.IdleSessionTimeout.Build(),
@@ -81,14 +99,18 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 		Heartbeat:                   options.Heartbeat.Build(),
 		DialOut:                     outbound.dialOut,
 		Logger:                      logger,
-	})
+	}
+	client, err := outbound.newClient()
 	if err != nil {
 		return nil, err
 	}
-	outbound.client = client
+	outbound.client.Store(client)
+	if outbound.pauseManager != nil {
+		outbound.pauseCallback = outbound.pauseManager.RegisterCallback(outbound.onPauseEvent)
+	}
 
 	outbound.uotClient = &uot.Client{
-		Dialer:  (anytlsDialer)(client.CreateProxy),
+		Dialer:  (anytlsDialer)(outbound.createProxy),
 		Version: uot.Version,
 	}
 	return outbound, nil
@@ -104,6 +126,10 @@ func (d anytlsDialer) ListenPacket(ctx context.Context, destination M.Socksaddr)
 	return nil, os.ErrInvalid
 }
 
+func (h *Outbound) createProxy(ctx context.Context, destination M.Socksaddr) (net.Conn, error) {
+	return h.client.Load().CreateProxy(ctx, destination)
+}
+
 func (h *Outbound) dialOut(ctx context.Context) (net.Conn, error) {
 	conn, err := h.dialer.DialContext(ctx, N.NetworkTCP, h.server)
 	if err != nil {
@@ -124,7 +150,7 @@ func (h *Outbound) DialContext(ctx context.Context, network string, destination
 	switch N.NetworkName(network) {
 	case N.NetworkTCP:
 		h.logger.InfoContext(ctx, "outbound connection to ", destination)
-		return h.client.CreateProxy(ctx, destination)
+		return h.createProxy(ctx, destination)
 	case N.NetworkUDP:
 		h.logger.InfoContext(ctx, "outbound UoT packet connection to ", destination)
 		return h.uotClient.DialContext(ctx, network, destination)
@@ -140,6 +166,55 @@ func (h *Outbound) ListenPacket(ctx context.Context, destination M.Socksaddr) (n
 	return h.uotClient.ListenPacket(ctx, destination)
 }
 
+// newClient creates a session client. Idle sessions are not pre-dialed while
+// paused, since they would not survive the pause.
+func (h *Outbound) newClient() (*anytls.Client, error) {
+	clientConfig := h.clientConfig
+	if h.pauseManager != nil && h.pauseManager.IsPaused() {
+		clientConfig.EnsureIdleSession = 0
+	}
+	return anytls.NewClient(h.ctx, clientConfig)
+}
+
+// resetClient replaces the session client and closes its sessions. The idle
+// sessions are warmed up again by the new client unless paused.
+func (h *Outbound) resetClient() {
+	h.access.Lock()
+	defer h.access.Unlock()
+	if h.closed {
+		return
+	}
+	client, err := h.newClient()
+	if err != nil {
+		h.logger.Error(E.Cause(err, "recreate session client"))
+		return
+	}
+	common.Close(h.client.Swap(client))
+}
+
+// onPauseEvent closes the sessions when the device or network is paused, and
+// warms them up again on wake
+func (h *Outbound) onPauseEvent(event int) {
+	switch event {
+	case pause.EventDevicePaused, pause.EventNetworkPause, pause.EventDeviceWake, pause.EventNetworkWake:
+		h.resetClient()
+	}
+}
+
+// InterfaceUpdated replaces the session client, since sessions are bound to
+// the previous route
+func (h *Outbound) InterfaceUpdated() {
+	h.resetClient()
+}
+
 func (h *Outbound) Close() error {
-	return common.Close(h.client)
+	h.access.Lock()
+	h.closed = true
+	err := common.Close(h.client.Load())
+	h.access.Unlock()
+	// Callbacks run under the lock of the pause manager
+	if h.pauseCallback != nil {
+		h.pauseManager.UnregisterCallback(h.pauseCallback)
+	}
+	return err
 }
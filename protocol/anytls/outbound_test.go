@@ -0,0 +1,97 @@
+package anytls
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/sagernet/sing-box/log"
+	M "github.com/sagernet/sing/common/metadata"
+	"github.com/sagernet/sing/service"
+	"github.com/sagernet/sing/service/pause"
+
+	anytls "github.com/anytls/sing-anytls"
+)
+
+func newPauseTestOutbound(t *testing.T, ctx context.Context) *Outbound {
+	outbound := &Outbound{
+		ctx:          ctx,
+		logger:       log.NewNOPFactory().Logger(),
+		pauseManager: service.FromContext[pause.Manager](ctx),
+	}
+	outbound.clientConfig = anytls.ClientConfig{
+		Password:          "password",
+		EnsureIdleSession: 1,
+		DialOut: func(ctx context.Context) (net.Conn, error) {
+			conn, serverConn := net.Pipe()
+			go io.Copy(io.Discard, serverConn)
+			return conn, nil
+		},
+		Logger: outbound.logger,
+	}
+	client, err := outbound.newClient()
+	if err != nil {
+		t.Fatal(err)
+	}
+	outbound.client.Store(client)
+	outbound.pauseCallback = outbound.pauseManager.RegisterCallback(outbound.onPauseEvent)
+	return outbound
+}
+
+func TestOutboundPause(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = pause.WithDefaultManager(ctx)
+	pauseManager := service.FromContext[pause.Manager](ctx)
+	outbound := newPauseTestOutbound(t, ctx)
+	destination := M.ParseSocksaddr("example.com:443")
+
+	client := outbound.client.Load()
+	conn, err := client.CreateProxy(ctx, destination)
+	if err != nil {
+		t.Fatalf("CreateProxy failed: %v", err)
+	}
+	conn.Close()
+
+	// Sessions do not survive the pause
+	pauseManager.NetworkPause()
+	pausedClient := outbound.client.Load()
+	if pausedClient == client {
+		t.Fatal("Expected the client to be replaced on pause")
+	}
+	_, err = client.CreateProxy(ctx, destination)
+	if err == nil {
+		t.Error("Expected the client to be closed on pause")
+	}
+	// Connections can still be dialed while paused
+	conn, err = pausedClient.CreateProxy(ctx, destination)
+	if err != nil {
+		t.Fatalf("CreateProxy while paused failed: %v", err)
+	}
+	conn.Close()
+
+	pauseManager.NetworkWake()
+	wokenClient := outbound.client.Load()
+	if wokenClient == pausedClient {
+		t.Fatal("Expected the client to be replaced on wake")
+	}
+	_, err = pausedClient.CreateProxy(ctx, destination)
+	if err == nil {
+		t.Error("Expected the paused client to be closed on wake")
+	}
+
+	// Events after close do not create clients
+	err = outbound.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pauseManager.NetworkPause()
+	if outbound.client.Load() != wokenClient {
+		t.Error("Expected the client to be kept after close")
+	}
+	_, err = wokenClient.CreateProxy(ctx, destination)
+	if err == nil {
+		t.Error("Expected the client to be closed")
+	}
+}
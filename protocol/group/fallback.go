@@ -0,0 +1,111 @@
+package group
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/adapter/outbound"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	N "github.com/sagernet/sing/common/network"
+)
+
+func RegisterFallback(registry *outbound.Registry) {
+	outbound.Register[option.FallbackOutboundOptions](registry, C.TypeFallback, NewFallback)
+}
+
+// NewFallback creates a fallback group. It shares health checking, probes,
+// outlier detection and retry with urltest, but selects the first healthy
+// member in declared order instead of the fastest one.
+func NewFallback(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.FallbackOutboundOptions) (adapter.Outbound, error) {
+	outbound, err := newURLTest(ctx, router, logger, C.TypeFallback, tag, option.URLTestOutboundOptions{
+		Outbounds:                 options.Outbounds,
+		URL:                       options.URL,
+		Interval:                  options.Interval,
+		IdleTimeout:               options.IdleTimeout,
+		Probe:                     options.Probe,
+		UDPProbe:                  options.UDPProbe,
+		OutlierDetection:          options.OutlierDetection,
+		Retry:                     options.Retry,
+		InterruptExistConnections: options.InterruptExistConnections,
+	})
+	if err != nil {
+		return nil, err
+	}
+	outbound.fallback = newFallbackSelector(time.Duration(options.PreemptDelay))
+	return outbound, nil
+}
+
+// fallbackSelector selects the first healthy member in declared order. Once
+// the group has moved down the list, it only moves back to a higher priority
+// member after that member has stayed healthy for preemptDelay.
+type fallbackSelector struct {
+	preemptDelay time.Duration
+	access       sync.Mutex
+	healthySince map[fallbackMember]time.Time
+}
+
+type fallbackMember struct {
+	network string
+	tag     string
+}
+
+func newFallbackSelector(preemptDelay time.Duration) *fallbackSelector {
+	return &fallbackSelector{
+		preemptDelay: preemptDelay,
+		healthySince: make(map[fallbackMember]time.Time),
+	}
+}
+
+func (f *fallbackSelector) Select(g *URLTestGroup, network string) (adapter.Outbound, bool) {
+	current := g.selectedOutbound(network)
+	candidates := g.outlier.Filter(common.Filter(g.outbounds, func(it adapter.Outbound) bool {
+		return common.Contains(it.Network(), network)
+	}))
+	if network == N.NetworkUDP {
+		candidates = g.udpHealth.Filter(candidates)
+	}
+	now := time.Now()
+	f.access.Lock()
+	defer f.access.Unlock()
+	healthy := make([]adapter.Outbound, 0, len(candidates))
+	for _, detour := range g.outbounds {
+		if _, loaded := g.loadDelay(network, detour); loaded && common.Contains(candidates, detour) {
+			healthy = append(healthy, detour)
+			member := fallbackMember{network, detour.Tag()}
+			if _, loaded = f.healthySince[member]; !loaded {
+				f.healthySince[member] = now
+			}
+		} else {
+			delete(f.healthySince, fallbackMember{network, detour.Tag()})
+		}
+	}
+	currentHealthy := current != nil && common.Contains(healthy, current)
+	for _, detour := range healthy {
+		if currentHealthy && detour != current && now.Sub(f.healthySince[fallbackMember{network, detour.Tag()}]) < f.preemptDelay {
+			// Only members ahead of current can come before it in the list
+			continue
+		}
+		return detour, true
+	}
+	if len(candidates) > 0 {
+		return candidates[0], false
+	}
+	return nil, false
+}
+
+func (f *fallbackSelector) selectRetry(g *URLTestGroup, network string, candidates []adapter.Outbound) adapter.Outbound {
+	for _, detour := range candidates {
+		if _, loaded := g.loadDelay(network, detour); loaded {
+			return detour
+		}
+	}
+	if len(candidates) > 0 {
+		return candidates[0]
+	}
+	return nil
+}
@@ -0,0 +1,138 @@
+package group
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/interrupt"
+	"github.com/sagernet/sing-box/common/urltest"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newFallbackTestGroup(preemptDelay time.Duration, tags ...string) *URLTestGroup {
+	return &URLTestGroup{
+		outbounds: newStrategyTestCandidates(tags...),
+		history:   urltest.NewHistoryStorage(),
+		fallback:  newFallbackSelector(preemptDelay),
+	}
+}
+
+func (g *URLTestGroup) setTestHealthy(tag string, healthy bool) {
+	if healthy {
+		g.history.StoreURLTestHistory(tag, &adapter.URLTestHistory{Time: time.Now(), Delay: 500})
+	} else {
+		g.history.DeleteURLTestHistory(tag)
+	}
+}
+
+func TestFallbackOrderedSelection(t *testing.T) {
+	group := newFallbackTestGroup(0, "a", "b", "c")
+	group.setTestHealthy("b", true)
+	group.setTestHealthy("c", true)
+	// Latency must not matter
+	group.history.StoreURLTestHistory("c", &adapter.URLTestHistory{Time: time.Now(), Delay: 1})
+
+	selected, healthy := group.Select("tcp")
+	require.True(t, healthy)
+	assert.Equal(t, "b", selected.Tag())
+	group.selectedOutboundTCP = selected
+
+	// b fails: move down the list
+	group.setTestHealthy("b", false)
+	selected, _ = group.Select("tcp")
+	assert.Equal(t, "c", selected.Tag())
+	group.selectedOutboundTCP = selected
+
+	// a recovers: preempt back immediately without delay
+	group.setTestHealthy("a", true)
+	selected, _ = group.Select("tcp")
+	assert.Equal(t, "a", selected.Tag())
+}
+
+func TestFallbackPreemptDelay(t *testing.T) {
+	group := newFallbackTestGroup(50*time.Millisecond, "a", "b")
+	group.setTestHealthy("b", true)
+	selected, _ := group.Select("tcp")
+	require.Equal(t, "b", selected.Tag())
+	group.selectedOutboundTCP = selected
+
+	group.setTestHealthy("a", true)
+	selected, _ = group.Select("tcp")
+	assert.Equal(t, "b", selected.Tag(), "must stay on b until a has been healthy for preempt_delay")
+
+	time.Sleep(60 * time.Millisecond)
+	selected, _ = group.Select("tcp")
+	assert.Equal(t, "a", selected.Tag())
+	group.selectedOutboundTCP = selected
+
+	// A flapping member restarts its delay
+	group.setTestHealthy("a", false)
+	selected, _ = group.Select("tcp")
+	require.Equal(t, "b", selected.Tag())
+	group.selectedOutboundTCP = selected
+	group.setTestHealthy("a", true)
+	selected, _ = group.Select("tcp")
+	assert.Equal(t, "b", selected.Tag())
+
+	// The current member failing moves to the first healthy member at once
+	group.setTestHealthy("b", false)
+	selected, _ = group.Select("tcp")
+	assert.Equal(t, "a", selected.Tag())
+}
+
+func TestFallbackNoHealthyMember(t *testing.T) {
+	group := newFallbackTestGroup(0, "a", "b")
+	selected, healthy := group.Select("tcp")
+	assert.False(t, healthy)
+	require.NotNil(t, selected)
+	assert.Equal(t, "a", selected.Tag())
+}
+
+func TestFallbackOutlierAndRetry(t *testing.T) {
+	group := newFallbackTestGroup(0, "a", "b", "c")
+	group.outlier = newOutlierDetector(&option.OutlierDetectionOptions{Failures: 1})
+	group.setTestHealthy("a", true)
+	group.setTestHealthy("b", true)
+	group.setTestHealthy("c", true)
+
+	group.outlier.ReportFailure("a")
+	selected, _ := group.Select("tcp")
+	assert.Equal(t, "b", selected.Tag())
+
+	retry := group.selectRetry("tcp", []string{"b"})
+	require.NotNil(t, retry)
+	assert.Equal(t, "c", retry.Tag())
+}
+
+func TestFallbackConcurrentUpdateCheck(t *testing.T) {
+	group := newFallbackTestGroup(0, "a", "b")
+	group.interruptGroup = interrupt.NewGroup()
+	group.setTestHealthy("a", true)
+	group.setTestHealthy("b", true)
+	group.performUpdateCheck()
+	require.Equal(t, "a", group.selectedOutbound("tcp").Tag())
+
+	// Failed dials re-check the selection while connections are dialed
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				group.setTestHealthy("a", j%2 == 1)
+				group.performUpdateCheck()
+				assert.NotNil(t, group.selectedOutbound("tcp"))
+				assert.NotNil(t, group.selectedOutbound("udp"))
+			}
+		}()
+	}
+	wg.Wait()
+	group.setTestHealthy("a", true)
+	group.performUpdateCheck()
+	assert.Equal(t, "a", group.selectedOutbound("tcp").Tag())
+}
@@ -21,6 +21,7 @@ import (
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json/badoption"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/common/x/list"
@@ -31,8 +32,12 @@ import (
 )
 
 const (
-	strategyRandom         = "random"
-	strategyConsistentHash = "consistent_hash"
+	strategyRandom             = "random"
+	strategyConsistentHash     = "consistent_hash"
+	strategyRoundRobin         = "round_robin"
+	strategyWeightedRoundRobin = "weighted_round_robin"
+	strategyLeastConnections   = "least_connections"
+	strategyLatencyWeighted    = "latency_weighted"
 
 	emptyPoolActionError       = "error"
 	emptyPoolActionFallbackAll = "fallback_all"
@@ -47,6 +52,10 @@ const (
 	defaultOnEmptyKey    = onEmptyKeyRandom
 	defaultEmptyPoolAction = emptyPoolActionError
 
+	// Latency weighted defaults
+	defaultLatencyExponent  = 1.0
+	defaultLatencySmoothing = 0.3
+
 	// Hysteresis defaults
 	defaultPrimaryFailures = 3
 	defaultBackupHoldTime  = 30 * time.Second
@@ -65,12 +74,15 @@ type LoadBalance struct {
 	primaryTags   []string
 	backupTags    []string
 	link          string
+	prober        urltest.Prober
+	udpHealth     *udpHealth
 	interval      time.Duration
 	timeout       time.Duration
 	idleTimeout   time.Duration
 	topNPrimary   int
 	topNBackup    int
 	strategy      string
+	weights       map[string]uint32
 	emptyPoolAction string
 
 	// Hash configuration
@@ -79,6 +91,9 @@ type LoadBalance struct {
 	hashOnEmptyKey   string
 	hashKeySalt      string
 
+	// Latency weighted configuration
+	latencyExponent float64
+
 	// Hysteresis configuration
 	hystPrimaryFailures uint32
 	hystBackupHoldTime  time.Duration
@@ -93,6 +108,18 @@ type LoadBalance struct {
 	// Candidate pools and selection state
 	candidateState atomic.Value // *candidateSnapshot
 	tierState      atomic.Value // *tierStateSnapshot
+	tuning         atomic.Pointer[loadBalanceTuning] // Runtime copy of the tunable configuration
+	updateAccess   sync.Mutex
+
+	// Strategy state
+	roundRobinIndex    atomic.Uint64
+	weightedRoundRobin weightedRoundRobin
+	memberConnections  *memberConnections
+	latencyEWMA        *latencyEWMA
+	outlier            *outlierDetector
+	retry              *dialRetry
+	sticky             *stickyTable
+	stickyPersist      bool
 
 	// Health check coordination
 	checking atomic.Bool
@@ -159,8 +186,10 @@ func NewLoadBalance(
 		return nil, E.New("top_n.primary must be > 0")
 	}
 
-	if options.Strategy != strategyRandom && options.Strategy != strategyConsistentHash {
-		return nil, E.New("strategy must be 'random' or 'consistent_hash'")
+	switch options.Strategy {
+	case strategyRandom, strategyConsistentHash, strategyRoundRobin, strategyWeightedRoundRobin, strategyLeastConnections, strategyLatencyWeighted:
+	default:
+		return nil, E.New("strategy must be one of 'random', 'consistent_hash', 'round_robin', 'weighted_round_robin', 'least_connections' or 'latency_weighted'")
 	}
 
 	if options.Strategy == strategyConsistentHash && options.Hash == nil {
@@ -192,6 +221,7 @@ func NewLoadBalance(
 		topNPrimary: options.TopN.Primary,
 		topNBackup:  options.TopN.Backup,
 		strategy:    options.Strategy,
+		weights:     options.Weights,
 		emptyPoolAction: options.EmptyPoolAction,
 		interruptExternalConnections: options.InterruptExistConnections,
 		close:       make(chan struct{}),
@@ -201,6 +231,15 @@ func NewLoadBalance(
 	if lb.link == "" {
 		lb.link = "https://www.gstatic.com/generate_204"
 	}
+	prober, err := urltest.NewProber(options.Probe, lb.link)
+	if err != nil {
+		return nil, err
+	}
+	lb.prober = prober
+	lb.udpHealth, err = newUDPHealth(options.UDPProbe)
+	if err != nil {
+		return nil, err
+	}
 	if lb.interval == 0 {
 		lb.interval = defaultInterval
 	}
@@ -235,8 +274,34 @@ func NewLoadBalance(
 		if lb.hashOnEmptyKey != onEmptyKeyRandom && lb.hashOnEmptyKey != onEmptyKeyHashEmpty {
 			return nil, E.New("hash.on_empty_key must be 'random' or 'hash_empty'")
 		}
+		if options.Hash.Sticky != nil {
+			if options.Strategy != strategyConsistentHash {
+				return nil, E.New("hash.sticky requires consistent_hash strategy")
+			}
+			lb.sticky = newStickyTable(tag, options.Hash.Sticky, logger)
+			lb.stickyPersist = options.Hash.Sticky.Persist
+		}
 	}
 
+	// Latency weighted configuration
+	lb.latencyExponent = defaultLatencyExponent
+	latencySmoothing := defaultLatencySmoothing
+	if options.LatencyWeighted != nil {
+		if options.LatencyWeighted.Exponent != 0 {
+			lb.latencyExponent = options.LatencyWeighted.Exponent
+		}
+		if options.LatencyWeighted.Smoothing != 0 {
+			latencySmoothing = options.LatencyWeighted.Smoothing
+		}
+	}
+	if lb.latencyExponent < 0 {
+		return nil, E.New("latency_weighted.exponent must be >= 0")
+	}
+	if latencySmoothing < 0 || latencySmoothing > 1 {
+		return nil, E.New("latency_weighted.smoothing must be between 0 and 1")
+	}
+	lb.latencyEWMA = newLatencyEWMA(latencySmoothing)
+
 	// Hysteresis configuration
 	if options.Hysteresis != nil {
 		lb.hystPrimaryFailures = options.Hysteresis.PrimaryFailures
@@ -249,6 +314,8 @@ func NewLoadBalance(
 		lb.hystBackupHoldTime = defaultBackupHoldTime
 	}
 
+	lb.tuning.Store(lb.loadTuning())
+
 	// Initialize tier state
 	initialTierState := &tierStateSnapshot{
 		activeTier: "primary",
@@ -264,6 +331,22 @@ func NewLoadBalance(
 		tagSet[tag] = true
 	}
 
+	for tag, weight := range lb.weights {
+		if !tagSet[tag] {
+			return nil, E.New("weights: unknown outbound tag: ", tag)
+		}
+		if weight == 0 {
+			return nil, E.New("weights: weight of ", tag, " must be > 0")
+		}
+	}
+	lb.memberConnections = newMemberConnections(allTags)
+	lb.outlier = newOutlierDetector(options.OutlierDetection)
+	retry, err := newDialRetry(options.Retry)
+	if err != nil {
+		return nil, err
+	}
+	lb.retry = retry
+
 	if lb.interruptExternalConnections {
 		lb.interruptGroup = interrupt.NewGroup()
 	}
@@ -346,6 +429,14 @@ func (lb *LoadBalance) Start(stage adapter.StartStage) error {
 	// Initialize pause manager
 	lb.pauseManager = service.FromContext[pause.Manager](lb.ctx)
 
+	// Restore persisted sticky sessions
+	if lb.stickyPersist && lb.Tag() != "" {
+		cacheFile := service.FromContext[adapter.CacheFile](lb.ctx)
+		if cacheFile != nil {
+			lb.sticky.Restore(cacheFile)
+		}
+	}
+
 	return nil
 }
 
@@ -462,11 +553,13 @@ func (lb *LoadBalance) performHealthCheck(ctx context.Context) {
 			semaphore <- struct{}{}
 			defer func() { <-semaphore }()
 
+			lb.checkUDP(ctx, d)
+
 			// Create context with timeout
 			testCtx, cancel := context.WithTimeout(ctx, lb.timeout)
 			defer cancel()
 
-			t, err := urltest.URLTest(testCtx, lb.link, d)
+			t, err := lb.prober.Probe(testCtx, d)
 			if err != nil {
 				lb.logger.Debug("health check failed for ", d.Tag(), ": ", err)
 				resultChan <- nodeStat{tag: d.Tag(), failure: true}
@@ -475,12 +568,14 @@ func (lb *LoadBalance) performHealthCheck(ctx context.Context) {
 					Time:  time.Now(),
 					Delay: t,
 				})
+				lb.latencyEWMA.Update(RealTag(d), t)
 				resultChan <- nodeStat{tag: d.Tag(), delay: t}
 			}
 		}(detour)
 	}
 	wg.Wait()
 	close(resultChan)
+	lb.udpHealth.SetChecked()
 
 	// Update candidate pools
 	lb.updateCandidates()
@@ -488,6 +583,16 @@ func (lb *LoadBalance) performHealthCheck(ctx context.Context) {
 
 // updateCandidates rebuilds candidate pools based on current health check results
 func (lb *LoadBalance) updateCandidates() {
+	lb.updateAccess.Lock()
+	defer lb.updateAccess.Unlock()
+	lb.rebuildCandidates(true)
+}
+
+// rebuildCandidates rebuilds candidate pools with the current tuning. Hysteresis
+// is only applied after a health check round, not when parameters are tuned.
+func (lb *LoadBalance) rebuildCandidates(checked bool) {
+	tuning := lb.loadTuning()
+
 	// Collect health stats for primary tier
 	primaryStats := lb.collectTierStats(lb.primaryTags)
 
@@ -495,13 +600,15 @@ func (lb *LoadBalance) updateCandidates() {
 	backupStats := lb.collectTierStats(lb.backupTags)
 
 	// Select Top-N candidates per tier
-	primaryCandidates := lb.selectTopN(primaryStats, lb.topNPrimary)
-	backupCandidates := lb.selectTopN(backupStats, lb.topNBackup)
+	primaryCandidates := lb.selectTopN(primaryStats, tuning.topNPrimary)
+	backupCandidates := lb.selectTopN(backupStats, tuning.topNBackup)
 
 	// Apply hysteresis to determine active tier
-	currentTierState := lb.tierState.Load().(*tierStateSnapshot)
-	newTierState := lb.applyHysteresis(currentTierState, primaryCandidates, backupCandidates)
-	lb.tierState.Store(newTierState)
+	newTierState := lb.tierState.Load().(*tierStateSnapshot)
+	if checked {
+		newTierState = lb.applyHysteresis(newTierState, primaryCandidates, backupCandidates)
+		lb.tierState.Store(newTierState)
+	}
 
 	// Build new snapshot
 	newSnapshot := &candidateSnapshot{
@@ -511,7 +618,7 @@ func (lb *LoadBalance) updateCandidates() {
 	}
 
 	// Build consistent hash ring if needed
-	if lb.strategy == strategyConsistentHash {
+	if tuning.strategy == strategyConsistentHash {
 		var ringMembers []adapter.Outbound
 		if newTierState.activeTier == "primary" && len(primaryCandidates) > 0 {
 			ringMembers = primaryCandidates
@@ -644,6 +751,7 @@ func (lb *LoadBalance) applyHysteresis(
 	current *tierStateSnapshot,
 	primaryCandidates, backupCandidates []adapter.Outbound,
 ) *tierStateSnapshot {
+	tuning := lb.loadTuning()
 	newState := &tierStateSnapshot{
 		activeTier:          current.activeTier,
 		primaryFailureCount: current.primaryFailureCount,
@@ -659,16 +767,16 @@ func (lb *LoadBalance) applyHysteresis(
 			// Primary tier failed
 			newState.primaryFailureCount++
 			lb.logger.Debug(
-				"primary tier failure ", newState.primaryFailureCount, "/", lb.hystPrimaryFailures,
+				"primary tier failure ", newState.primaryFailureCount, "/", tuning.hystPrimaryFailures,
 			)
 
-			if newState.primaryFailureCount >= lb.hystPrimaryFailures {
+			if newState.primaryFailureCount >= tuning.hystPrimaryFailures {
 				// Switch to backup tier
 				if backupAvailable {
 					newState.activeTier = "backup"
 					newState.backupActivatedAt = time.Now()
 					newState.primaryFailureCount = 0
-					lb.logger.Warn("switching to backup tier after ", lb.hystPrimaryFailures, " failures")
+					lb.logger.Warn("switching to backup tier after ", tuning.hystPrimaryFailures, " failures")
 				} else {
 					lb.logger.Error("primary tier failed but no backup candidates available")
 				}
@@ -681,14 +789,14 @@ func (lb *LoadBalance) applyHysteresis(
 	case "backup":
 		if primaryAvailable {
 			// Check if backup hold time has elapsed
-			if time.Since(current.backupActivatedAt) >= lb.hystBackupHoldTime {
+			if time.Since(current.backupActivatedAt) >= tuning.hystBackupHoldTime {
 				newState.activeTier = "primary"
 				newState.primaryFailureCount = 0
 				lb.logger.Info("switching back to primary tier after hold time")
 			} else {
 				lb.logger.Debug(
 					"primary tier available but backup hold time not elapsed: ",
-					time.Since(current.backupActivatedAt), "/", lb.hystBackupHoldTime,
+					time.Since(current.backupActivatedAt), "/", tuning.hystBackupHoldTime,
 				)
 			}
 		} else if !backupAvailable {
@@ -770,13 +878,14 @@ func (lb *LoadBalance) lookupHashRing(ring *consistentHashRing, keyHash uint64)
 
 // buildHashKey constructs hash key from connection metadata
 func (lb *LoadBalance) buildHashKey(metadata *adapter.InboundContext) string {
-	if len(lb.hashKeyParts) == 0 {
+	keyParts := lb.loadTuning().hashKeyParts
+	if len(keyParts) == 0 {
 		return ""
 	}
 
-	parts := make([]string, 0, len(lb.hashKeyParts))
+	parts := make([]string, 0, len(keyParts))
 
-	for _, part := range lb.hashKeyParts {
+	for _, part := range keyParts {
 		switch part {
 		case "src_ip":
 			if metadata.Source.IsValid() {
@@ -925,11 +1034,12 @@ func (lb *LoadBalance) selectOutboundBootstrap(network string, metadata *adapter
 	if len(candidates) == 0 {
 		return nil, E.New("no primary outbounds available for network ", network)
 	}
+	candidates = lb.outlier.Filter(candidates)
 
 	// Select based on strategy
 	var selected adapter.Outbound
 
-	switch lb.strategy {
+	switch lb.loadTuning().strategy {
 	case strategyRandom:
 		selected = candidates[rand.Intn(len(candidates))]
 		lb.logger.Debug(
@@ -937,6 +1047,18 @@ func (lb *LoadBalance) selectOutboundBootstrap(network string, metadata *adapter
 			", pool_size=", len(candidates),
 		)
 
+	case strategyRoundRobin:
+		selected = lb.selectRoundRobin(candidates)
+
+	case strategyWeightedRoundRobin:
+		selected = lb.selectWeightedRoundRobin(candidates)
+
+	case strategyLeastConnections:
+		selected = lb.selectLeastConnections(candidates)
+
+	case strategyLatencyWeighted:
+		selected = lb.selectLatencyWeighted(candidates)
+
 	case strategyConsistentHash:
 		// Build temporary hash ring from all primary outbounds
 		tempRing := lb.buildHashRing(candidates)
@@ -967,6 +1089,16 @@ func (lb *LoadBalance) selectOutboundBootstrap(network string, metadata *adapter
 				}
 			}
 		} else {
+			// Honor an existing sticky assignment
+			selected = lb.sticky.Select(hashKey, candidates)
+			if selected != nil {
+				lb.logger.Debug(
+					"bootstrap sticky selection: key=", hashKey,
+					", selected=", selected.Tag(),
+				)
+				break
+			}
+
 			// Hash the key and lookup
 			keyHash := xxhash.Sum64String(hashKey)
 			nodeTag := lb.lookupHashRing(tempRing, keyHash)
@@ -987,6 +1119,7 @@ func (lb *LoadBalance) selectOutboundBootstrap(network string, metadata *adapter
 					", selected=", selected.Tag(),
 				)
 			}
+			lb.sticky.Store(hashKey, selected.Tag())
 		}
 	}
 
@@ -1054,11 +1187,18 @@ func (lb *LoadBalance) selectOutbound(network string, metadata *adapter.InboundC
 	if len(networkCandidates) == 0 {
 		return nil, E.New("no candidates support network ", network)
 	}
+	networkCandidates = lb.outlier.Filter(networkCandidates)
+	if network == N.NetworkUDP {
+		networkCandidates = lb.filterUDPHealthy(cs, networkCandidates)
+		if len(networkCandidates) == 0 {
+			return nil, E.New("no UDP-healthy candidates available")
+		}
+	}
 
 	// Select based on strategy
 	var selected adapter.Outbound
 
-	switch lb.strategy {
+	switch lb.loadTuning().strategy {
 	case strategyRandom:
 		selected = networkCandidates[rand.Intn(len(networkCandidates))]
 		lb.logger.Debug(
@@ -1067,6 +1207,38 @@ func (lb *LoadBalance) selectOutbound(network string, metadata *adapter.InboundC
 			", pool_size=", len(networkCandidates),
 		)
 
+	case strategyRoundRobin:
+		selected = lb.selectRoundRobin(networkCandidates)
+		lb.logger.Debug(
+			"round_robin selection: tier=", cs.activeTier,
+			", selected=", selected.Tag(),
+			", pool_size=", len(networkCandidates),
+		)
+
+	case strategyWeightedRoundRobin:
+		selected = lb.selectWeightedRoundRobin(networkCandidates)
+		lb.logger.Debug(
+			"weighted_round_robin selection: tier=", cs.activeTier,
+			", selected=", selected.Tag(),
+			", weight=", lb.weight(selected.Tag()),
+		)
+
+	case strategyLeastConnections:
+		selected = lb.selectLeastConnections(networkCandidates)
+		lb.logger.Debug(
+			"least_connections selection: tier=", cs.activeTier,
+			", selected=", selected.Tag(),
+			", connections=", lb.memberConnections.Load(selected.Tag()),
+		)
+
+	case strategyLatencyWeighted:
+		selected = lb.selectLatencyWeighted(networkCandidates)
+		lb.logger.Debug(
+			"latency_weighted selection: tier=", cs.activeTier,
+			", selected=", selected.Tag(),
+			", pool_size=", len(networkCandidates),
+		)
+
 	case strategyConsistentHash:
 		if cs.hashRing == nil || len(cs.hashRing.points) == 0 {
 			// Fallback to random if ring not built
@@ -1101,6 +1273,17 @@ func (lb *LoadBalance) selectOutbound(network string, metadata *adapter.InboundC
 					}
 				}
 			} else {
+				// Honor an existing sticky assignment
+				selected = lb.sticky.Select(hashKey, networkCandidates)
+				if selected != nil {
+					lb.logger.Debug(
+						"sticky selection: tier=", cs.activeTier,
+						", key=", hashKey,
+						", selected=", selected.Tag(),
+					)
+					break
+				}
+
 				// Hash the key and lookup
 				keyHash := xxhash.Sum64String(hashKey)
 				nodeTag := lb.lookupHashRing(cs.hashRing, keyHash)
@@ -1125,6 +1308,7 @@ func (lb *LoadBalance) selectOutbound(network string, metadata *adapter.InboundC
 						", selected=", selected.Tag(),
 					)
 				}
+				lb.sticky.Store(hashKey, selected.Tag())
 			}
 		}
 	}
@@ -1136,6 +1320,75 @@ func (lb *LoadBalance) selectOutbound(network string, metadata *adapter.InboundC
 	return selected, nil
 }
 
+// filterUDPHealthy returns the UDP-healthy members of the active tier, or of
+// the other tier if none of the active tier is UDP-healthy.
+func (lb *LoadBalance) filterUDPHealthy(cs *candidateSnapshot, candidates []adapter.Outbound) []adapter.Outbound {
+	if lb.udpHealth == nil {
+		return candidates
+	}
+	healthy := lb.udpHealth.Filter(candidates)
+	if len(healthy) > 0 {
+		return healthy
+	}
+	otherTier := cs.backupCandidates
+	if cs.activeTier != "primary" {
+		otherTier = cs.primaryCandidates
+	}
+	otherTier = common.Filter(otherTier, func(it adapter.Outbound) bool {
+		return common.Contains(it.Network(), N.NetworkUDP)
+	})
+	healthy = lb.udpHealth.Filter(lb.outlier.Filter(otherTier))
+	if len(healthy) > 0 {
+		lb.logger.Debug("no UDP-healthy candidates in ", cs.activeTier, " tier, using the other tier")
+	}
+	return healthy
+}
+
+// checkUDP probes the UDP path of detour if UDP health checking is enabled.
+func (lb *LoadBalance) checkUDP(ctx context.Context, detour adapter.Outbound) {
+	if lb.udpHealth == nil || !common.Contains(detour.Network(), N.NetworkUDP) {
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, lb.timeout)
+	defer cancel()
+	t, err := lb.udpHealth.Probe(ctx, detour)
+	if err != nil {
+		lb.logger.Debug("UDP health check failed for ", detour.Tag(), ": ", err)
+	} else {
+		lb.logger.Debug("UDP health check succeeded for ", detour.Tag(), ": ", t, "ms")
+	}
+}
+
+// nextCandidate returns the best untried member of the active tier for a retry.
+// Candidates are ranked by latency, so the first untried one is the next best.
+func (lb *LoadBalance) nextCandidate(network string, tried []string) adapter.Outbound {
+	var candidates []adapter.Outbound
+	if snapshot := lb.candidateState.Load(); snapshot != nil {
+		cs := snapshot.(*candidateSnapshot)
+		if cs.activeTier == "primary" && len(cs.primaryCandidates) > 0 {
+			candidates = cs.primaryCandidates
+		} else {
+			candidates = cs.backupCandidates
+		}
+	} else {
+		for _, tag := range lb.primaryTags {
+			if detour, loaded := lb.outbound.Outbound(tag); loaded {
+				candidates = append(candidates, detour)
+			}
+		}
+	}
+	candidates = lb.outlier.Filter(candidates)
+	if network == N.NetworkUDP {
+		candidates = lb.udpHealth.Filter(candidates)
+	}
+	for _, candidate := range candidates {
+		if common.Contains(candidate.Network(), network) && !common.Contains(tried, candidate.Tag()) {
+			return candidate
+		}
+	}
+	return nil
+}
+
 func (lb *LoadBalance) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
 	metadata := adapter.ContextFrom(ctx)
 	if metadata == nil {
@@ -1147,10 +1400,21 @@ func (lb *LoadBalance) DialContext(ctx context.Context, network string, destinat
 		return nil, err
 	}
 
-	conn, err := selected.DialContext(ctx, network, destination)
+	conn, selected, err := dialWithRetry(ctx, lb.retry, lb.logger, selected,
+		func(tried []string) adapter.Outbound {
+			return lb.nextCandidate(network, tried)
+		},
+		func(ctx context.Context, detour adapter.Outbound) (net.Conn, error) {
+			return detour.DialContext(ctx, network, destination)
+		},
+		func(detour adapter.Outbound, err error) {
+			lb.outlier.ReportResult(ctx, lb.logger, detour.Tag(), err)
+		},
+	)
 	if err != nil {
 		return nil, err
 	}
+	conn = lb.memberConnections.NewConn(selected.Tag(), conn)
 
 	if lb.interruptGroup != nil {
 		return lb.interruptGroup.NewConn(conn, interrupt.IsExternalConnectionFromContext(ctx)), nil
@@ -1170,10 +1434,21 @@ func (lb *LoadBalance) ListenPacket(ctx context.Context, destination M.Socksaddr
 		return nil, err
 	}
 
-	conn, err := selected.ListenPacket(ctx, destination)
+	conn, selected, err := dialWithRetry(ctx, lb.retry, lb.logger, selected,
+		func(tried []string) adapter.Outbound {
+			return lb.nextCandidate(N.NetworkUDP, tried)
+		},
+		func(ctx context.Context, detour adapter.Outbound) (net.PacketConn, error) {
+			return detour.ListenPacket(ctx, destination)
+		},
+		func(detour adapter.Outbound, err error) {
+			lb.outlier.ReportResult(ctx, lb.logger, detour.Tag(), err)
+		},
+	)
 	if err != nil {
 		return nil, err
 	}
+	conn = lb.memberConnections.NewPacketConn(selected.Tag(), conn)
 
 	if lb.interruptGroup != nil {
 		return lb.interruptGroup.NewPacketConn(conn, interrupt.IsExternalConnectionFromContext(ctx)), nil
@@ -1212,6 +1487,66 @@ func (lb *LoadBalance) All() []string {
 	return allTags
 }
 
+func (lb *LoadBalance) OutlierStatus() map[string]adapter.OutlierStatus {
+	return lb.outlier.Status()
+}
+
+// LoadBalanceStatus returns a point-in-time view of tiers, candidates,
+// hysteresis and per-member traffic for the Clash API
+func (lb *LoadBalance) LoadBalanceStatus() *adapter.LoadBalanceStatus {
+	tuning := lb.loadTuning()
+	status := &adapter.LoadBalanceStatus{
+		Strategy: tuning.strategy,
+		Hysteresis: adapter.LoadBalanceHysteresis{
+			PrimaryFailuresThreshold: tuning.hystPrimaryFailures,
+			BackupHoldTime:           badoption.Duration(tuning.hystBackupHoldTime),
+		},
+	}
+	candidates := make(map[string]bool)
+	if snapshot := lb.candidateState.Load(); snapshot != nil {
+		cs := snapshot.(*candidateSnapshot)
+		status.ActiveTier = cs.activeTier
+		status.PrimaryCandidates = common.Map(cs.primaryCandidates, adapter.Outbound.Tag)
+		status.BackupCandidates = common.Map(cs.backupCandidates, adapter.Outbound.Tag)
+		if cs.hashRing != nil {
+			status.RingMembers = append([]string{}, cs.hashRing.members...)
+		}
+		for _, tag := range status.PrimaryCandidates {
+			candidates[tag] = true
+		}
+		for _, tag := range status.BackupCandidates {
+			candidates[tag] = true
+		}
+	}
+	if state := lb.tierState.Load(); state != nil {
+		ts := state.(*tierStateSnapshot)
+		status.Hysteresis.PrimaryFailures = ts.primaryFailureCount
+		status.Hysteresis.BackupActivatedAt = ts.backupActivatedAt
+	}
+	appendMembers := func(tier string, tags []string) {
+		for _, tag := range tags {
+			member := adapter.LoadBalanceMemberStatus{
+				Name:        tag,
+				Tier:        tier,
+				Candidate:   candidates[tag],
+				Connections: lb.memberConnections.Load(tag),
+			}
+			member.Upload, member.Download = lb.memberConnections.Traffic(tag)
+			if lb.history != nil {
+				if detour, loaded := lb.outbound.Outbound(tag); loaded {
+					if history := lb.history.LoadURLTestHistory(RealTag(detour)); history != nil {
+						member.Delay = history.Delay
+					}
+				}
+			}
+			status.Members = append(status.Members, member)
+		}
+	}
+	appendMembers("primary", lb.primaryTags)
+	appendMembers("backup", lb.backupTags)
+	return status
+}
+
 // URLTest performs on-demand health checks and returns latency results
 func (lb *LoadBalance) URLTest(ctx context.Context) (map[string]uint16, error) {
 	result := make(map[string]uint16)
@@ -1262,21 +1597,25 @@ func (lb *LoadBalance) URLTest(ctx context.Context) (map[string]uint16, error) {
 			semaphore <- struct{}{}
 			defer func() { <-semaphore }()
 
+			lb.checkUDP(ctx, d)
+
 			// Create context with timeout
 			testCtx, cancel := context.WithTimeout(ctx, lb.timeout)
 			defer cancel()
 
-			t, err := urltest.URLTest(testCtx, lb.link, d)
+			t, err := lb.prober.Probe(testCtx, d)
 			if err != nil {
 				lb.logger.Debug("health check failed for ", d.Tag(), ": ", err)
 				resultChan <- nodeStat{tag: d.Tag(), failure: true}
 				lb.history.DeleteURLTestHistory(RealTag(d))
+				lb.latencyEWMA.Delete(RealTag(d))
 			} else {
 				lb.logger.Debug("health check succeeded for ", d.Tag(), ": ", t, "ms")
 				lb.history.StoreURLTestHistory(RealTag(d), &adapter.URLTestHistory{
 					Time:  time.Now(),
 					Delay: t,
 				})
+				lb.latencyEWMA.Update(RealTag(d), t)
 				resultChan <- nodeStat{tag: d.Tag(), delay: t}
 
 				resultAccess.Lock()
@@ -1287,6 +1626,7 @@ func (lb *LoadBalance) URLTest(ctx context.Context) (map[string]uint16, error) {
 	}
 	wg.Wait()
 	close(resultChan)
+	lb.udpHealth.SetChecked()
 
 	// Update candidate pools after testing
 	lb.updateCandidates()
@@ -0,0 +1,130 @@
+package group
+
+import (
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/contrab/freelru"
+	"github.com/sagernet/sing/contrab/maphash"
+)
+
+const (
+	defaultStickyTTL        = 30 * time.Minute
+	defaultStickyMaxEntries = 4096
+)
+
+// stickyTable records hash key to outbound assignments in an LRU with TTL.
+// Every hit refreshes the TTL. A nil stickyTable never holds assignments.
+type stickyTable struct {
+	group     string
+	ttl       time.Duration
+	cache     freelru.Cache[string, stickyAssignment]
+	cacheFile adapter.StickySessionStore
+	logger    log.ContextLogger
+}
+
+type stickyAssignment struct {
+	outbound string
+	// persistedAt is the time the assignment was last written to the cache file.
+	persistedAt int64
+}
+
+func newStickyTable(group string, options *option.LoadBalanceStickyOptions, logger log.ContextLogger) *stickyTable {
+	if options == nil {
+		return nil
+	}
+	ttl := time.Duration(options.TTL)
+	if ttl == 0 {
+		ttl = defaultStickyTTL
+	}
+	maxEntries := options.MaxEntries
+	if maxEntries == 0 {
+		maxEntries = defaultStickyMaxEntries
+	}
+	cache := common.Must1(freelru.NewSynced[string, stickyAssignment](maxEntries, maphash.NewHasher[string]().Hash32))
+	cache.SetLifetime(ttl)
+	return &stickyTable{
+		group:  group,
+		ttl:    ttl,
+		cache:  cache,
+		logger: logger,
+	}
+}
+
+// Restore loads persisted assignments and enables writing new ones back to cacheFile.
+func (t *stickyTable) Restore(cacheFile adapter.StickySessionStore) {
+	if t == nil || cacheFile == nil {
+		return
+	}
+	t.cacheFile = cacheFile
+	now := time.Now()
+	sessions := cacheFile.LoadStickySessions(t.group)
+	for key, session := range sessions {
+		lifetime := session.ExpiresAt.Sub(now)
+		if lifetime <= 0 {
+			continue
+		}
+		t.cache.AddWithLifetime(key, stickyAssignment{
+			outbound:    session.Outbound,
+			persistedAt: now.Unix(),
+		}, lifetime)
+	}
+	if len(sessions) > 0 {
+		t.logger.Debug("restored ", t.cache.Len(), " sticky sessions")
+	}
+}
+
+// Select returns the candidate key is assigned to, refreshing its TTL. It
+// returns nil if the key has no live assignment or the assigned outbound is
+// no longer among candidates.
+func (t *stickyTable) Select(key string, candidates []adapter.Outbound) adapter.Outbound {
+	if t == nil || key == "" {
+		return nil
+	}
+	assignment, loaded := t.cache.Get(key)
+	if !loaded {
+		return nil
+	}
+	for _, candidate := range candidates {
+		if candidate.Tag() == assignment.outbound {
+			t.store(key, assignment)
+			return candidate
+		}
+	}
+	return nil
+}
+
+// Store assigns key to outbound, replacing any previous assignment.
+func (t *stickyTable) Store(key string, outbound string) {
+	if t == nil || key == "" {
+		return
+	}
+	t.store(key, stickyAssignment{outbound: outbound})
+}
+
+func (t *stickyTable) store(key string, assignment stickyAssignment) {
+	now := time.Now()
+	// Refreshing an assignment only rewrites the cache file once half of the
+	// persisted TTL has passed, so hot keys do not cause a write per connection.
+	persist := t.cacheFile != nil && time.Duration(now.Unix()-assignment.persistedAt)*time.Second >= t.ttl/2
+	if persist {
+		assignment.persistedAt = now.Unix()
+	}
+	t.cache.Add(key, assignment)
+	if !persist {
+		return
+	}
+	session := adapter.SavedStickySession{
+		Outbound:  assignment.outbound,
+		ExpiresAt: now.Add(t.ttl),
+	}
+	go func() {
+		err := t.cacheFile.StoreStickySession(t.group, key, session)
+		if err != nil {
+			t.logger.Warn("store sticky session: ", err)
+		}
+	}()
+}
@@ -0,0 +1,188 @@
+package group
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json/badoption"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Mock sticky session store
+type mockStickySessionStore struct {
+	access   sync.Mutex
+	sessions map[string]adapter.SavedStickySession
+}
+
+func (m *mockStickySessionStore) LoadStickySessions(group string) map[string]adapter.SavedStickySession {
+	m.access.Lock()
+	defer m.access.Unlock()
+	sessions := make(map[string]adapter.SavedStickySession, len(m.sessions))
+	for key, session := range m.sessions {
+		sessions[key] = session
+	}
+	return sessions
+}
+
+func (m *mockStickySessionStore) StoreStickySession(group string, key string, session adapter.SavedStickySession) error {
+	m.access.Lock()
+	defer m.access.Unlock()
+	m.sessions[key] = session
+	return nil
+}
+
+func (m *mockStickySessionStore) Load(key string) (adapter.SavedStickySession, bool) {
+	m.access.Lock()
+	defer m.access.Unlock()
+	session, loaded := m.sessions[key]
+	return session, loaded
+}
+
+func newStickyTestLoadBalance(candidates []adapter.Outbound) *LoadBalance {
+	lb := &LoadBalance{
+		logger:           &mockLogger{},
+		strategy:         strategyConsistentHash,
+		hashKeyParts:     []string{"src_ip"},
+		hashOnEmptyKey:   onEmptyKeyRandom,
+		hashVirtualNodes: 100,
+		sticky:           newStickyTable("lb", &option.LoadBalanceStickyOptions{}, &mockLogger{}),
+	}
+	lb.setStickyTestCandidates(candidates)
+	return lb
+}
+
+func (lb *LoadBalance) setStickyTestCandidates(candidates []adapter.Outbound) {
+	lb.candidateState.Store(&candidateSnapshot{
+		primaryCandidates: candidates,
+		activeTier:        "primary",
+		hashRing:          lb.buildHashRing(candidates),
+	})
+}
+
+func TestStickyKeepsAssignmentOnMembershipChange(t *testing.T) {
+	candidates := newStrategyTestCandidates("a", "b", "c")
+	lb := newStickyTestLoadBalance(candidates)
+
+	assignments := make(map[string]string)
+	for i := 0; i < 50; i++ {
+		metadata := &adapter.InboundContext{Source: M.ParseSocksaddr(fmt.Sprintf("10.0.0.%d:1000", i))}
+		selected, err := lb.selectOutbound("tcp", metadata)
+		require.NoError(t, err)
+		assignments[metadata.Source.String()] = selected.Tag()
+	}
+
+	// Adding a member would remap some keys on the ring, but sticky keys stay put
+	lb.setStickyTestCandidates(newStrategyTestCandidates("a", "b", "c", "d", "e"))
+	for source, tag := range assignments {
+		selected, err := lb.selectOutbound("tcp", &adapter.InboundContext{Source: M.ParseSocksaddr(source)})
+		require.NoError(t, err)
+		assert.Equal(t, tag, selected.Tag(), "sticky key %s must keep its outbound", source)
+	}
+
+	// Removing a member rehashes only the keys assigned to it
+	lb.setStickyTestCandidates(newStrategyTestCandidates("b", "c", "d", "e"))
+	for source, tag := range assignments {
+		selected, err := lb.selectOutbound("tcp", &adapter.InboundContext{Source: M.ParseSocksaddr(source)})
+		require.NoError(t, err)
+		if tag == "a" {
+			assert.NotEqual(t, "a", selected.Tag())
+			assignments[source] = selected.Tag()
+		} else {
+			assert.Equal(t, tag, selected.Tag())
+		}
+	}
+
+	// The rehashed assignment sticks even after the member comes back
+	lb.setStickyTestCandidates(newStrategyTestCandidates("a", "b", "c", "d", "e"))
+	for source, tag := range assignments {
+		selected, err := lb.selectOutbound("tcp", &adapter.InboundContext{Source: M.ParseSocksaddr(source)})
+		require.NoError(t, err)
+		assert.Equal(t, tag, selected.Tag())
+	}
+}
+
+func TestStickyIgnoresEjectedMember(t *testing.T) {
+	candidates := newStrategyTestCandidates("a", "b")
+	lb := newStickyTestLoadBalance(candidates)
+	lb.outlier = newOutlierDetector(&option.OutlierDetectionOptions{Failures: 1})
+
+	metadata := &adapter.InboundContext{Source: M.ParseSocksaddr("10.0.0.1:1000")}
+	selected, err := lb.selectOutbound("tcp", metadata)
+	require.NoError(t, err)
+	original := selected.Tag()
+
+	lb.outlier.ReportFailure(original)
+	selected, err = lb.selectOutbound("tcp", metadata)
+	require.NoError(t, err)
+	assert.NotEqual(t, original, selected.Tag())
+}
+
+func TestStickyTTL(t *testing.T) {
+	table := newStickyTable("lb", &option.LoadBalanceStickyOptions{
+		TTL: badoption.Duration(50 * time.Millisecond),
+	}, &mockLogger{})
+	candidates := newStrategyTestCandidates("a", "b")
+
+	table.Store("key", "b")
+	require.NotNil(t, table.Select("key", candidates))
+
+	// A hit refreshes the TTL
+	time.Sleep(30 * time.Millisecond)
+	require.NotNil(t, table.Select("key", candidates))
+	time.Sleep(30 * time.Millisecond)
+	selected := table.Select("key", candidates)
+	require.NotNil(t, selected)
+	assert.Equal(t, "b", selected.Tag())
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Nil(t, table.Select("key", candidates))
+}
+
+func TestStickyMaxEntries(t *testing.T) {
+	table := newStickyTable("lb", &option.LoadBalanceStickyOptions{MaxEntries: 2}, &mockLogger{})
+	candidates := newStrategyTestCandidates("a")
+
+	table.Store("k1", "a")
+	table.Store("k2", "a")
+	table.Select("k1", candidates)
+	table.Store("k3", "a")
+
+	assert.NotNil(t, table.Select("k1", candidates))
+	assert.Nil(t, table.Select("k2", candidates), "least recently used key must be evicted")
+	assert.NotNil(t, table.Select("k3", candidates))
+}
+
+func TestStickyPersistence(t *testing.T) {
+	store := &mockStickySessionStore{sessions: map[string]adapter.SavedStickySession{
+		"restored": {Outbound: "b", ExpiresAt: time.Now().Add(time.Minute)},
+		"expired":  {Outbound: "b", ExpiresAt: time.Now().Add(-time.Minute)},
+	}}
+	table := newStickyTable("lb", &option.LoadBalanceStickyOptions{Persist: true}, &mockLogger{})
+	table.Restore(store)
+	candidates := newStrategyTestCandidates("a", "b")
+
+	selected := table.Select("restored", candidates)
+	require.NotNil(t, selected)
+	assert.Equal(t, "b", selected.Tag())
+	assert.Nil(t, table.Select("expired", candidates))
+
+	table.Store("new", "a")
+	require.Eventually(t, func() bool {
+		session, loaded := store.Load("new")
+		return loaded && session.Outbound == "a"
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestStickyNilTable(t *testing.T) {
+	var table *stickyTable
+	table.Store("key", "a")
+	table.Restore(&mockStickySessionStore{})
+	assert.Nil(t, table.Select("key", newStrategyTestCandidates("a")))
+}
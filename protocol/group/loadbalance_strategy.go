@@ -0,0 +1,317 @@
+package group
+
+import (
+	"math"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common/bufio"
+)
+
+// memberConnections counts live connections and transferred bytes per member
+// outbound. The map is built once at construction and never mutated afterwards,
+// so lookups are lock-free.
+type memberConnections struct {
+	counters map[string]*memberCounter
+}
+
+type memberCounter struct {
+	connections atomic.Int64
+	upload      atomic.Int64
+	download    atomic.Int64
+}
+
+func newMemberConnections(tags []string) *memberConnections {
+	counters := make(map[string]*memberCounter, len(tags))
+	for _, tag := range tags {
+		counters[tag] = new(memberCounter)
+	}
+	return &memberConnections{counters: counters}
+}
+
+func (m *memberConnections) Load(tag string) int64 {
+	counter := m.counter(tag)
+	if counter == nil {
+		return 0
+	}
+	return counter.connections.Load()
+}
+
+// Traffic returns the total bytes sent and received through the member.
+func (m *memberConnections) Traffic(tag string) (upload int64, download int64) {
+	counter := m.counter(tag)
+	if counter == nil {
+		return 0, 0
+	}
+	return counter.upload.Load(), counter.download.Load()
+}
+
+func (m *memberConnections) counter(tag string) *memberCounter {
+	if m == nil {
+		return nil
+	}
+	return m.counters[tag]
+}
+
+// NewConn wraps conn so that its traffic is counted and the member's live
+// connection count is decremented on close.
+func (m *memberConnections) NewConn(tag string, conn net.Conn) net.Conn {
+	counter := m.counter(tag)
+	if counter == nil {
+		return conn
+	}
+	counter.connections.Add(1)
+	conn = bufio.NewInt64CounterConn(conn, []*atomic.Int64{&counter.download}, []*atomic.Int64{&counter.upload})
+	return &memberConn{Conn: conn, counter: counter}
+}
+
+// NewPacketConn wraps conn so that its traffic is counted and the member's live
+// connection count is decremented on close.
+func (m *memberConnections) NewPacketConn(tag string, conn net.PacketConn) net.PacketConn {
+	counter := m.counter(tag)
+	if counter == nil {
+		return conn
+	}
+	counter.connections.Add(1)
+	conn = bufio.NewNetPacketConn(bufio.NewInt64CounterPacketConn(bufio.NewPacketConn(conn), []*atomic.Int64{&counter.download}, nil, []*atomic.Int64{&counter.upload}, nil))
+	return &memberPacketConn{PacketConn: conn, counter: counter}
+}
+
+type memberConn struct {
+	net.Conn
+	counter   *memberCounter
+	closeOnce sync.Once
+}
+
+func (c *memberConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.counter.connections.Add(-1)
+	})
+	return c.Conn.Close()
+}
+
+func (c *memberConn) ReaderReplaceable() bool {
+	return true
+}
+
+func (c *memberConn) WriterReplaceable() bool {
+	return true
+}
+
+func (c *memberConn) Upstream() any {
+	return c.Conn
+}
+
+type memberPacketConn struct {
+	net.PacketConn
+	counter   *memberCounter
+	closeOnce sync.Once
+}
+
+func (c *memberPacketConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.counter.connections.Add(-1)
+	})
+	return c.PacketConn.Close()
+}
+
+func (c *memberPacketConn) ReaderReplaceable() bool {
+	return true
+}
+
+func (c *memberPacketConn) WriterReplaceable() bool {
+	return true
+}
+
+func (c *memberPacketConn) Upstream() any {
+	return c.PacketConn
+}
+
+// weightedRoundRobin implements NGINX-style smooth weighted round-robin.
+// State survives candidate pool changes so that a rebuilt pool does not
+// restart the sequence from the heaviest member.
+type weightedRoundRobin struct {
+	access  sync.Mutex
+	current map[string]int64
+}
+
+func (w *weightedRoundRobin) Next(candidates []adapter.Outbound, weight func(tag string) uint32) adapter.Outbound {
+	w.access.Lock()
+	defer w.access.Unlock()
+	if w.current == nil {
+		w.current = make(map[string]int64)
+	}
+	var (
+		total    int64
+		selected adapter.Outbound
+		maxValue int64
+	)
+	for _, candidate := range candidates {
+		tag := candidate.Tag()
+		candidateWeight := int64(weight(tag))
+		total += candidateWeight
+		w.current[tag] += candidateWeight
+		if selected == nil || w.current[tag] > maxValue {
+			selected = candidate
+			maxValue = w.current[tag]
+		}
+	}
+	if selected != nil {
+		w.current[selected.Tag()] -= total
+	}
+	return selected
+}
+
+// weight returns the configured weight of a member, defaulting to 1.
+func (lb *LoadBalance) weight(tag string) uint32 {
+	if weight, loaded := lb.weights[tag]; loaded {
+		return weight
+	}
+	return 1
+}
+
+// selectRoundRobin cycles through candidates in order.
+func (lb *LoadBalance) selectRoundRobin(candidates []adapter.Outbound) adapter.Outbound {
+	index := lb.roundRobinIndex.Add(1) - 1
+	return candidates[index%uint64(len(candidates))]
+}
+
+// selectWeightedRoundRobin picks candidates proportionally to their configured weights.
+func (lb *LoadBalance) selectWeightedRoundRobin(candidates []adapter.Outbound) adapter.Outbound {
+	return lb.weightedRoundRobin.Next(candidates, lb.weight)
+}
+
+// selectLeastConnections picks the candidate with the fewest live connections.
+// Ties are broken by weight (higher first), then by candidate order, which
+// follows latency ranking from selectTopN.
+func (lb *LoadBalance) selectLeastConnections(candidates []adapter.Outbound) adapter.Outbound {
+	var (
+		selected       adapter.Outbound
+		selectedLoad   int64
+		selectedWeight uint32
+	)
+	for _, candidate := range candidates {
+		load := lb.memberConnections.Load(candidate.Tag())
+		weight := lb.weight(candidate.Tag())
+		// Compare load/weight without division: a/wa < b/wb <=> a*wb < b*wa
+		if selected == nil ||
+			load*int64(selectedWeight) < selectedLoad*int64(weight) ||
+			(load*int64(selectedWeight) == selectedLoad*int64(weight) && weight > selectedWeight) {
+			selected = candidate
+			selectedLoad = load
+			selectedWeight = weight
+		}
+	}
+	return selected
+}
+
+// latencyEWMA keeps an exponentially weighted moving average of probe delays
+// next to the shared URLTestHistoryStorage, which only retains the latest sample.
+type latencyEWMA struct {
+	access sync.RWMutex
+	alpha  float64
+	values map[string]float64
+}
+
+func newLatencyEWMA(alpha float64) *latencyEWMA {
+	return &latencyEWMA{
+		alpha:  alpha,
+		values: make(map[string]float64),
+	}
+}
+
+func (e *latencyEWMA) Update(tag string, delay uint16) float64 {
+	if e == nil {
+		return float64(delay)
+	}
+	e.access.Lock()
+	defer e.access.Unlock()
+	value, loaded := e.values[tag]
+	if !loaded {
+		value = float64(delay)
+	} else {
+		value = e.alpha*float64(delay) + (1-e.alpha)*value
+	}
+	e.values[tag] = value
+	return value
+}
+
+func (e *latencyEWMA) Load(tag string) (float64, bool) {
+	if e == nil {
+		return 0, false
+	}
+	e.access.RLock()
+	defer e.access.RUnlock()
+	value, loaded := e.values[tag]
+	return value, loaded
+}
+
+func (e *latencyEWMA) Delete(tag string) {
+	if e == nil {
+		return
+	}
+	e.access.Lock()
+	defer e.access.Unlock()
+	delete(e.values, tag)
+}
+
+// smoothedDelay returns the EWMA delay of a member, falling back to the latest
+// URL test result. It reports false if the member has never been probed.
+func (lb *LoadBalance) smoothedDelay(detour adapter.Outbound) (float64, bool) {
+	realTag := RealTag(detour)
+	if delay, loaded := lb.latencyEWMA.Load(realTag); loaded {
+		return delay, true
+	}
+	if lb.history != nil {
+		if history := lb.history.LoadURLTestHistory(realTag); history != nil {
+			return float64(history.Delay), true
+		}
+	}
+	return 0, false
+}
+
+// selectLatencyWeighted picks a candidate with probability inversely
+// proportional to its smoothed delay raised to latencyExponent.
+// Candidates without any measurement get the mean weight of measured ones,
+// or all candidates are weighted equally if none has been measured yet.
+func (lb *LoadBalance) selectLatencyWeighted(candidates []adapter.Outbound) adapter.Outbound {
+	weights := make([]float64, len(candidates))
+	var (
+		measuredTotal float64
+		measuredCount int
+	)
+	for i, candidate := range candidates {
+		delay, loaded := lb.smoothedDelay(candidate)
+		if !loaded {
+			continue
+		}
+		if delay < 1 {
+			delay = 1
+		}
+		weights[i] = 1 / math.Pow(delay, lb.latencyExponent)
+		measuredTotal += weights[i]
+		measuredCount++
+	}
+	fallbackWeight := 1.0
+	if measuredCount > 0 {
+		fallbackWeight = measuredTotal / float64(measuredCount)
+	}
+	var total float64
+	for i := range weights {
+		if weights[i] == 0 {
+			weights[i] = fallbackWeight
+		}
+		total += weights[i]
+	}
+	point := rand.Float64() * total
+	for i, weight := range weights {
+		point -= weight
+		if point < 0 {
+			return candidates[i]
+		}
+	}
+	return candidates[len(candidates)-1]
+}
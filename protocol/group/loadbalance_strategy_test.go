@@ -0,0 +1,267 @@
+package group
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newStrategyTestCandidates(tags ...string) []adapter.Outbound {
+	candidates := make([]adapter.Outbound, 0, len(tags))
+	for _, tag := range tags {
+		candidates = append(candidates, &mockOutbound{tag: tag, network: []string{"tcp", "udp"}})
+	}
+	return candidates
+}
+
+func TestRoundRobinSelection(t *testing.T) {
+	lb := &LoadBalance{logger: &mockLogger{}}
+	candidates := newStrategyTestCandidates("a", "b", "c")
+
+	var selected []string
+	for i := 0; i < 6; i++ {
+		selected = append(selected, lb.selectRoundRobin(candidates).Tag())
+	}
+	assert.Equal(t, []string{"a", "b", "c", "a", "b", "c"}, selected)
+}
+
+func TestWeightedRoundRobinSelection(t *testing.T) {
+	lb := &LoadBalance{
+		logger:  &mockLogger{},
+		weights: map[string]uint32{"a": 5, "b": 1},
+	}
+	candidates := newStrategyTestCandidates("a", "b", "c")
+
+	counts := make(map[string]int)
+	for i := 0; i < 70; i++ {
+		counts[lb.selectWeightedRoundRobin(candidates).Tag()]++
+	}
+	// Total weight is 7 (c defaults to 1), so 10 full rounds
+	assert.Equal(t, 50, counts["a"])
+	assert.Equal(t, 10, counts["b"])
+	assert.Equal(t, 10, counts["c"])
+
+	// Smooth WRR must not pick the heavy member five times in a row
+	lb.weightedRoundRobin = weightedRoundRobin{}
+	var sequence []string
+	for i := 0; i < 7; i++ {
+		sequence = append(sequence, lb.selectWeightedRoundRobin(candidates).Tag())
+	}
+	assert.Contains(t, sequence[:4], "b")
+}
+
+func TestLeastConnectionsSelection(t *testing.T) {
+	lb := &LoadBalance{
+		logger:            &mockLogger{},
+		memberConnections: newMemberConnections([]string{"a", "b", "c"}),
+	}
+	candidates := newStrategyTestCandidates("a", "b", "c")
+
+	// All idle: first candidate (lowest latency) wins
+	assert.Equal(t, "a", lb.selectLeastConnections(candidates).Tag())
+
+	connA, _ := net.Pipe()
+	wrappedA := lb.memberConnections.NewConn("a", connA)
+	connB, _ := net.Pipe()
+	wrappedB := lb.memberConnections.NewConn("b", connB)
+
+	assert.Equal(t, int64(1), lb.memberConnections.Load("a"))
+	assert.Equal(t, "c", lb.selectLeastConnections(candidates).Tag())
+
+	require.NoError(t, wrappedA.Close())
+	// Double close must not drive the counter negative
+	wrappedA.Close()
+	assert.Equal(t, int64(0), lb.memberConnections.Load("a"))
+	assert.Equal(t, "a", lb.selectLeastConnections(candidates).Tag())
+
+	require.NoError(t, wrappedB.Close())
+	assert.Equal(t, int64(0), lb.memberConnections.Load("b"))
+}
+
+func TestLeastConnectionsWeighted(t *testing.T) {
+	lb := &LoadBalance{
+		logger:            &mockLogger{},
+		weights:           map[string]uint32{"a": 3},
+		memberConnections: newMemberConnections([]string{"a", "b"}),
+	}
+	candidates := newStrategyTestCandidates("a", "b")
+
+	var conns []net.Conn
+	for i := 0; i < 2; i++ {
+		conn, _ := net.Pipe()
+		conns = append(conns, lb.memberConnections.NewConn("a", conn))
+	}
+	conn, _ := net.Pipe()
+	conns = append(conns, lb.memberConnections.NewConn("b", conn))
+
+	// a: 2/3 < b: 1/1
+	assert.Equal(t, "a", lb.selectLeastConnections(candidates).Tag())
+	for _, conn := range conns {
+		conn.Close()
+	}
+}
+
+func TestMemberTraffic(t *testing.T) {
+	connections := newMemberConnections([]string{"a"})
+	client, server := net.Pipe()
+	wrapped := connections.NewConn("a", client)
+	go func() {
+		buffer := make([]byte, 5)
+		io.ReadFull(server, buffer)
+		server.Write([]byte("abc"))
+	}()
+	_, err := wrapped.Write([]byte("hello"))
+	require.NoError(t, err)
+	_, err = io.ReadFull(wrapped, make([]byte, 3))
+	require.NoError(t, err)
+	upload, download := connections.Traffic("a")
+	assert.Equal(t, int64(5), upload)
+	assert.Equal(t, int64(3), download)
+	wrapped.Close()
+	server.Close()
+
+	upload, download = connections.Traffic("unknown")
+	assert.Zero(t, upload)
+	assert.Zero(t, download)
+}
+
+func TestLoadBalanceStatus(t *testing.T) {
+	primary := newStrategyTestCandidates("p1", "p2")
+	backup := newStrategyTestCandidates("b1")
+	outbounds := make(map[string]adapter.Outbound)
+	for _, detour := range append(append([]adapter.Outbound{}, primary...), backup...) {
+		outbounds[detour.Tag()] = detour
+	}
+	history := urltest.NewHistoryStorage()
+	history.StoreURLTestHistory("p1", &adapter.URLTestHistory{Time: time.Now(), Delay: 42})
+	lb := &LoadBalance{
+		logger:              &mockLogger{},
+		outbound:            &mockOutboundManager{outbounds: outbounds},
+		primaryTags:         []string{"p1", "p2"},
+		backupTags:          []string{"b1"},
+		strategy:            strategyConsistentHash,
+		hashVirtualNodes:    10,
+		hystPrimaryFailures: 3,
+		hystBackupHoldTime:  time.Minute,
+		history:             history,
+		memberConnections:   newMemberConnections([]string{"p1", "p2", "b1"}),
+	}
+	lb.candidateState.Store(&candidateSnapshot{
+		primaryCandidates: primary[:1],
+		backupCandidates:  backup,
+		activeTier:        "primary",
+		hashRing:          lb.buildHashRing(primary[:1]),
+	})
+	lb.tierState.Store(&tierStateSnapshot{activeTier: "primary", primaryFailureCount: 2})
+	conn, _ := net.Pipe()
+	conn = lb.memberConnections.NewConn("p1", conn)
+	defer conn.Close()
+
+	status := lb.LoadBalanceStatus()
+	assert.Equal(t, strategyConsistentHash, status.Strategy)
+	assert.Equal(t, "primary", status.ActiveTier)
+	assert.Equal(t, []string{"p1"}, status.PrimaryCandidates)
+	assert.Equal(t, []string{"b1"}, status.BackupCandidates)
+	assert.Equal(t, []string{"p1"}, status.RingMembers)
+	assert.Equal(t, uint32(2), status.Hysteresis.PrimaryFailures)
+	assert.Equal(t, uint32(3), status.Hysteresis.PrimaryFailuresThreshold)
+	require.Len(t, status.Members, 3)
+	assert.Equal(t, adapter.LoadBalanceMemberStatus{Name: "p1", Tier: "primary", Candidate: true, Delay: 42, Connections: 1}, status.Members[0])
+	assert.Equal(t, adapter.LoadBalanceMemberStatus{Name: "p2", Tier: "primary"}, status.Members[1])
+	assert.Equal(t, adapter.LoadBalanceMemberStatus{Name: "b1", Tier: "backup", Candidate: true}, status.Members[2])
+}
+
+func TestLatencyEWMA(t *testing.T) {
+	ewma := newLatencyEWMA(0.5)
+	assert.Equal(t, 100.0, ewma.Update("a", 100))
+	assert.Equal(t, 150.0, ewma.Update("a", 200))
+	assert.Equal(t, 125.0, ewma.Update("a", 100))
+
+	value, loaded := ewma.Load("a")
+	assert.True(t, loaded)
+	assert.Equal(t, 125.0, value)
+
+	ewma.Delete("a")
+	_, loaded = ewma.Load("a")
+	assert.False(t, loaded)
+}
+
+func TestLatencyWeightedSelection(t *testing.T) {
+	history := urltest.NewHistoryStorage()
+	now := time.Now()
+	history.StoreURLTestHistory("fast", &adapter.URLTestHistory{Time: now, Delay: 10})
+	history.StoreURLTestHistory("slow", &adapter.URLTestHistory{Time: now, Delay: 90})
+
+	lb := &LoadBalance{
+		logger:          &mockLogger{},
+		history:         history,
+		latencyExponent: 1,
+		latencyEWMA:     newLatencyEWMA(1),
+	}
+	candidates := newStrategyTestCandidates("fast", "slow")
+
+	counts := make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		counts[lb.selectLatencyWeighted(candidates).Tag()]++
+	}
+	// Expected share of fast: (1/10) / (1/10 + 1/90) = 0.9
+	assert.InDelta(t, 9000, counts["fast"], 300)
+	assert.Greater(t, counts["slow"], 0, "slow member should still receive spillover")
+
+	// A higher exponent concentrates traffic on the fast member
+	lb.latencyExponent = 2
+	counts = make(map[string]int)
+	for i := 0; i < 10000; i++ {
+		counts[lb.selectLatencyWeighted(candidates).Tag()]++
+	}
+	// (1/100) / (1/100 + 1/8100) ~= 0.988
+	assert.InDelta(t, 9878, counts["fast"], 150)
+}
+
+func TestLatencyWeightedPrefersEWMA(t *testing.T) {
+	history := urltest.NewHistoryStorage()
+	now := time.Now()
+	// Latest probe of a is a spike, but its smoothed delay is still low
+	history.StoreURLTestHistory("a", &adapter.URLTestHistory{Time: now, Delay: 500})
+	history.StoreURLTestHistory("b", &adapter.URLTestHistory{Time: now, Delay: 100})
+
+	lb := &LoadBalance{
+		logger:          &mockLogger{},
+		history:         history,
+		latencyExponent: 1,
+		latencyEWMA:     newLatencyEWMA(0.1),
+	}
+	for i := 0; i < 10; i++ {
+		lb.latencyEWMA.Update("a", 20)
+	}
+	lb.latencyEWMA.Update("a", 500)
+	lb.latencyEWMA.Update("b", 100)
+
+	delay, loaded := lb.smoothedDelay(&mockOutbound{tag: "a"})
+	require.True(t, loaded)
+	assert.Less(t, delay, 100.0)
+}
+
+func TestLatencyWeightedUnmeasured(t *testing.T) {
+	lb := &LoadBalance{
+		logger:          &mockLogger{},
+		history:         urltest.NewHistoryStorage(),
+		latencyExponent: 1,
+		latencyEWMA:     newLatencyEWMA(defaultLatencySmoothing),
+	}
+	candidates := newStrategyTestCandidates("a", "b")
+
+	counts := make(map[string]int)
+	for i := 0; i < 1000; i++ {
+		counts[lb.selectLatencyWeighted(candidates).Tag()]++
+	}
+	assert.Greater(t, counts["a"], 0)
+	assert.Greater(t, counts["b"], 0)
+}
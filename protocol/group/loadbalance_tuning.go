@@ -0,0 +1,97 @@
+package group
+
+import (
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// loadBalanceTuning holds the parameters that can be changed at runtime.
+// A stored value is never modified: Tune swaps in a new one, so readers see
+// either the old or the new parameters as a whole.
+type loadBalanceTuning struct {
+	topNPrimary         int
+	topNBackup          int
+	strategy            string
+	hashKeyParts        []string
+	hystPrimaryFailures uint32
+	hystBackupHoldTime  time.Duration
+}
+
+// loadTuning returns the current runtime parameters. Until Tune is called,
+// these are the configured ones.
+func (lb *LoadBalance) loadTuning() *loadBalanceTuning {
+	if tuning := lb.tuning.Load(); tuning != nil {
+		return tuning
+	}
+	return &loadBalanceTuning{
+		topNPrimary:         lb.topNPrimary,
+		topNBackup:          lb.topNBackup,
+		strategy:            lb.strategy,
+		hashKeyParts:        lb.hashKeyParts,
+		hystPrimaryFailures: lb.hystPrimaryFailures,
+		hystBackupHoldTime:  lb.hystBackupHoldTime,
+	}
+}
+
+// Tune changes top_n, strategy, hash key parts and hysteresis thresholds
+// without restarting the group. Candidate pools and the hash ring are rebuilt
+// from the latest health check results and swapped in atomically, so existing
+// connections are kept. New hysteresis thresholds apply from the next check.
+func (lb *LoadBalance) Tune(options option.LoadBalanceTuningOptions) error {
+	lb.updateAccess.Lock()
+	defer lb.updateAccess.Unlock()
+
+	tuning := *lb.loadTuning()
+	if options.TopN != nil {
+		if options.TopN.Primary < 0 || options.TopN.Backup < 0 {
+			return E.New("top_n must be >= 0")
+		}
+		if options.TopN.Primary > 0 {
+			tuning.topNPrimary = options.TopN.Primary
+		}
+		if options.TopN.Backup > 0 {
+			tuning.topNBackup = options.TopN.Backup
+		}
+	}
+	if options.Strategy != "" {
+		switch options.Strategy {
+		case strategyRandom, strategyConsistentHash, strategyRoundRobin, strategyWeightedRoundRobin, strategyLeastConnections, strategyLatencyWeighted:
+		default:
+			return E.New("strategy must be one of 'random', 'consistent_hash', 'round_robin', 'weighted_round_robin', 'least_connections' or 'latency_weighted'")
+		}
+		tuning.strategy = options.Strategy
+	}
+	if len(options.KeyParts) > 0 {
+		tuning.hashKeyParts = options.KeyParts
+	}
+	if options.Hysteresis != nil {
+		if options.Hysteresis.PrimaryFailures > 0 {
+			tuning.hystPrimaryFailures = options.Hysteresis.PrimaryFailures
+		}
+		if options.Hysteresis.BackupHoldTime > 0 {
+			tuning.hystBackupHoldTime = time.Duration(options.Hysteresis.BackupHoldTime)
+		}
+	}
+	if tuning.strategy == strategyConsistentHash && lb.hashVirtualNodes == 0 {
+		return E.New("hash configuration required for consistent_hash strategy")
+	}
+	if lb.sticky != nil && tuning.strategy != strategyConsistentHash {
+		return E.New("hash.sticky requires consistent_hash strategy")
+	}
+	lb.tuning.Store(&tuning)
+	lb.logger.Info(
+		"tuned: strategy=", tuning.strategy,
+		", top_n=", tuning.topNPrimary, "/", tuning.topNBackup,
+		", key_parts=", strings.Join(tuning.hashKeyParts, ","),
+		", hysteresis=", tuning.hystPrimaryFailures, "/", tuning.hystBackupHoldTime,
+	)
+
+	// Keep bootstrap mode until the first health check has run
+	if lb.candidateState.Load() != nil {
+		lb.rebuildCandidates(false)
+	}
+	return nil
+}
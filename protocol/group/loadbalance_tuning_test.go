@@ -0,0 +1,117 @@
+package group
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/json/badoption"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTuningTestLoadBalance() *LoadBalance {
+	history := urltest.NewHistoryStorage()
+	now := time.Now()
+	history.StoreURLTestHistory("p1", &adapter.URLTestHistory{Time: now, Delay: 10})
+	history.StoreURLTestHistory("p2", &adapter.URLTestHistory{Time: now, Delay: 20})
+	history.StoreURLTestHistory("p3", &adapter.URLTestHistory{Time: now, Delay: 30})
+	lb := &LoadBalance{
+		logger:              &mockLogger{},
+		primaryTags:         []string{"p1", "p2", "p3"},
+		topNPrimary:         1,
+		interval:            time.Minute,
+		history:             history,
+		strategy:            strategyRandom,
+		hashVirtualNodes:    10,
+		hystPrimaryFailures: 3,
+		hystBackupHoldTime:  time.Minute,
+		outbound: &mockOutboundManager{
+			outbounds: map[string]adapter.Outbound{
+				"p1": &mockOutbound{tag: "p1", network: []string{"tcp"}},
+				"p2": &mockOutbound{tag: "p2", network: []string{"tcp"}},
+				"p3": &mockOutbound{tag: "p3", network: []string{"tcp"}},
+			},
+		},
+	}
+	lb.tuning.Store(lb.loadTuning())
+	lb.tierState.Store(&tierStateSnapshot{activeTier: "primary"})
+	return lb
+}
+
+func TestLoadBalanceTune(t *testing.T) {
+	lb := newTuningTestLoadBalance()
+	lb.updateCandidates()
+	snapshot := lb.candidateState.Load().(*candidateSnapshot)
+	require.Len(t, snapshot.primaryCandidates, 1)
+	require.Nil(t, snapshot.hashRing)
+	lb.tierState.Store(&tierStateSnapshot{activeTier: "primary", primaryFailureCount: 2})
+
+	err := lb.Tune(option.LoadBalanceTuningOptions{
+		TopN:     &option.LoadBalanceTopNOptions{Primary: 2},
+		Strategy: strategyConsistentHash,
+		KeyParts: []string{"src_ip"},
+		Hysteresis: &option.LoadBalanceHysteresisOptions{
+			PrimaryFailures: 5,
+		},
+	})
+	require.NoError(t, err)
+
+	snapshot = lb.candidateState.Load().(*candidateSnapshot)
+	assert.Equal(t, []string{"p1", "p2"}, common.Map(snapshot.primaryCandidates, adapter.Outbound.Tag))
+	require.NotNil(t, snapshot.hashRing, "switching to consistent_hash must build the ring")
+	assert.ElementsMatch(t, []string{"p1", "p2"}, snapshot.hashRing.members)
+	// Tuning must not count as a health check round
+	assert.Equal(t, uint32(2), lb.tierState.Load().(*tierStateSnapshot).primaryFailureCount)
+
+	status := lb.LoadBalanceStatus()
+	assert.Equal(t, strategyConsistentHash, status.Strategy)
+	assert.Equal(t, uint32(5), status.Hysteresis.PrimaryFailuresThreshold)
+	assert.Equal(t, badoption.Duration(time.Minute), status.Hysteresis.BackupHoldTime, "omitted fields keep their value")
+
+	metadata := &adapter.InboundContext{Source: M.SocksaddrFrom(netip.MustParseAddr("10.0.0.1"), 1234)}
+	assert.Equal(t, "10.0.0.1", lb.buildHashKey(metadata))
+	first, err := lb.selectOutbound("tcp", metadata)
+	require.NoError(t, err)
+	for i := 0; i < 5; i++ {
+		selected, err := lb.selectOutbound("tcp", metadata)
+		require.NoError(t, err)
+		assert.Equal(t, first.Tag(), selected.Tag())
+	}
+}
+
+func TestLoadBalanceTuneBootstrap(t *testing.T) {
+	lb := newTuningTestLoadBalance()
+	require.NoError(t, lb.Tune(option.LoadBalanceTuningOptions{Strategy: strategyRoundRobin}))
+	assert.Nil(t, lb.candidateState.Load(), "tuning must not leave bootstrap mode")
+	var selected []string
+	for i := 0; i < 3; i++ {
+		detour, err := lb.selectOutbound("tcp", &adapter.InboundContext{})
+		require.NoError(t, err)
+		selected = append(selected, detour.Tag())
+	}
+	assert.Equal(t, []string{"p1", "p2", "p3"}, selected)
+}
+
+func TestLoadBalanceTuneInvalid(t *testing.T) {
+	lb := newTuningTestLoadBalance()
+	assert.Error(t, lb.Tune(option.LoadBalanceTuningOptions{Strategy: "bad"}))
+	assert.Error(t, lb.Tune(option.LoadBalanceTuningOptions{TopN: &option.LoadBalanceTopNOptions{Primary: -1}}))
+
+	lb.hashVirtualNodes = 0
+	assert.Error(t, lb.Tune(option.LoadBalanceTuningOptions{Strategy: strategyConsistentHash}), "consistent_hash requires hash configuration")
+
+	lb = newTuningTestLoadBalance()
+	require.NoError(t, lb.Tune(option.LoadBalanceTuningOptions{Strategy: strategyConsistentHash}))
+	lb.sticky = newStickyTable("", &option.LoadBalanceStickyOptions{}, lb.logger)
+	assert.Error(t, lb.Tune(option.LoadBalanceTuningOptions{Strategy: strategyRandom}), "sticky requires consistent_hash")
+
+	// Failed tuning leaves parameters untouched
+	assert.Equal(t, strategyConsistentHash, lb.loadTuning().strategy)
+}
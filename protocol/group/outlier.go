@@ -0,0 +1,234 @@
+package group
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+)
+
+const (
+	defaultOutlierFailures         = 5
+	defaultOutlierInterval         = 10 * time.Second
+	defaultOutlierMinimumRequests  = 10
+	defaultOutlierBaseEjectionTime = 30 * time.Second
+	defaultOutlierMaxEjectionTime  = 5 * time.Minute
+)
+
+// outlierDetector implements Envoy-style passive health checking: members
+// failing real dials are ejected for a back-off period without waiting for
+// the next active probe. A nil detector is valid and never ejects anything.
+type outlierDetector struct {
+	failures         uint32
+	interval         time.Duration
+	failureRate      uint8
+	minimumRequests  uint32
+	baseEjectionTime time.Duration
+	maxEjectionTime  time.Duration
+
+	access  sync.Mutex
+	members map[string]*outlierMember
+}
+
+type outlierMember struct {
+	windowStart  time.Time
+	requests     uint32
+	failures     uint32
+	ejectedUntil time.Time
+	ejections    uint32
+}
+
+func newOutlierDetector(options *option.OutlierDetectionOptions) *outlierDetector {
+	if options == nil {
+		return nil
+	}
+	detector := &outlierDetector{
+		failures:         options.Failures,
+		interval:         time.Duration(options.Interval),
+		failureRate:      options.FailureRate,
+		minimumRequests:  options.MinimumRequests,
+		baseEjectionTime: time.Duration(options.BaseEjectionTime),
+		maxEjectionTime:  time.Duration(options.MaxEjectionTime),
+		members:          make(map[string]*outlierMember),
+	}
+	if detector.failures == 0 {
+		detector.failures = defaultOutlierFailures
+	}
+	if detector.interval == 0 {
+		detector.interval = defaultOutlierInterval
+	}
+	if detector.failureRate > 100 {
+		detector.failureRate = 100
+	}
+	if detector.minimumRequests == 0 {
+		detector.minimumRequests = defaultOutlierMinimumRequests
+	}
+	if detector.baseEjectionTime == 0 {
+		detector.baseEjectionTime = defaultOutlierBaseEjectionTime
+	}
+	if detector.maxEjectionTime == 0 {
+		detector.maxEjectionTime = defaultOutlierMaxEjectionTime
+	}
+	if detector.maxEjectionTime < detector.baseEjectionTime {
+		detector.maxEjectionTime = detector.baseEjectionTime
+	}
+	return detector
+}
+
+// member returns the state of tag with its window rolled forward. Must be called with access held.
+func (d *outlierDetector) member(tag string, now time.Time) *outlierMember {
+	member := d.members[tag]
+	if member == nil {
+		member = &outlierMember{windowStart: now}
+		d.members[tag] = member
+		return member
+	}
+	if now.Sub(member.windowStart) >= d.interval {
+		// A clean window after an ejection slowly forgives the member
+		if member.failures == 0 && member.ejections > 0 && !now.Before(member.ejectedUntil) {
+			member.ejections--
+		}
+		member.windowStart = now
+		member.requests = 0
+		member.failures = 0
+	}
+	return member
+}
+
+func (d *outlierDetector) ReportSuccess(tag string) {
+	if d == nil {
+		return
+	}
+	d.access.Lock()
+	defer d.access.Unlock()
+	now := time.Now()
+	member := d.member(tag, now)
+	if now.Before(member.ejectedUntil) {
+		return
+	}
+	member.requests++
+}
+
+// ReportFailure records a failed dial through tag and reports whether the member got ejected by it.
+func (d *outlierDetector) ReportFailure(tag string) bool {
+	if d == nil {
+		return false
+	}
+	d.access.Lock()
+	defer d.access.Unlock()
+	now := time.Now()
+	member := d.member(tag, now)
+	if now.Before(member.ejectedUntil) {
+		return false
+	}
+	member.requests++
+	member.failures++
+	if member.failures < d.failures && !d.exceedsFailureRate(member) {
+		return false
+	}
+	member.ejections++
+	ejectionTime := d.baseEjectionTime * time.Duration(member.ejections)
+	if ejectionTime > d.maxEjectionTime {
+		ejectionTime = d.maxEjectionTime
+	}
+	member.ejectedUntil = now.Add(ejectionTime)
+	member.windowStart = member.ejectedUntil
+	member.requests = 0
+	member.failures = 0
+	return true
+}
+
+// ReportResult records the outcome of a dial through tag and reports whether
+// the member got ejected by it. Dials aborted by the caller are not counted.
+func (d *outlierDetector) ReportResult(ctx context.Context, logger logger.ContextLogger, tag string, err error) bool {
+	if d == nil {
+		return false
+	}
+	if err == nil {
+		d.ReportSuccess(tag)
+		return false
+	}
+	if ctx.Err() != nil || E.IsCanceled(err) {
+		return false
+	}
+	if !d.ReportFailure(tag) {
+		return false
+	}
+	logger.WarnContext(ctx, "outbound ", tag, " ejected by outlier detection: ", err)
+	return true
+}
+
+func (d *outlierDetector) exceedsFailureRate(member *outlierMember) bool {
+	if d.failureRate == 0 || member.requests < d.minimumRequests {
+		return false
+	}
+	return uint64(member.failures)*100 >= uint64(d.failureRate)*uint64(member.requests)
+}
+
+func (d *outlierDetector) IsEjected(tag string) bool {
+	if d == nil {
+		return false
+	}
+	d.access.Lock()
+	defer d.access.Unlock()
+	member := d.members[tag]
+	return member != nil && time.Now().Before(member.ejectedUntil)
+}
+
+// Filter removes ejected members from outbounds. If every member is ejected,
+// outbounds is returned unchanged so that traffic is never black-holed by
+// passive detection alone.
+func (d *outlierDetector) Filter(outbounds []adapter.Outbound) []adapter.Outbound {
+	if d == nil {
+		return outbounds
+	}
+	d.access.Lock()
+	defer d.access.Unlock()
+	now := time.Now()
+	var filtered []adapter.Outbound
+	for i, detour := range outbounds {
+		member := d.members[detour.Tag()]
+		if member == nil || !now.Before(member.ejectedUntil) {
+			if filtered != nil {
+				filtered = append(filtered, detour)
+			}
+			continue
+		}
+		if filtered == nil {
+			filtered = make([]adapter.Outbound, i, len(outbounds))
+			copy(filtered, outbounds[:i])
+		}
+	}
+	if len(filtered) == 0 {
+		return outbounds
+	}
+	return filtered
+}
+
+func (d *outlierDetector) Status() map[string]adapter.OutlierStatus {
+	if d == nil {
+		return nil
+	}
+	d.access.Lock()
+	defer d.access.Unlock()
+	now := time.Now()
+	status := make(map[string]adapter.OutlierStatus, len(d.members))
+	for tag, member := range d.members {
+		memberStatus := adapter.OutlierStatus{
+			Ejected:   now.Before(member.ejectedUntil),
+			Ejections: member.ejections,
+		}
+		if memberStatus.Ejected {
+			memberStatus.EjectedUntil = member.ejectedUntil
+		} else {
+			memberStatus.Requests = member.requests
+			memberStatus.Failures = member.failures
+		}
+		status[tag] = memberStatus
+	}
+	return status
+}
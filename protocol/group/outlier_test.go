@@ -0,0 +1,127 @@
+package group
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json/badoption"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutlierConsecutiveFailures(t *testing.T) {
+	detector := newOutlierDetector(&option.OutlierDetectionOptions{
+		Failures:         3,
+		Interval:         badoption.Duration(time.Minute),
+		BaseEjectionTime: badoption.Duration(time.Minute),
+	})
+
+	assert.False(t, detector.ReportFailure("a"))
+	assert.False(t, detector.ReportFailure("a"))
+	assert.False(t, detector.IsEjected("a"))
+	assert.True(t, detector.ReportFailure("a"))
+	assert.True(t, detector.IsEjected("a"))
+	assert.False(t, detector.IsEjected("b"))
+
+	// Failures while ejected are ignored
+	assert.False(t, detector.ReportFailure("a"))
+
+	status := detector.Status()
+	require.Contains(t, status, "a")
+	assert.True(t, status["a"].Ejected)
+	assert.Equal(t, uint32(1), status["a"].Ejections)
+	assert.False(t, status["a"].EjectedUntil.IsZero())
+}
+
+func TestOutlierFailureRate(t *testing.T) {
+	detector := newOutlierDetector(&option.OutlierDetectionOptions{
+		Failures:        100,
+		Interval:        badoption.Duration(time.Minute),
+		FailureRate:     50,
+		MinimumRequests: 4,
+	})
+
+	detector.ReportSuccess("a")
+	assert.False(t, detector.ReportFailure("a"))
+	detector.ReportSuccess("a")
+	// 2 of 4 requests failed
+	assert.True(t, detector.ReportFailure("a"))
+	assert.True(t, detector.IsEjected("a"))
+}
+
+func TestOutlierWindowReset(t *testing.T) {
+	detector := newOutlierDetector(&option.OutlierDetectionOptions{
+		Failures: 2,
+		Interval: badoption.Duration(50 * time.Millisecond),
+	})
+
+	assert.False(t, detector.ReportFailure("a"))
+	time.Sleep(60 * time.Millisecond)
+	assert.False(t, detector.ReportFailure("a"), "failures from an expired window must not count")
+}
+
+func TestOutlierEjectionBackoff(t *testing.T) {
+	detector := newOutlierDetector(&option.OutlierDetectionOptions{
+		Failures:         1,
+		Interval:         badoption.Duration(time.Minute),
+		BaseEjectionTime: badoption.Duration(20 * time.Millisecond),
+		MaxEjectionTime:  badoption.Duration(30 * time.Millisecond),
+	})
+
+	start := time.Now()
+	require.True(t, detector.ReportFailure("a"))
+	firstUntil := detector.Status()["a"].EjectedUntil
+	assert.InDelta(t, 20*time.Millisecond, firstUntil.Sub(start), float64(10*time.Millisecond))
+
+	time.Sleep(25 * time.Millisecond)
+	assert.False(t, detector.IsEjected("a"))
+
+	start = time.Now()
+	require.True(t, detector.ReportFailure("a"))
+	secondUntil := detector.Status()["a"].EjectedUntil
+	// Second ejection would be 40ms but is capped at 30ms
+	assert.InDelta(t, 30*time.Millisecond, secondUntil.Sub(start), float64(10*time.Millisecond))
+}
+
+func TestOutlierFilter(t *testing.T) {
+	detector := newOutlierDetector(&option.OutlierDetectionOptions{Failures: 1})
+	candidates := newStrategyTestCandidates("a", "b", "c")
+
+	assert.Equal(t, candidates, detector.Filter(candidates))
+
+	detector.ReportFailure("b")
+	filtered := detector.Filter(candidates)
+	require.Len(t, filtered, 2)
+	assert.Equal(t, "a", filtered[0].Tag())
+	assert.Equal(t, "c", filtered[1].Tag())
+
+	// Everything ejected: keep serving from the full pool
+	detector.ReportFailure("a")
+	detector.ReportFailure("c")
+	assert.Equal(t, candidates, detector.Filter(candidates))
+}
+
+func TestOutlierReportResultIgnoresCanceled(t *testing.T) {
+	detector := newOutlierDetector(&option.OutlierDetectionOptions{Failures: 1})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.False(t, detector.ReportResult(ctx, &mockLogger{}, "a", E.New("dial failed")))
+	assert.False(t, detector.ReportResult(context.Background(), &mockLogger{}, "a", context.Canceled))
+	assert.False(t, detector.IsEjected("a"))
+
+	assert.True(t, detector.ReportResult(context.Background(), &mockLogger{}, "a", E.New("dial failed")))
+}
+
+func TestOutlierNilDetector(t *testing.T) {
+	var detector *outlierDetector
+	candidates := newStrategyTestCandidates("a")
+	assert.False(t, detector.ReportFailure("a"))
+	assert.False(t, detector.IsEjected("a"))
+	assert.Equal(t, candidates, detector.Filter(candidates))
+	assert.Nil(t, detector.Status())
+}
@@ -0,0 +1,168 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+)
+
+const (
+	retryOnAny                = "any"
+	retryOnTimeout            = "timeout"
+	retryOnConnectionRefused  = "connection_refused"
+	retryOnConnectionReset    = "connection_reset"
+	retryOnNetworkUnreachable = "network_unreachable"
+
+	defaultRetryMaxAttempts = 2
+)
+
+var errAttemptTimeout = E.New("dial attempt timed out")
+
+// dialRetry re-dials through other members when a dial fails. A nil
+// dialRetry performs exactly one attempt.
+type dialRetry struct {
+	maxAttempts    int
+	attemptTimeout time.Duration
+	retryOn        []string
+}
+
+func newDialRetry(options *option.GroupRetryOptions) (*dialRetry, error) {
+	if options == nil {
+		return nil, nil
+	}
+	retry := &dialRetry{
+		maxAttempts:    int(options.MaxAttempts),
+		attemptTimeout: time.Duration(options.AttemptTimeout),
+		retryOn:        options.RetryOn,
+	}
+	if retry.maxAttempts == 0 {
+		retry.maxAttempts = defaultRetryMaxAttempts
+	}
+	if len(retry.retryOn) == 0 {
+		retry.retryOn = []string{retryOnAny}
+	}
+	for _, retryOn := range retry.retryOn {
+		switch retryOn {
+		case retryOnAny, retryOnTimeout, retryOnConnectionRefused, retryOnConnectionReset, retryOnNetworkUnreachable:
+		default:
+			return nil, E.New("retry: unknown retry_on value: ", retryOn)
+		}
+	}
+	return retry, nil
+}
+
+// IsRetryable reports whether err, returned by an attempt under ctx, may be retried.
+func (r *dialRetry) IsRetryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	if errors.Is(err, errAttemptTimeout) {
+		return common.Contains(r.retryOn, retryOnAny) || common.Contains(r.retryOn, retryOnTimeout)
+	}
+	if E.IsCanceled(err) {
+		return false
+	}
+	for _, retryOn := range r.retryOn {
+		switch retryOn {
+		case retryOnAny:
+			return true
+		case retryOnTimeout:
+			if E.IsTimeout(err) {
+				return true
+			}
+		case retryOnConnectionRefused:
+			if errors.Is(err, syscall.ECONNREFUSED) {
+				return true
+			}
+		case retryOnConnectionReset:
+			if errors.Is(err, syscall.ECONNRESET) {
+				return true
+			}
+		case retryOnNetworkUnreachable:
+			if errors.Is(err, syscall.ENETUNREACH) || errors.Is(err, syscall.EHOSTUNREACH) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// attempt runs a single dial, enforcing attempt_timeout. Some outbounds keep
+// using the dial context for the lifetime of the connection, so the attempt
+// context is detached from ctx once the dial returns instead of being
+// canceled.
+func attempt[T any](ctx context.Context, timeout time.Duration, dial func(ctx context.Context) (T, error)) (T, error) {
+	if timeout == 0 {
+		return dial(ctx)
+	}
+	attemptCtx, cancel := context.WithCancelCause(context.WithoutCancel(ctx))
+	stopPropagation := context.AfterFunc(ctx, func() {
+		cancel(context.Cause(ctx))
+	})
+	timer := time.AfterFunc(timeout, func() {
+		cancel(errAttemptTimeout)
+	})
+	conn, err := dial(attemptCtx)
+	stopPropagation()
+	if !timer.Stop() {
+		// The attempt context is canceled even if the dial succeeded
+		if err == nil {
+			common.Close(conn)
+			var zero T
+			conn = zero
+			err = errAttemptTimeout
+		} else {
+			err = E.Cause(errAttemptTimeout, err)
+		}
+	}
+	if err != nil {
+		cancel(nil)
+	}
+	return conn, err
+}
+
+// dialWithRetry dials through selected, then through the members returned by
+// next until an attempt succeeds, the error is not retryable, max_attempts is
+// reached or no untried member is left. report is called after every attempt.
+func dialWithRetry[T any](
+	ctx context.Context,
+	r *dialRetry,
+	logger logger.ContextLogger,
+	selected adapter.Outbound,
+	next func(tried []string) adapter.Outbound,
+	dial func(ctx context.Context, detour adapter.Outbound) (T, error),
+	report func(detour adapter.Outbound, err error),
+) (T, adapter.Outbound, error) {
+	if r == nil {
+		conn, err := dial(ctx, selected)
+		report(selected, err)
+		return conn, selected, err
+	}
+	var tried []string
+	for {
+		conn, err := attempt(ctx, r.attemptTimeout, func(ctx context.Context) (T, error) {
+			return dial(ctx, selected)
+		})
+		report(selected, err)
+		if err == nil {
+			return conn, selected, nil
+		}
+		tried = append(tried, selected.Tag())
+		if len(tried) >= r.maxAttempts || !r.IsRetryable(ctx, err) {
+			return conn, selected, err
+		}
+		nextOutbound := next(tried)
+		if nextOutbound == nil {
+			return conn, selected, err
+		}
+		logger.DebugContext(ctx, "dial through ", selected.Tag(), " failed, retrying with ", nextOutbound.Tag(), ": ", err)
+		selected = nextOutbound
+	}
+}
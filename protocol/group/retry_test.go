@@ -0,0 +1,222 @@
+package group
+
+import (
+	"context"
+	"io"
+	"net"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json/badoption"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Mock outbound with a configurable dial result
+type dialResultOutbound struct {
+	mockOutbound
+	err   error
+	delay time.Duration
+	dials int
+}
+
+func (m *dialResultOutbound) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	m.dials++
+	if m.delay > 0 {
+		select {
+		case <-time.After(m.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if m.err != nil {
+		return nil, m.err
+	}
+	conn, _ := net.Pipe()
+	return conn, nil
+}
+
+func newDialResultOutbound(tag string, err error) *dialResultOutbound {
+	return &dialResultOutbound{
+		mockOutbound: mockOutbound{tag: tag, network: []string{"tcp", "udp"}},
+		err:          err,
+	}
+}
+
+func TestRetryOptions(t *testing.T) {
+	retry, err := newDialRetry(nil)
+	require.NoError(t, err)
+	assert.Nil(t, retry)
+
+	retry, err = newDialRetry(&option.GroupRetryOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, defaultRetryMaxAttempts, retry.maxAttempts)
+	assert.Equal(t, []string{retryOnAny}, retry.retryOn)
+
+	_, err = newDialRetry(&option.GroupRetryOptions{RetryOn: []string{"bad"}})
+	assert.Error(t, err)
+}
+
+func TestRetryIsRetryable(t *testing.T) {
+	ctx := context.Background()
+	retry, err := newDialRetry(&option.GroupRetryOptions{
+		RetryOn: []string{retryOnConnectionRefused, retryOnTimeout},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, retry.IsRetryable(ctx, E.Cause(syscall.ECONNREFUSED, "dial")))
+	assert.True(t, retry.IsRetryable(ctx, E.Cause(errAttemptTimeout, "dial")))
+	assert.False(t, retry.IsRetryable(ctx, E.Cause(syscall.ECONNRESET, "dial")))
+	assert.False(t, retry.IsRetryable(ctx, context.Canceled))
+
+	canceledCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	assert.False(t, retry.IsRetryable(canceledCtx, E.Cause(syscall.ECONNREFUSED, "dial")))
+}
+
+func TestLoadBalanceRetryNextCandidate(t *testing.T) {
+	p1 := newDialResultOutbound("p1", E.New("broken"))
+	p2 := newDialResultOutbound("p2", E.New("broken"))
+	p3 := newDialResultOutbound("p3", nil)
+
+	retry, err := newDialRetry(&option.GroupRetryOptions{MaxAttempts: 3})
+	require.NoError(t, err)
+
+	lb := &LoadBalance{
+		logger:      &mockLogger{},
+		primaryTags: []string{"p1", "p2", "p3"},
+		strategy:    strategyRoundRobin,
+		retry:       retry,
+		outbound: &mockOutboundManager{
+			outbounds: map[string]adapter.Outbound{"p1": p1, "p2": p2, "p3": p3},
+		},
+	}
+	lb.tierState.Store(&tierStateSnapshot{activeTier: "primary"})
+	lb.candidateState.Store(&candidateSnapshot{
+		primaryCandidates: []adapter.Outbound{p1, p2, p3},
+		activeTier:        "primary",
+	})
+
+	conn, err := lb.DialContext(context.Background(), "tcp", M.ParseSocksaddr("example.com:443"))
+	require.NoError(t, err)
+	conn.Close()
+	assert.Equal(t, 1, p1.dials)
+	assert.Equal(t, 1, p2.dials)
+	assert.Equal(t, 1, p3.dials)
+}
+
+func TestLoadBalanceRetryMaxAttempts(t *testing.T) {
+	p1 := newDialResultOutbound("p1", E.New("broken"))
+	p2 := newDialResultOutbound("p2", E.New("broken"))
+	p3 := newDialResultOutbound("p3", nil)
+
+	retry, err := newDialRetry(&option.GroupRetryOptions{MaxAttempts: 2})
+	require.NoError(t, err)
+
+	lb := &LoadBalance{
+		logger:      &mockLogger{},
+		primaryTags: []string{"p1", "p2", "p3"},
+		strategy:    strategyRoundRobin,
+		retry:       retry,
+		outbound: &mockOutboundManager{
+			outbounds: map[string]adapter.Outbound{"p1": p1, "p2": p2, "p3": p3},
+		},
+	}
+	lb.candidateState.Store(&candidateSnapshot{
+		primaryCandidates: []adapter.Outbound{p1, p2, p3},
+		activeTier:        "primary",
+	})
+
+	_, err = lb.DialContext(context.Background(), "tcp", M.ParseSocksaddr("example.com:443"))
+	assert.Error(t, err)
+	assert.Equal(t, 0, p3.dials)
+}
+
+func TestRetryAttemptTimeout(t *testing.T) {
+	slow := newDialResultOutbound("slow", nil)
+	slow.delay = time.Second
+	fast := newDialResultOutbound("fast", nil)
+
+	retry, err := newDialRetry(&option.GroupRetryOptions{
+		AttemptTimeout: badoption.Duration(20 * time.Millisecond),
+		RetryOn:        []string{retryOnTimeout},
+	})
+	require.NoError(t, err)
+
+	start := time.Now()
+	conn, selected, err := dialWithRetry(context.Background(), retry, &mockLogger{}, adapter.Outbound(slow),
+		func(tried []string) adapter.Outbound {
+			return fast
+		},
+		func(ctx context.Context, detour adapter.Outbound) (net.Conn, error) {
+			return detour.DialContext(ctx, "tcp", M.ParseSocksaddr("example.com:443"))
+		},
+		func(detour adapter.Outbound, err error) {},
+	)
+	require.NoError(t, err)
+	conn.Close()
+	assert.Equal(t, "fast", selected.Tag())
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestRetryNotRetryable(t *testing.T) {
+	p1 := newDialResultOutbound("p1", E.Cause(syscall.ECONNRESET, "dial"))
+	p2 := newDialResultOutbound("p2", nil)
+
+	retry, err := newDialRetry(&option.GroupRetryOptions{RetryOn: []string{retryOnConnectionRefused}})
+	require.NoError(t, err)
+
+	var reported []string
+	_, _, err = dialWithRetry(context.Background(), retry, &mockLogger{}, adapter.Outbound(p1),
+		func(tried []string) adapter.Outbound {
+			return p2
+		},
+		func(ctx context.Context, detour adapter.Outbound) (net.Conn, error) {
+			return detour.DialContext(ctx, "tcp", M.ParseSocksaddr("example.com:443"))
+		},
+		func(detour adapter.Outbound, err error) {
+			reported = append(reported, detour.Tag())
+		},
+	)
+	assert.Error(t, err)
+	assert.Equal(t, 0, p2.dials)
+	assert.Equal(t, []string{"p1"}, reported)
+}
+
+func TestRetryAttemptDetach(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var dialCtx context.Context
+	conn, err := attempt(ctx, 20*time.Millisecond, func(ctx context.Context) (net.Conn, error) {
+		dialCtx = ctx
+		conn, _ := net.Pipe()
+		return conn, nil
+	})
+	require.NoError(t, err)
+	defer conn.Close()
+	// Neither the attempt timeout nor the dial context going away cancel the
+	// context of a successful dial
+	cancel()
+	time.Sleep(40 * time.Millisecond)
+	assert.NoError(t, dialCtx.Err())
+}
+
+func TestRetryAttemptTimeoutAfterDial(t *testing.T) {
+	var pipeConn net.Conn
+	_, err := attempt(context.Background(), 10*time.Millisecond, func(ctx context.Context) (net.Conn, error) {
+		// The dial succeeds after the attempt context was canceled
+		<-ctx.Done()
+		var peerConn net.Conn
+		pipeConn, peerConn = net.Pipe()
+		go peerConn.Read(make([]byte, 1))
+		return pipeConn, nil
+	})
+	require.ErrorIs(t, err, errAttemptTimeout)
+	_, err = pipeConn.Write([]byte("x"))
+	assert.ErrorIs(t, err, io.ErrClosedPipe)
+}
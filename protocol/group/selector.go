@@ -24,6 +24,7 @@ func RegisterSelector(registry *outbound.Registry) {
 
 var (
 	_ adapter.OutboundGroup             = (*Selector)(nil)
+	_ adapter.OutlierDetectionGroup     = (*Selector)(nil)
 	_ adapter.ConnectionHandlerEx       = (*Selector)(nil)
 	_ adapter.PacketConnectionHandlerEx = (*Selector)(nil)
 )
@@ -40,6 +41,7 @@ type Selector struct {
 	selected                     common.TypedValue[adapter.Outbound]
 	interruptGroup               *interrupt.Group
 	interruptExternalConnections bool
+	outlier                      *outlierDetector
 }
 
 func NewSelector(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.SelectorOutboundOptions) (adapter.Outbound, error) {
@@ -54,6 +56,7 @@ func NewSelector(ctx context.Context, router adapter.Router, logger log.ContextL
 		outbounds:                    make(map[string]adapter.Outbound),
 		interruptGroup:               interrupt.NewGroup(),
 		interruptExternalConnections: options.InterruptExistConnections,
+		outlier:                      newOutlierDetector(options.OutlierDetection),
 	}
 	if len(outbound.tags) == 0 {
 		return nil, E.New("missing tags")
@@ -138,8 +141,31 @@ func (s *Selector) SelectOutbound(tag string) bool {
 	return true
 }
 
+func (s *Selector) OutlierStatus() map[string]adapter.OutlierStatus {
+	return s.outlier.Status()
+}
+
+// dialOutbound returns the selected outbound, or while it is ejected by
+// outlier detection, the first healthy member in declared order.
+func (s *Selector) dialOutbound(network string) adapter.Outbound {
+	selected := s.selected.Load()
+	if s.outlier == nil || !s.outlier.IsEjected(selected.Tag()) {
+		return selected
+	}
+	for _, tag := range s.tags {
+		detour := s.outbounds[tag]
+		if detour == nil || !common.Contains(detour.Network(), network) || s.outlier.IsEjected(tag) {
+			continue
+		}
+		return detour
+	}
+	return selected
+}
+
 func (s *Selector) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
-	conn, err := s.selected.Load().DialContext(ctx, network, destination)
+	selected := s.dialOutbound(N.NetworkName(network))
+	conn, err := selected.DialContext(ctx, network, destination)
+	s.outlier.ReportResult(ctx, s.logger, selected.Tag(), err)
 	if err != nil {
 		return nil, err
 	}
@@ -147,7 +173,9 @@ func (s *Selector) DialContext(ctx context.Context, network string, destination
 }
 
 func (s *Selector) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
-	conn, err := s.selected.Load().ListenPacket(ctx, destination)
+	selected := s.dialOutbound(N.NetworkUDP)
+	conn, err := selected.ListenPacket(ctx, destination)
+	s.outlier.ReportResult(ctx, s.logger, selected.Tag(), err)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,94 @@
+package group
+
+import (
+	"context"
+	"sync"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// udpHealth tracks the results of UDP probes separately from the TCP/HTTP
+// URL test history, since a member passing TCP checks may still drop UDP.
+// A nil udpHealth considers every member healthy.
+type udpHealth struct {
+	prober  urltest.Prober
+	access  sync.RWMutex
+	checked bool
+	delay   map[string]uint16
+}
+
+func newUDPHealth(options *option.HealthCheckProbeOptions) (*udpHealth, error) {
+	if options == nil {
+		return nil, nil
+	}
+	prober, err := urltest.NewProber(options, "")
+	if err != nil {
+		return nil, E.Cause(err, "udp_probe")
+	}
+	if prober.Network() != N.NetworkUDP {
+		return nil, E.New("udp_probe: type must be one of 'dns', 'udp_echo' or 'stun'")
+	}
+	return &udpHealth{
+		prober: prober,
+		delay:  make(map[string]uint16),
+	}, nil
+}
+
+// Probe sends a UDP probe through detour and records the result.
+func (h *udpHealth) Probe(ctx context.Context, detour adapter.Outbound) (uint16, error) {
+	tag := RealTag(detour)
+	delay, err := h.prober.Probe(ctx, detour)
+	h.access.Lock()
+	defer h.access.Unlock()
+	if err != nil {
+		delete(h.delay, tag)
+	} else {
+		h.delay[tag] = delay
+	}
+	return delay, err
+}
+
+// SetChecked marks that a full round of probes has completed. Until then,
+// Filter does not exclude any member.
+func (h *udpHealth) SetChecked() {
+	if h == nil {
+		return
+	}
+	h.access.Lock()
+	defer h.access.Unlock()
+	h.checked = true
+}
+
+// Load returns the last UDP probe delay of tag.
+func (h *udpHealth) Load(tag string) (uint16, bool) {
+	if h == nil {
+		return 0, false
+	}
+	h.access.RLock()
+	defer h.access.RUnlock()
+	delay, loaded := h.delay[tag]
+	return delay, loaded
+}
+
+// Filter returns the UDP-healthy candidates.
+func (h *udpHealth) Filter(candidates []adapter.Outbound) []adapter.Outbound {
+	if h == nil {
+		return candidates
+	}
+	h.access.RLock()
+	defer h.access.RUnlock()
+	if !h.checked {
+		return candidates
+	}
+	var healthy []adapter.Outbound
+	for _, candidate := range candidates {
+		if _, loaded := h.delay[RealTag(candidate)]; loaded {
+			healthy = append(healthy, candidate)
+		}
+	}
+	return healthy
+}
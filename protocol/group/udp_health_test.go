@@ -0,0 +1,156 @@
+package group
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/interrupt"
+	"github.com/sagernet/sing-box/common/urltest"
+	"github.com/sagernet/sing-box/option"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestUDPHealth(delay map[string]uint16) *udpHealth {
+	return &udpHealth{checked: true, delay: delay}
+}
+
+func TestUDPHealthOptions(t *testing.T) {
+	health, err := newUDPHealth(nil)
+	require.NoError(t, err)
+	assert.Nil(t, health)
+
+	_, err = newUDPHealth(&option.HealthCheckProbeOptions{Type: urltest.ProbeTypeHTTP})
+	assert.Error(t, err, "TCP probes must be rejected")
+
+	health, err = newUDPHealth(&option.HealthCheckProbeOptions{Type: urltest.ProbeTypeDNS})
+	require.NoError(t, err)
+	assert.NotNil(t, health)
+}
+
+func TestUDPHealthFilter(t *testing.T) {
+	candidates := newStrategyTestCandidates("a", "b", "c")
+
+	var health *udpHealth
+	assert.Equal(t, candidates, health.Filter(candidates))
+
+	// Before the first round of probes, every member is considered healthy
+	health = &udpHealth{delay: make(map[string]uint16)}
+	assert.Equal(t, candidates, health.Filter(candidates))
+
+	health.delay["b"] = 10
+	health.SetChecked()
+	filtered := health.Filter(candidates)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, "b", filtered[0].Tag())
+}
+
+func TestLoadBalanceUDPHealthyPool(t *testing.T) {
+	primary := newStrategyTestCandidates("p1", "p2")
+	backup := newStrategyTestCandidates("b1")
+	lb := &LoadBalance{
+		logger:    &mockLogger{},
+		strategy:  strategyRoundRobin,
+		udpHealth: newTestUDPHealth(map[string]uint16{"p2": 10, "b1": 10}),
+	}
+	lb.candidateState.Store(&candidateSnapshot{
+		primaryCandidates: primary,
+		backupCandidates:  backup,
+		activeTier:        "primary",
+	})
+
+	for i := 0; i < 4; i++ {
+		selected, err := lb.selectOutbound("udp", &adapter.InboundContext{})
+		require.NoError(t, err)
+		assert.Equal(t, "p2", selected.Tag())
+	}
+
+	// TCP selection is not affected
+	seen := make(map[string]bool)
+	for i := 0; i < 4; i++ {
+		selected, err := lb.selectOutbound("tcp", &adapter.InboundContext{})
+		require.NoError(t, err)
+		seen[selected.Tag()] = true
+	}
+	assert.True(t, seen["p1"])
+
+	// No UDP-healthy primary: use UDP-healthy backup members
+	lb.udpHealth = newTestUDPHealth(map[string]uint16{"b1": 10})
+	selected, err := lb.selectOutbound("udp", &adapter.InboundContext{})
+	require.NoError(t, err)
+	assert.Equal(t, "b1", selected.Tag())
+
+	// No UDP-healthy member at all
+	lb.udpHealth = newTestUDPHealth(map[string]uint16{})
+	_, err = lb.selectOutbound("udp", &adapter.InboundContext{})
+	assert.Error(t, err)
+}
+
+func TestURLTestGroupUDPSelect(t *testing.T) {
+	outbounds := newStrategyTestCandidates("a", "b", "c")
+	history := urltest.NewHistoryStorage()
+	now := time.Now()
+	history.StoreURLTestHistory("a", &adapter.URLTestHistory{Time: now, Delay: 10})
+	history.StoreURLTestHistory("b", &adapter.URLTestHistory{Time: now, Delay: 200})
+	history.StoreURLTestHistory("c", &adapter.URLTestHistory{Time: now, Delay: 300})
+	group := &URLTestGroup{
+		outbounds: outbounds,
+		tolerance: 50,
+		history:   history,
+		udpHealth: newTestUDPHealth(map[string]uint16{"b": 100, "c": 30}),
+	}
+
+	selected, _ := group.Select("tcp")
+	require.NotNil(t, selected)
+	assert.Equal(t, "a", selected.Tag())
+
+	selected, _ = group.Select("udp")
+	require.NotNil(t, selected)
+	assert.Equal(t, "c", selected.Tag(), "UDP selection must use UDP probe delays")
+
+	retry := group.selectRetry("udp", []string{"c"})
+	require.NotNil(t, retry)
+	assert.Equal(t, "b", retry.Tag())
+
+	group.udpHealth = newTestUDPHealth(map[string]uint16{})
+	selected, _ = group.Select("udp")
+	assert.Nil(t, selected)
+}
+
+func TestURLTestGroupUDPUnhealthy(t *testing.T) {
+	outbounds := newStrategyTestCandidates("a", "b")
+	history := urltest.NewHistoryStorage()
+	history.StoreURLTestHistory("a", &adapter.URLTestHistory{Time: time.Now(), Delay: 10})
+	history.StoreURLTestHistory("b", &adapter.URLTestHistory{Time: time.Now(), Delay: 20})
+	group := &URLTestGroup{
+		outbounds:      outbounds,
+		tolerance:      50,
+		history:        history,
+		udpHealth:      newTestUDPHealth(map[string]uint16{"b": 10}),
+		interruptGroup: interrupt.NewGroup(),
+	}
+	group.performUpdateCheck()
+	require.NotNil(t, group.selectedOutbound("udp"))
+	assert.Equal(t, "b", group.selectedOutbound("udp").Tag())
+
+	// The stale UDP selection is dropped once no member is UDP-healthy
+	group.udpHealth = newTestUDPHealth(map[string]uint16{})
+	group.performUpdateCheck()
+	assert.Nil(t, group.selectedOutbound("udp"))
+	require.NotNil(t, group.selectedOutbound("tcp"))
+	assert.Equal(t, "a", group.selectedOutbound("tcp").Tag())
+
+	outbound := &URLTest{group: group}
+	_, err := outbound.ListenPacket(context.Background(), M.ParseSocksaddr("1.1.1.1:53"))
+	assert.Error(t, err)
+
+	// A member recovers
+	group.udpHealth = newTestUDPHealth(map[string]uint16{"a": 10})
+	group.performUpdateCheck()
+	require.NotNil(t, group.selectedOutbound("udp"))
+	assert.Equal(t, "a", group.selectedOutbound("udp").Tag())
+}
@@ -28,7 +28,10 @@ func RegisterURLTest(registry *outbound.Registry) {
 	outbound.Register[option.URLTestOutboundOptions](registry, C.TypeURLTest, NewURLTest)
 }
 
-var _ adapter.OutboundGroup = (*URLTest)(nil)
+var (
+	_ adapter.OutboundGroup         = (*URLTest)(nil)
+	_ adapter.OutlierDetectionGroup = (*URLTest)(nil)
+)
 
 type URLTest struct {
 	outbound.Adapter
@@ -38,32 +41,54 @@ type URLTest struct {
 	connection                   adapter.ConnectionManager
 	logger                       log.ContextLogger
 	tags                         []string
-	link                         string
+	prober                       urltest.Prober
+	udpHealth                    *udpHealth
 	interval                     time.Duration
 	tolerance                    uint16
 	idleTimeout                  time.Duration
 	group                        *URLTestGroup
 	interruptExternalConnections bool
+	outlierDetection             *option.OutlierDetectionOptions
+	retry                        *dialRetry
+	fallback                     *fallbackSelector
 }
 
 func NewURLTest(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.URLTestOutboundOptions) (adapter.Outbound, error) {
+	return newURLTest(ctx, router, logger, C.TypeURLTest, tag, options)
+}
+
+func newURLTest(ctx context.Context, router adapter.Router, logger log.ContextLogger, outboundType string, tag string, options option.URLTestOutboundOptions) (*URLTest, error) {
 	outbound := &URLTest{
-		Adapter:                      outbound.NewAdapter(C.TypeURLTest, tag, []string{N.NetworkTCP, N.NetworkUDP}, options.Outbounds),
+		Adapter:                      outbound.NewAdapter(outboundType, tag, []string{N.NetworkTCP, N.NetworkUDP}, options.Outbounds),
 		ctx:                          ctx,
 		router:                       router,
 		outbound:                     service.FromContext[adapter.OutboundManager](ctx),
 		connection:                   service.FromContext[adapter.ConnectionManager](ctx),
 		logger:                       logger,
 		tags:                         options.Outbounds,
-		link:                         options.URL,
 		interval:                     time.Duration(options.Interval),
 		tolerance:                    options.Tolerance,
 		idleTimeout:                  time.Duration(options.IdleTimeout),
 		interruptExternalConnections: options.InterruptExistConnections,
+		outlierDetection:             options.OutlierDetection,
 	}
 	if len(outbound.tags) == 0 {
 		return nil, E.New("missing tags")
 	}
+	prober, err := urltest.NewProber(options.Probe, options.URL)
+	if err != nil {
+		return nil, err
+	}
+	outbound.prober = prober
+	outbound.udpHealth, err = newUDPHealth(options.UDPProbe)
+	if err != nil {
+		return nil, err
+	}
+	retry, err := newDialRetry(options.Retry)
+	if err != nil {
+		return nil, err
+	}
+	outbound.retry = retry
 	return outbound, nil
 }
 
@@ -76,10 +101,11 @@ func (s *URLTest) Start() error {
 		}
 		outbounds = append(outbounds, detour)
 	}
-	group, err := NewURLTestGroup(s.ctx, s.outbound, s.logger, outbounds, s.link, s.interval, s.tolerance, s.idleTimeout, s.interruptExternalConnections)
+	group, err := NewURLTestGroup(s.ctx, s.outbound, s.logger, outbounds, s.prober, s.udpHealth, s.interval, s.tolerance, s.idleTimeout, s.interruptExternalConnections, s.outlierDetection)
 	if err != nil {
 		return err
 	}
+	group.fallback = s.fallback
 	s.group = group
 	return nil
 }
@@ -96,10 +122,10 @@ func (s *URLTest) Close() error {
 }
 
 func (s *URLTest) Now() string {
-	if s.group.selectedOutboundTCP != nil {
-		return s.group.selectedOutboundTCP.Tag()
-	} else if s.group.selectedOutboundUDP != nil {
-		return s.group.selectedOutboundUDP.Tag()
+	if selected := s.group.selectedOutbound(N.NetworkTCP); selected != nil {
+		return selected.Tag()
+	} else if selected = s.group.selectedOutbound(N.NetworkUDP); selected != nil {
+		return selected.Tag()
 	}
 	return ""
 }
@@ -112,54 +138,84 @@ func (s *URLTest) URLTest(ctx context.Context) (map[string]uint16, error) {
 	return s.group.URLTest(ctx)
 }
 
+func (s *URLTest) OutlierStatus() map[string]adapter.OutlierStatus {
+	return s.group.outlier.Status()
+}
+
 func (s *URLTest) CheckOutbounds() {
 	s.group.CheckOutbounds(true)
 }
 
 func (s *URLTest) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
 	s.group.Touch()
-	var outbound adapter.Outbound
-	switch N.NetworkName(network) {
-	case N.NetworkTCP:
-		outbound = s.group.selectedOutboundTCP
-	case N.NetworkUDP:
-		outbound = s.group.selectedOutboundUDP
+	network = N.NetworkName(network)
+	switch network {
+	case N.NetworkTCP, N.NetworkUDP:
 	default:
 		return nil, E.Extend(N.ErrUnknownNetwork, network)
 	}
-	if outbound == nil {
+	outbound := s.group.selectedOutbound(network)
+	if outbound == nil || s.group.outlier.IsEjected(outbound.Tag()) {
 		outbound, _ = s.group.Select(network)
 	}
 	if outbound == nil {
 		return nil, E.New("missing supported outbound")
 	}
-	conn, err := outbound.DialContext(ctx, network, destination)
+	conn, _, err := dialWithRetry(ctx, s.retry, s.logger, outbound,
+		func(tried []string) adapter.Outbound {
+			return s.group.selectRetry(network, tried)
+		},
+		func(ctx context.Context, detour adapter.Outbound) (net.Conn, error) {
+			return detour.DialContext(ctx, network, destination)
+		},
+		func(detour adapter.Outbound, err error) {
+			s.reportResult(ctx, detour, err)
+		},
+	)
 	if err == nil {
 		return s.group.interruptGroup.NewConn(conn, interrupt.IsExternalConnectionFromContext(ctx)), nil
 	}
 	s.logger.ErrorContext(ctx, err)
-	s.group.history.DeleteURLTestHistory(outbound.Tag())
 	return nil, err
 }
 
 func (s *URLTest) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
 	s.group.Touch()
-	outbound := s.group.selectedOutboundUDP
-	if outbound == nil {
+	outbound := s.group.selectedOutbound(N.NetworkUDP)
+	if outbound == nil || s.group.outlier.IsEjected(outbound.Tag()) {
 		outbound, _ = s.group.Select(N.NetworkUDP)
 	}
 	if outbound == nil {
 		return nil, E.New("missing supported outbound")
 	}
-	conn, err := outbound.ListenPacket(ctx, destination)
+	conn, _, err := dialWithRetry(ctx, s.retry, s.logger, outbound,
+		func(tried []string) adapter.Outbound {
+			return s.group.selectRetry(N.NetworkUDP, tried)
+		},
+		func(ctx context.Context, detour adapter.Outbound) (net.PacketConn, error) {
+			return detour.ListenPacket(ctx, destination)
+		},
+		func(detour adapter.Outbound, err error) {
+			s.reportResult(ctx, detour, err)
+		},
+	)
 	if err == nil {
 		return s.group.interruptGroup.NewPacketConn(conn, interrupt.IsExternalConnectionFromContext(ctx)), nil
 	}
 	s.logger.ErrorContext(ctx, err)
-	s.group.history.DeleteURLTestHistory(outbound.Tag())
 	return nil, err
 }
 
+func (s *URLTest) reportResult(ctx context.Context, detour adapter.Outbound, err error) {
+	if err != nil {
+		s.group.history.DeleteURLTestHistory(detour.Tag())
+	}
+	if s.group.outlier.ReportResult(ctx, s.logger, detour.Tag(), err) || (err != nil && s.fallback != nil) {
+		// Fallback groups move down the list as soon as the selected member fails
+		s.group.performUpdateCheck()
+	}
+}
+
 func (s *URLTest) NewConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
 	ctx = interrupt.ContextWithIsExternalConnection(ctx)
 	s.connection.NewConnection(ctx, s, conn, metadata, onClose)
@@ -178,16 +234,21 @@ type URLTestGroup struct {
 	pauseCallback                *list.Element[pause.Callback]
 	logger                       log.Logger
 	outbounds                    []adapter.Outbound
-	link                         string
+	prober                       urltest.Prober
+	udpHealth                    *udpHealth
 	interval                     time.Duration
 	tolerance                    uint16
 	idleTimeout                  time.Duration
 	history                      adapter.URLTestHistoryStorage
 	checking                     atomic.Bool
+	updateAccess                 sync.Mutex
+	selectedAccess               sync.RWMutex
 	selectedOutboundTCP          adapter.Outbound
 	selectedOutboundUDP          adapter.Outbound
 	interruptGroup               *interrupt.Group
 	interruptExternalConnections bool
+	outlier                      *outlierDetector
+	fallback                     *fallbackSelector
 	access                       sync.Mutex
 	ticker                       *time.Ticker
 	close                        chan struct{}
@@ -195,7 +256,7 @@ type URLTestGroup struct {
 	lastActive                   common.TypedValue[time.Time]
 }
 
-func NewURLTestGroup(ctx context.Context, outboundManager adapter.OutboundManager, logger log.Logger, outbounds []adapter.Outbound, link string, interval time.Duration, tolerance uint16, idleTimeout time.Duration, interruptExternalConnections bool) (*URLTestGroup, error) {
+func NewURLTestGroup(ctx context.Context, outboundManager adapter.OutboundManager, logger log.Logger, outbounds []adapter.Outbound, prober urltest.Prober, udpHealth *udpHealth, interval time.Duration, tolerance uint16, idleTimeout time.Duration, interruptExternalConnections bool, outlierDetection *option.OutlierDetectionOptions) (*URLTestGroup, error) {
 	if interval == 0 {
 		interval = C.DefaultURLTestInterval
 	}
@@ -221,7 +282,8 @@ func NewURLTestGroup(ctx context.Context, outboundManager adapter.OutboundManage
 		outbound:                     outboundManager,
 		logger:                       logger,
 		outbounds:                    outbounds,
-		link:                         link,
+		prober:                       prober,
+		udpHealth:                    udpHealth,
 		interval:                     interval,
 		tolerance:                    tolerance,
 		idleTimeout:                  idleTimeout,
@@ -230,6 +292,7 @@ func NewURLTestGroup(ctx context.Context, outboundManager adapter.OutboundManage
 		pause:                        service.FromContext[pause.Manager](ctx),
 		interruptGroup:               interrupt.NewGroup(),
 		interruptExternalConnections: interruptExternalConnections,
+		outlier:                      newOutlierDetector(outlierDetection),
 	}, nil
 }
 
@@ -269,49 +332,104 @@ func (g *URLTestGroup) Close() error {
 }
 
 func (g *URLTestGroup) Select(network string) (adapter.Outbound, bool) {
+	if g.fallback != nil {
+		return g.fallback.Select(g, network)
+	}
 	var minDelay uint16
 	var minOutbound adapter.Outbound
-	switch network {
-	case N.NetworkTCP:
-		if g.selectedOutboundTCP != nil {
-			if history := g.history.LoadURLTestHistory(RealTag(g.selectedOutboundTCP)); history != nil {
-				minOutbound = g.selectedOutboundTCP
-				minDelay = history.Delay
-			}
-		}
-	case N.NetworkUDP:
-		if g.selectedOutboundUDP != nil {
-			if history := g.history.LoadURLTestHistory(RealTag(g.selectedOutboundUDP)); history != nil {
-				minOutbound = g.selectedOutboundUDP
-				minDelay = history.Delay
-			}
+	if selected := g.selectedOutbound(network); selected != nil && !g.outlier.IsEjected(selected.Tag()) {
+		if delay, loaded := g.loadDelay(network, selected); loaded {
+			minOutbound = selected
+			minDelay = delay
 		}
 	}
 	for _, detour := range g.outbounds {
-		if !common.Contains(detour.Network(), network) {
+		if !common.Contains(detour.Network(), network) || g.outlier.IsEjected(detour.Tag()) {
 			continue
 		}
-		history := g.history.LoadURLTestHistory(RealTag(detour))
-		if history == nil {
+		delay, loaded := g.loadDelay(network, detour)
+		if !loaded {
 			continue
 		}
-		if minDelay == 0 || minDelay > history.Delay+g.tolerance {
-			minDelay = history.Delay
+		if minDelay == 0 || minDelay > delay+g.tolerance {
+			minDelay = delay
 			minOutbound = detour
 		}
 	}
 	if minOutbound == nil {
-		for _, detour := range g.outbounds {
-			if !common.Contains(detour.Network(), network) {
-				continue
-			}
-			return detour, false
+		candidates := g.outlier.Filter(common.Filter(g.outbounds, func(it adapter.Outbound) bool {
+			return common.Contains(it.Network(), network)
+		}))
+		if network == N.NetworkUDP {
+			candidates = g.udpHealth.Filter(candidates)
+		}
+		if len(candidates) > 0 {
+			return candidates[0], false
 		}
 		return nil, false
 	}
 	return minOutbound, true
 }
 
+// selectedOutbound returns the outbound currently selected for network
+func (g *URLTestGroup) selectedOutbound(network string) adapter.Outbound {
+	g.selectedAccess.RLock()
+	defer g.selectedAccess.RUnlock()
+	switch network {
+	case N.NetworkTCP:
+		return g.selectedOutboundTCP
+	case N.NetworkUDP:
+		return g.selectedOutboundUDP
+	default:
+		return nil
+	}
+}
+
+// loadDelay returns the measured delay of detour for network. UDP delays come
+// from UDP probes when UDP health checking is enabled.
+func (g *URLTestGroup) loadDelay(network string, detour adapter.Outbound) (uint16, bool) {
+	if network == N.NetworkUDP && g.udpHealth != nil {
+		return g.udpHealth.Load(RealTag(detour))
+	}
+	history := g.history.LoadURLTestHistory(RealTag(detour))
+	if history == nil {
+		return 0, false
+	}
+	return history.Delay, true
+}
+
+// selectRetry returns the lowest-latency untried outbound for a retry,
+// or the first untried one if none of them has been tested yet.
+func (g *URLTestGroup) selectRetry(network string, tried []string) adapter.Outbound {
+	candidates := g.outlier.Filter(common.Filter(g.outbounds, func(it adapter.Outbound) bool {
+		return common.Contains(it.Network(), network) && !common.Contains(tried, it.Tag())
+	}))
+	if network == N.NetworkUDP {
+		candidates = g.udpHealth.Filter(candidates)
+	}
+	if g.fallback != nil {
+		return g.fallback.selectRetry(g, network, candidates)
+	}
+	var (
+		minDelay    uint16
+		minOutbound adapter.Outbound
+	)
+	for _, detour := range candidates {
+		delay, loaded := g.loadDelay(network, detour)
+		if !loaded {
+			continue
+		}
+		if minOutbound == nil || delay < minDelay {
+			minDelay = delay
+			minOutbound = detour
+		}
+	}
+	if minOutbound == nil && len(candidates) > 0 {
+		minOutbound = candidates[0]
+	}
+	return minOutbound
+}
+
 func (g *URLTestGroup) loopCheck() {
 	if time.Since(g.lastActive.Load()) > g.interval {
 		g.lastActive.Store(time.Now())
@@ -371,7 +489,7 @@ func (g *URLTestGroup) urlTest(ctx context.Context, force bool) (map[string]uint
 		b.Go(realTag, func() (any, error) {
 			testCtx, cancel := context.WithTimeout(g.ctx, C.TCPTimeout)
 			defer cancel()
-			t, err := urltest.URLTest(testCtx, g.link, p)
+			t, err := g.prober.Probe(testCtx, p)
 			if err != nil {
 				g.logger.Debug("outbound ", tag, " unavailable: ", err)
 				g.history.DeleteURLTestHistory(realTag)
@@ -385,29 +503,68 @@ func (g *URLTestGroup) urlTest(ctx context.Context, force bool) (map[string]uint
 				result[tag] = t
 				resultAccess.Unlock()
 			}
+			if g.udpHealth != nil && common.Contains(p.Network(), N.NetworkUDP) {
+				udpCtx, udpCancel := context.WithTimeout(g.ctx, C.TCPTimeout)
+				t, err = g.udpHealth.Probe(udpCtx, p)
+				udpCancel()
+				if err != nil {
+					g.logger.Debug("outbound ", tag, " UDP unavailable: ", err)
+				} else {
+					g.logger.Debug("outbound ", tag, " UDP available: ", t, "ms")
+				}
+			}
 			return nil, nil
 		})
 	}
 	b.Wait()
+	g.udpHealth.SetChecked()
 	g.performUpdateCheck()
 	return result, nil
 }
 
+// performUpdateCheck re-selects the outbounds. It runs both from the checker
+// and from failed dials, so updates are serialized by updateAccess.
 func (g *URLTestGroup) performUpdateCheck() {
+	g.updateAccess.Lock()
+	defer g.updateAccess.Unlock()
 	var updated bool
-	if outbound, exists := g.Select(N.NetworkTCP); outbound != nil && (g.selectedOutboundTCP == nil || (exists && outbound != g.selectedOutboundTCP)) {
-		if g.selectedOutboundTCP != nil {
+	if outbound, exists := g.Select(N.NetworkTCP); outbound != nil {
+		if g.updateSelected(&g.selectedOutboundTCP, outbound, exists) {
 			updated = true
 		}
-		g.selectedOutboundTCP = outbound
 	}
-	if outbound, exists := g.Select(N.NetworkUDP); outbound != nil && (g.selectedOutboundUDP == nil || (exists && outbound != g.selectedOutboundUDP)) {
-		if g.selectedOutboundUDP != nil {
+	if outbound, exists := g.Select(N.NetworkUDP); outbound != nil {
+		if g.updateSelected(&g.selectedOutboundUDP, outbound, exists) {
 			updated = true
 		}
-		g.selectedOutboundUDP = outbound
+	} else if g.udpHealth != nil && g.clearSelected(&g.selectedOutboundUDP) {
+		// No member is UDP-healthy, the stale selection must not be used
+		updated = true
 	}
 	if updated {
 		g.interruptGroup.Interrupt(g.interruptExternalConnections)
 	}
 }
+
+// updateSelected stores outbound as the selection if there is none yet, or if
+// it was chosen by measured delay. It reports whether a previous selection was
+// replaced.
+func (g *URLTestGroup) updateSelected(selected *adapter.Outbound, outbound adapter.Outbound, exists bool) bool {
+	g.selectedAccess.Lock()
+	defer g.selectedAccess.Unlock()
+	if *selected != nil && (!exists || outbound == *selected) {
+		return false
+	}
+	replaced := *selected != nil
+	*selected = outbound
+	return replaced
+}
+
+// clearSelected clears the selection and reports whether there was one
+func (g *URLTestGroup) clearSelected(selected *adapter.Outbound) bool {
+	g.selectedAccess.Lock()
+	defer g.selectedAccess.Unlock()
+	cleared := *selected != nil
+	*selected = nil
+	return cleared
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/outbound"
+	"github.com/sagernet/sing-box/common/connpool"
 	"github.com/sagernet/sing-box/common/dialer"
 	"github.com/sagernet/sing-box/common/tls"
 	C "github.com/sagernet/sing-box/constant"
@@ -25,8 +26,9 @@ func RegisterOutbound(registry *outbound.Registry) {
 
 type Outbound struct {
 	outbound.Adapter
-	logger logger.ContextLogger
-	client *sHTTP.Client
+	logger   logger.ContextLogger
+	client   *sHTTP.Client
+	connPool *connpool.Pool
 }
 
 func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.HTTPOutboundOptions) (adapter.Outbound, error) {
@@ -38,6 +40,10 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 	if err != nil {
 		return nil, err
 	}
+	detour, connPool, err := connpool.NewDialer(ctx, logger, detour, options.ServerOptions.Build(), options.DialerOptions)
+	if err != nil {
+		return nil, err
+	}
 	return &Outbound{
 		Adapter: outbound.NewAdapterWithDialerOptions(C.TypeHTTP, tag, []string{N.NetworkTCP}, options.DialerOptions),
 		logger:  logger,
@@ -49,6 +55,7 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 			Path:     options.Path,
 			Headers:  options.Headers.Build(),
 		}),
+		connPool: connPool,
 	}, nil
 }
 
@@ -63,3 +70,20 @@ func (h *Outbound) DialContext(ctx context.Context, network string, destination
 func (h *Outbound) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
 	return nil, os.ErrInvalid
 }
+
+func (h *Outbound) InterfaceUpdated() {
+	if h.connPool != nil {
+		h.connPool.Reset()
+	}
+}
+
+func (h *Outbound) Close() error {
+	return common.Close(common.PtrOrNil(h.connPool))
+}
+
+func (h *Outbound) ConnectionPool() adapter.ConnectionPool {
+	if h.connPool == nil {
+		return nil
+	}
+	return h.connPool
+}
@@ -0,0 +1,210 @@
+package router
+
+import (
+	"crypto/subtle"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/auth"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/contrab/freelru"
+	"github.com/sagernet/sing/contrab/maphash"
+)
+
+const (
+	defaultAuthStatusCode      = http.StatusUnauthorized
+	defaultRateLimitStatusCode = http.StatusTooManyRequests
+	maxRateLimitClients        = 65536
+)
+
+// routeAccess enforces a route's authentication and per-source-IP limits
+// before a connection is handed to the target inbound. A nil routeAccess
+// allows everything.
+type routeAccess struct {
+	// Authentication
+	authenticator  *auth.Authenticator
+	bearerTokens   [][]byte
+	realm          string
+	authStatusCode int
+
+	// Rate limiting
+	rate            float64
+	burst           float64
+	maxConnections  int
+	limitStatusCode int
+	access          sync.Mutex
+	buckets         *freelru.LRU[netip.Addr, *tokenBucket]
+	connections     map[netip.Addr]int
+}
+
+type tokenBucket struct {
+	tokens     float64
+	lastRefill time.Time
+}
+
+func newRouteAccess(authOptions *option.RouteAuthOptions, rateLimitOptions *option.RouteRateLimitOptions) (*routeAccess, error) {
+	if authOptions == nil && rateLimitOptions == nil {
+		return nil, nil
+	}
+	access := &routeAccess{}
+	if authOptions != nil {
+		if len(authOptions.Users) == 0 && len(authOptions.BearerTokens) == 0 {
+			return nil, E.New("auth: missing users or bearer_tokens")
+		}
+		for _, token := range authOptions.BearerTokens {
+			if token == "" {
+				return nil, E.New("auth: empty bearer token")
+			}
+		}
+		access.authenticator = auth.NewAuthenticator(authOptions.Users)
+		access.bearerTokens = common.Map(authOptions.BearerTokens, func(it string) []byte {
+			return []byte(it)
+		})
+		access.realm = authOptions.Realm
+		if access.realm == "" {
+			access.realm = "Restricted"
+		}
+		access.authStatusCode = authOptions.StatusCode
+		if access.authStatusCode == 0 {
+			access.authStatusCode = defaultAuthStatusCode
+		}
+		if access.authStatusCode < 400 || access.authStatusCode > 599 {
+			return nil, E.New("auth: invalid status_code: ", access.authStatusCode)
+		}
+	}
+	if rateLimitOptions != nil {
+		if rateLimitOptions.Rate < 0 || rateLimitOptions.Burst < 0 || rateLimitOptions.MaxConnections < 0 {
+			return nil, E.New("rate_limit: rate, burst and max_connections must be >= 0")
+		}
+		if rateLimitOptions.Rate == 0 && rateLimitOptions.MaxConnections == 0 {
+			return nil, E.New("rate_limit: missing rate or max_connections")
+		}
+		access.limitStatusCode = rateLimitOptions.StatusCode
+		if access.limitStatusCode == 0 {
+			access.limitStatusCode = defaultRateLimitStatusCode
+		}
+		if access.limitStatusCode < 400 || access.limitStatusCode > 599 {
+			return nil, E.New("rate_limit: invalid status_code: ", access.limitStatusCode)
+		}
+		if rateLimitOptions.Rate > 0 {
+			access.rate = rateLimitOptions.Rate
+			access.burst = float64(rateLimitOptions.Burst)
+			if access.burst < 1 {
+				access.burst = max(1, access.rate)
+			}
+			// A bucket left alone for this long is full again and can be forgotten
+			refillTime := time.Duration(access.burst / access.rate * float64(time.Second))
+			access.buckets = common.Must1(freelru.New[netip.Addr, *tokenBucket](maxRateLimitClients, maphash.NewHasher[netip.Addr]().Hash32))
+			access.buckets.SetLifetime(max(refillTime, time.Second))
+		}
+		if rateLimitOptions.MaxConnections > 0 {
+			access.maxConnections = rateLimitOptions.MaxConnections
+			access.connections = make(map[netip.Addr]int)
+		}
+	}
+	return access, nil
+}
+
+func (a *routeAccess) hasAuth() bool {
+	return a != nil && (a.authenticator != nil || len(a.bearerTokens) > 0)
+}
+
+// authorize checks the Authorization header of request against the
+// configured basic auth users and bearer tokens
+func (a *routeAccess) authorize(request *http.Request) bool {
+	if !a.hasAuth() {
+		return true
+	}
+	authorization := request.Header.Get("Authorization")
+	scheme, credentials, _ := strings.Cut(authorization, " ")
+	switch {
+	case strings.EqualFold(scheme, "Bearer"):
+		token := []byte(strings.TrimSpace(credentials))
+		for _, bearerToken := range a.bearerTokens {
+			if subtle.ConstantTimeCompare(token, bearerToken) == 1 {
+				return true
+			}
+		}
+	case strings.EqualFold(scheme, "Basic"):
+		username, password, ok := request.BasicAuth()
+		return ok && a.authenticator != nil && a.authenticator.Verify(username, password)
+	}
+	return false
+}
+
+// authenticateHeader returns the WWW-Authenticate challenge for rejected requests
+func (a *routeAccess) authenticateHeader() string {
+	if a.authenticator != nil {
+		return "Basic realm=" + strconv.Quote(a.realm)
+	}
+	return "Bearer realm=" + strconv.Quote(a.realm)
+}
+
+// acquire takes a token from source's bucket and a slot from its connection
+// cap. On success, release must be called once the connection is closed.
+func (a *routeAccess) acquire(source netip.Addr) (release func(), ok bool) {
+	if a == nil || (a.buckets == nil && a.connections == nil) {
+		return func() {}, true
+	}
+	source = source.Unmap()
+	a.access.Lock()
+	defer a.access.Unlock()
+	if a.connections != nil && a.connections[source] >= a.maxConnections {
+		return nil, false
+	}
+	if a.buckets != nil {
+		now := time.Now()
+		bucket, loaded := a.buckets.Get(source)
+		if !loaded {
+			bucket = &tokenBucket{tokens: a.burst, lastRefill: now}
+		} else {
+			bucket.tokens = min(a.burst, bucket.tokens+now.Sub(bucket.lastRefill).Seconds()*a.rate)
+			bucket.lastRefill = now
+		}
+		// Re-adding refreshes the entry's lifetime
+		a.buckets.Add(source, bucket)
+		if bucket.tokens < 1 {
+			return nil, false
+		}
+		bucket.tokens--
+	}
+	if a.connections == nil {
+		return func() {}, true
+	}
+	a.connections[source]++
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			a.access.Lock()
+			defer a.access.Unlock()
+			if a.connections[source] <= 1 {
+				delete(a.connections, source)
+			} else {
+				a.connections[source]--
+			}
+		})
+	}, true
+}
+
+// writeStatusResponse writes a minimal HTTP response to a raw connection
+func writeStatusResponse(conn net.Conn, statusCode int, header http.Header) {
+	response := &http.Response{
+		StatusCode: statusCode,
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+		Header:     header,
+		Close:      true,
+	}
+	if response.Header == nil {
+		response.Header = make(http.Header)
+	}
+	response.Header.Set("Content-Length", "0")
+	_ = response.Write(conn)
+}
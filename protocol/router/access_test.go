@@ -0,0 +1,117 @@
+package router
+
+import (
+	"net/http"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/auth"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRouteAccessAuthorize(t *testing.T) {
+	t.Parallel()
+	access, err := newRouteAccess(&option.RouteAuthOptions{
+		Users:        []auth.User{{Username: "user", Password: "password"}},
+		BearerTokens: []string{"token"},
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusUnauthorized, access.authStatusCode)
+	require.Equal(t, `Basic realm="Restricted"`, access.authenticateHeader())
+	for _, testCase := range []struct {
+		authorization string
+		authorized    bool
+	}{
+		{"", false},
+		{"Bearer token", true},
+		{"bearer  token ", true},
+		{"Bearer other", false},
+		{"Basic dXNlcjpwYXNzd29yZA==", true}, // user:password
+		{"Basic dXNlcjpvdGhlcg==", false},    // user:other
+		{"Digest token", false},
+	} {
+		request, err := http.NewRequest(http.MethodGet, "http://example.com/", nil)
+		require.NoError(t, err)
+		if testCase.authorization != "" {
+			request.Header.Set("Authorization", testCase.authorization)
+		}
+		require.Equal(t, testCase.authorized, access.authorize(request), testCase.authorization)
+	}
+
+	// Routes without auth accept every request
+	var noAccess *routeAccess
+	require.True(t, noAccess.authorize(&http.Request{Header: http.Header{}}))
+}
+
+func TestRouteAccessOptions(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		auth      *option.RouteAuthOptions
+		rateLimit *option.RouteRateLimitOptions
+	}{
+		{auth: &option.RouteAuthOptions{}},
+		{auth: &option.RouteAuthOptions{BearerTokens: []string{""}}},
+		{auth: &option.RouteAuthOptions{BearerTokens: []string{"token"}, StatusCode: 200}},
+		{rateLimit: &option.RouteRateLimitOptions{}},
+		{rateLimit: &option.RouteRateLimitOptions{Rate: -1}},
+		{rateLimit: &option.RouteRateLimitOptions{MaxConnections: 1, StatusCode: 302}},
+	} {
+		_, err := newRouteAccess(testCase.auth, testCase.rateLimit)
+		require.Error(t, err)
+	}
+	access, err := newRouteAccess(nil, nil)
+	require.NoError(t, err)
+	require.Nil(t, access)
+}
+
+func TestRouteAccessRateLimit(t *testing.T) {
+	t.Parallel()
+	access, err := newRouteAccess(nil, &option.RouteRateLimitOptions{Rate: 20, Burst: 2})
+	require.NoError(t, err)
+	source := netip.MustParseAddr("192.0.2.1")
+	for i := 0; i < 2; i++ {
+		release, ok := access.acquire(source)
+		require.True(t, ok)
+		release()
+	}
+	_, ok := access.acquire(source)
+	require.False(t, ok)
+	// Other sources have their own bucket, IPv4-mapped addresses share one
+	_, ok = access.acquire(netip.MustParseAddr("192.0.2.2"))
+	require.True(t, ok)
+	_, ok = access.acquire(netip.MustParseAddr("::ffff:192.0.2.1"))
+	require.False(t, ok)
+
+	// Tokens refill at rate
+	time.Sleep(100 * time.Millisecond)
+	_, ok = access.acquire(source)
+	require.True(t, ok)
+}
+
+func TestRouteAccessMaxConnections(t *testing.T) {
+	t.Parallel()
+	access, err := newRouteAccess(nil, &option.RouteRateLimitOptions{MaxConnections: 2})
+	require.NoError(t, err)
+	source := netip.MustParseAddr("192.0.2.1")
+	release1, ok := access.acquire(source)
+	require.True(t, ok)
+	release2, ok := access.acquire(source)
+	require.True(t, ok)
+	_, ok = access.acquire(source)
+	require.False(t, ok)
+
+	// Releasing twice frees a single slot
+	release1()
+	release1()
+	release3, ok := access.acquire(source)
+	require.True(t, ok)
+	_, ok = access.acquire(source)
+	require.False(t, ok)
+
+	release2()
+	release3()
+	require.Empty(t, access.connections)
+}
@@ -1,27 +1,37 @@
 package router
 
 import (
+	"context"
 	"net/http"
+	"net/http/httputil"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
 	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/service"
 )
 
 type fallbackHandler struct {
-	fallbackType string
-	webroot      string
-	indexFiles   []string
-	statusCode   int
-	targetTag    string
+	fallbackType   string
+	webroot        string
+	indexFiles     []string
+	statusCode     int
+	targetTag      string
+	dialer         N.Dialer
+	proxy          *httputil.ReverseProxy
+	proxyTransport *http.Transport
 }
 
-func newFallbackHandler(options *option.FallbackOptions) (*fallbackHandler, error) {
+func newFallbackHandler(ctx context.Context, logger log.ContextLogger, options *option.FallbackOptions) (*fallbackHandler, error) {
 	if options == nil {
 		return &fallbackHandler{
 			fallbackType: "reject",
@@ -68,6 +78,29 @@ func newFallbackHandler(options *option.FallbackOptions) (*fallbackHandler, erro
 			return nil, E.New("target inbound tag is required for inbound fallback")
 		}
 
+	case "proxy":
+		if options.URL == "" {
+			return nil, E.New("url is required for proxy fallback")
+		}
+		upstream, err := url.Parse(options.URL)
+		if err != nil {
+			return nil, E.Cause(err, "parse proxy url")
+		}
+		if (upstream.Scheme != "http" && upstream.Scheme != "https") || upstream.Host == "" {
+			return nil, E.New("proxy url must be an absolute http or https URL: ", options.URL)
+		}
+		outboundManager := service.FromContext[adapter.OutboundManager](ctx)
+		if options.Detour != "" {
+			handler.dialer = dialer.NewDetour(outboundManager, options.Detour, false)
+		} else {
+			handler.dialer = &defaultOutboundDialer{outboundManager}
+		}
+		if options.ProxyProtocol > 2 {
+			return nil, E.New("unknown PROXY protocol version: ", options.ProxyProtocol)
+		}
+		handler.proxyTransport = newProxyTransport(ctx, handler.dialer, options.ProxyProtocol)
+		handler.proxy = newReverseProxy(logger, upstream, options.Host, handler.proxyTransport, options.ProxyProtocol)
+
 	default:
 		return nil, E.New("unknown fallback type: ", options.Type)
 	}
@@ -75,6 +108,13 @@ func newFallbackHandler(options *option.FallbackOptions) (*fallbackHandler, erro
 	return handler, nil
 }
 
+// close closes the idle upstream connections of the proxy fallback
+func (f *fallbackHandler) close() {
+	if f.proxyTransport != nil {
+		f.proxyTransport.CloseIdleConnections()
+	}
+}
+
 func (f *fallbackHandler) handle(r *Inbound, c *gin.Context) {
 	ctx := c.Request.Context()
 
@@ -87,6 +127,8 @@ func (f *fallbackHandler) handle(r *Inbound, c *gin.Context) {
 		f.reject(c)
 	case "inbound":
 		f.forwardToInbound(r, c)
+	case "proxy":
+		f.proxy.ServeHTTP(c.Writer, c.Request)
 	default:
 		r.logger.ErrorContext(ctx, "unknown fallback type: ", f.fallbackType)
 		c.AbortWithStatus(http.StatusInternalServerError)
@@ -152,10 +194,12 @@ func (f *fallbackHandler) drop(r *Inbound, c *gin.Context) {
 
 	// Hijack and immediately close the connection
 	hijacker, ok := c.Writer.(http.Hijacker)
-	if ok {
+	if ok && c.Request.ProtoMajor == 1 {
 		conn, _, err := hijacker.Hijack()
 		if err == nil {
+			_, onClose := loadAccessLog(c).trackConn(conn, nil)
 			conn.Close()
+			onClose(nil)
 			return
 		}
 	}
@@ -189,7 +233,9 @@ func (f *fallbackHandler) forwardToInbound(r *Inbound, c *gin.Context) {
 
 	// Forward to target inbound
 	onClose := r.trackHijackedConn(conn)
+	conn, onClose = loadAccessLog(c).trackConn(conn, onClose)
 	if err := r.forwardToInbound(ctx, conn, f.targetTag, metadata, onClose); err != nil {
+		loadAccessLog(c).fail()
 		r.logger.ErrorContext(ctx, "failed to forward to fallback inbound: ", err)
 		conn.Close()
 		onClose(nil)
@@ -1,11 +1,15 @@
 package router
 
 import (
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
 )
 
 // handleHTTPRequest processes incoming HTTP requests
@@ -17,37 +21,135 @@ func (r *Inbound) handleHTTPRequest(c *gin.Context) {
 	r.logger.DebugContext(ctx, "received request: ", c.Request.Method, " ", c.Request.URL.Path, " from ", c.Request.RemoteAddr)
 
 	// Evaluate routes in priority order
-	for _, route := range r.routes {
+	for _, route := range r.routeTable.Load().routes {
 		if route.matcher.matches(c.Request) {
 			r.logger.InfoContext(ctx, "matched route: ", route.name, " for ", c.Request.URL.Path)
+			access := r.startHTTPAccessLog(c, route)
+
+			// Check access before the connection is handed over
+			if !route.access.authorize(c.Request) {
+				r.logger.InfoContext(ctx, "unauthorized request for route ", route.name, " from ", c.Request.RemoteAddr)
+				c.Header("WWW-Authenticate", route.access.authenticateHeader())
+				c.AbortWithStatus(route.access.authStatusCode)
+				access.reject()
+				return
+			}
+			release, ok := route.access.acquire(M.ParseSocksaddr(c.Request.RemoteAddr).Addr)
+			if !ok {
+				r.logger.InfoContext(ctx, "rate limit exceeded for route ", route.name, " from ", c.Request.RemoteAddr)
+				c.AbortWithStatus(route.access.limitStatusCode)
+				access.reject()
+				return
+			}
+
+			// HTTP/2 and HTTP/3 streams can not be hijacked, so stream-capable
+			// inbounds serve those requests themselves
+			if c.Request.ProtoMajor >= 2 {
+				handler, loaded := r.loadHTTPHandler(route.targetInbound, c.Request.ProtoMajor)
+				if !loaded {
+					r.logger.ErrorContext(ctx, "target inbound ", route.targetInbound, " can not serve HTTP/", c.Request.ProtoMajor, " requests")
+					c.AbortWithStatus(http.StatusHTTPVersionNotSupported)
+					release()
+					return
+				}
+				r.dispatchHTTPRequest(c, route, handler, release)
+				return
+			}
 
 			// Check for WebSocket upgrade
 			if isWebSocketUpgrade(c) {
-				r.handleWebSocketUpgrade(c, route)
+				r.handleWebSocketUpgrade(c, route, release)
 				return
 			}
 
 			// For router inbound, all HTTP requests should be hijacked and forwarded
-			r.handleHijackedConnection(c, route)
+			r.handleHijackedConnection(c, route, release)
 			return
 		}
 	}
 
 	// No match - handle fallback
 	r.logger.DebugContext(ctx, "no route matched for ", c.Request.URL.Path, ", using fallback")
+	r.startHTTPAccessLog(c, nil)
 	r.fallback.handle(r, c)
 }
 
+// loadHTTPHandler returns the HTTP handler of the target inbound's transport,
+// if the target can serve requests of the HTTP major version without
+// hijacking. Dispatching to the router itself would loop, like
+// forwardToInbound rejects.
+func (r *Inbound) loadHTTPHandler(targetTag string, protoMajor int) (http.Handler, bool) {
+	if targetTag == r.Tag() {
+		return nil, false
+	}
+	targetInbound, loaded := r.inboundManager.Get(targetTag)
+	if !loaded {
+		return nil, false
+	}
+	httpInbound, isHTTP := targetInbound.(adapter.HTTPInjectableInbound)
+	if !isHTTP {
+		return nil, false
+	}
+	return httpInbound.HTTPHandler(protoMajor)
+}
+
+// checkHTTPVersions rejects HTTP routes whose target inbound can not serve
+// the HTTP/2 or HTTP/3 requests accepted by the router. Targets that do not
+// exist yet are checked again by Start.
+func (r *Inbound) checkHTTPVersions(table *routeTable) error {
+	var protoMajors []int
+	if r.http2 {
+		protoMajors = append(protoMajors, 2)
+	}
+	if r.http3 {
+		protoMajors = append(protoMajors, 3)
+	}
+	if len(protoMajors) == 0 {
+		return nil
+	}
+	for _, route := range table.routes {
+		if _, loaded := r.inboundManager.Get(route.targetInbound); !loaded {
+			continue
+		}
+		for _, protoMajor := range protoMajors {
+			if _, loaded := r.loadHTTPHandler(route.targetInbound, protoMajor); !loaded {
+				return E.New("route ", route.name, ": target inbound ", route.targetInbound, " can not serve HTTP/", protoMajor, " requests")
+			}
+		}
+	}
+	return nil
+}
+
+// dispatchHTTPRequest hands a single request to the target inbound's
+// transport. Unlike hijacking, this works with HTTP/2 and HTTP/3.
+func (r *Inbound) dispatchHTTPRequest(c *gin.Context, route *compiledRoute, handler http.Handler, release func()) {
+	defer release()
+	ctx := c.Request.Context()
+	r.logger.DebugContext(ctx, "dispatching request to ", route.targetInbound)
+
+	route.rewriter.rewrite(c.Request)
+
+	// Streams last as long as the request, so server timeouts must not cut them
+	controller := http.NewResponseController(c.Writer)
+	_ = controller.SetReadDeadline(time.Time{})
+	_ = controller.SetWriteDeadline(time.Time{})
+
+	// gin presets 404 for NoRoute handlers
+	c.Status(http.StatusOK)
+	handler.ServeHTTP(c.Writer, c.Request)
+}
+
 // handleWebSocketUpgrade hijacks connection for WebSocket
-func (r *Inbound) handleWebSocketUpgrade(c *gin.Context, route *compiledRoute) {
+func (r *Inbound) handleWebSocketUpgrade(c *gin.Context, route *compiledRoute, release func()) {
 	ctx := c.Request.Context()
 	r.logger.InfoContext(ctx, "handling WebSocket upgrade for route: ", route.name)
 
 	// Hijack the connection and reconstruct the HTTP request for the target inbound
-	conn, err := r.hijackConnectionForForwarding(c)
+	conn, err := r.hijackConnectionForForwarding(c, route.rewriter)
 	if err != nil {
 		r.logger.ErrorContext(ctx, "failed to hijack WebSocket connection: ", err)
 		c.AbortWithStatus(500)
+		release()
 		return
 	}
 
@@ -56,8 +158,10 @@ func (r *Inbound) handleWebSocketUpgrade(c *gin.Context, route *compiledRoute) {
 
 	// Forward to target inbound
 	r.logger.DebugContext(ctx, "forwarding WebSocket to ", route.targetInbound)
-	onClose := r.trackHijackedConn(conn)
+	onClose := N.AppendClose(r.trackHijackedConn(conn), func(error) { release() })
+	conn, onClose = loadAccessLog(c).trackConn(conn, onClose)
 	if err := r.forwardToInbound(ctx, conn, route.targetInbound, metadata, onClose); err != nil {
+		loadAccessLog(c).fail()
 		r.logger.ErrorContext(ctx, "failed to forward WebSocket to ", route.targetInbound, ": ", err)
 		conn.Close()
 		onClose(nil)
@@ -65,15 +169,16 @@ func (r *Inbound) handleWebSocketUpgrade(c *gin.Context, route *compiledRoute) {
 }
 
 // handleHijackedConnection handles raw TCP after HTTP
-func (r *Inbound) handleHijackedConnection(c *gin.Context, route *compiledRoute) {
+func (r *Inbound) handleHijackedConnection(c *gin.Context, route *compiledRoute, release func()) {
 	ctx := c.Request.Context()
 	r.logger.DebugContext(ctx, "hijacking connection for route: ", route.name)
 
 	// Hijack the connection and reconstruct the HTTP request for the target inbound
-	conn, err := r.hijackConnectionForForwarding(c)
+	conn, err := r.hijackConnectionForForwarding(c, route.rewriter)
 	if err != nil {
 		r.logger.ErrorContext(ctx, "failed to hijack connection: ", err)
 		c.AbortWithStatus(500)
+		release()
 		return
 	}
 
@@ -82,8 +187,10 @@ func (r *Inbound) handleHijackedConnection(c *gin.Context, route *compiledRoute)
 
 	// Forward to target inbound
 	r.logger.DebugContext(ctx, "forwarding connection to ", route.targetInbound)
-	onClose := r.trackHijackedConn(conn)
+	onClose := N.AppendClose(r.trackHijackedConn(conn), func(error) { release() })
+	conn, onClose = loadAccessLog(c).trackConn(conn, onClose)
 	if err := r.forwardToInbound(ctx, conn, route.targetInbound, metadata, onClose); err != nil {
+		loadAccessLog(c).fail()
 		r.logger.ErrorContext(ctx, "failed to forward connection to ", route.targetInbound, ": ", err)
 		conn.Close()
 		onClose(nil)
@@ -0,0 +1,91 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/adapter/inbound"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/require"
+)
+
+// testHTTPInbound serves HTTP/2 requests only, like a grpc transport
+type testHTTPInbound struct {
+	adapter.Inbound
+}
+
+func (h *testHTTPInbound) HTTPHandler(protoMajor int) (http.Handler, bool) {
+	if protoMajor != 2 {
+		return nil, false
+	}
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Write([]byte("served " + request.URL.Path))
+	}), true
+}
+
+func newTestHTTPRouter(t *testing.T, routes []option.RouteRule) *Inbound {
+	table, err := compileRoutes(routes, nil)
+	require.NoError(t, err)
+	router := &Inbound{
+		Adapter: inbound.NewAdapter(C.TypeRouter, "router"),
+		inboundManager: &testInboundManager{inbounds: map[string]adapter.Inbound{
+			"h2-in": &testHTTPInbound{},
+			"h1-in": &testTargetInbound{},
+		}},
+		logger:   log.NewNOPFactory().Logger(),
+		fallback: &fallbackHandler{},
+	}
+	router.routeTable.Store(table)
+	return router
+}
+
+func testHTTPRequest(router *Inbound, path string, protoMajor int) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest("GET", path, nil)
+	c.Request.ProtoMajor = protoMajor
+	router.handleHTTPRequest(c)
+	return recorder
+}
+
+func TestDispatchHTTPRequest(t *testing.T) {
+	t.Parallel()
+	router := newTestHTTPRouter(t, []option.RouteRule{
+		{Name: "h2", Target: "h2-in", Match: option.RouteMatch{PathPrefix: []string{"/h2"}}},
+		{Name: "h1", Target: "h1-in", Match: option.RouteMatch{PathPrefix: []string{"/h1"}}},
+	})
+
+	recorder := testHTTPRequest(router, "/h2/stream", 2)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.Equal(t, "served /h2/stream", recorder.Body.String())
+
+	// Targets that can not serve the version get a clean 505
+	require.Equal(t, http.StatusHTTPVersionNotSupported, testHTTPRequest(router, "/h2/stream", 3).Code)
+	require.Equal(t, http.StatusHTTPVersionNotSupported, testHTTPRequest(router, "/h1/stream", 2).Code)
+}
+
+func TestCheckHTTPVersions(t *testing.T) {
+	t.Parallel()
+	h2Route := option.RouteRule{Name: "h2", Target: "h2-in", Match: option.RouteMatch{PathPrefix: []string{"/h2"}}}
+	h1Route := option.RouteRule{Name: "h1", Target: "h1-in", Match: option.RouteMatch{PathPrefix: []string{"/h1"}}}
+	missingRoute := option.RouteRule{Name: "missing", Target: "missing-in", Match: option.RouteMatch{PathPrefix: []string{"/"}}}
+
+	router := newTestHTTPRouter(t, []option.RouteRule{h1Route})
+	require.NoError(t, router.checkHTTPVersions(router.routeTable.Load()))
+
+	router = newTestHTTPRouter(t, []option.RouteRule{h2Route, missingRoute})
+	router.http2 = true
+	require.NoError(t, router.checkHTTPVersions(router.routeTable.Load()))
+	router.http3 = true
+	require.Error(t, router.checkHTTPVersions(router.routeTable.Load()))
+
+	router = newTestHTTPRouter(t, []option.RouteRule{h2Route, h1Route})
+	router.http2 = true
+	require.Error(t, router.checkHTTPVersions(router.routeTable.Load()))
+}
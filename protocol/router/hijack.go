@@ -14,6 +14,10 @@ import (
 // hijackConnection hijacks the HTTP connection from gin
 // Returns a wrapped connection that preserves buffered data
 func (r *Inbound) hijackConnection(c *gin.Context) (net.Conn, error) {
+	if c.Request.ProtoMajor != 1 {
+		return nil, E.New("hijacking is not supported over HTTP/", c.Request.ProtoMajor)
+	}
+
 	// Get the hijacker interface
 	hijacker, ok := c.Writer.(http.Hijacker)
 	if !ok {
@@ -38,8 +42,13 @@ func (r *Inbound) hijackConnection(c *gin.Context) (net.Conn, error) {
 
 // hijackConnectionForForwarding hijacks the connection and reconstructs the HTTP request
 // This is needed because gin has already consumed the HTTP request from the wire
-// The target inbound needs to read the HTTP request, so we reconstruct it
-func (r *Inbound) hijackConnectionForForwarding(c *gin.Context) (net.Conn, error) {
+// The target inbound needs to read the HTTP request, so we reconstruct it after
+// applying the route's rewrites
+func (r *Inbound) hijackConnectionForForwarding(c *gin.Context, rewriter *routeRewriter) (net.Conn, error) {
+	if c.Request.ProtoMajor != 1 {
+		return nil, E.New("hijacking is not supported over HTTP/", c.Request.ProtoMajor)
+	}
+
 	// Get the hijacker interface
 	hijacker, ok := c.Writer.(http.Hijacker)
 	if !ok {
@@ -53,32 +62,11 @@ func (r *Inbound) hijackConnectionForForwarding(c *gin.Context) (net.Conn, error
 	}
 
 	// Reconstruct the HTTP request that gin consumed
-	var requestBuf bytes.Buffer
-
-	// Write request line: METHOD PATH HTTP/1.1
-	requestBuf.WriteString(c.Request.Method)
-	requestBuf.WriteString(" ")
-	requestBuf.WriteString(c.Request.RequestURI)
-	requestBuf.WriteString(" ")
-	requestBuf.WriteString(c.Request.Proto)
-	requestBuf.WriteString("\r\n")
-
-	// Write all headers
-	for key, values := range c.Request.Header {
-		for _, value := range values {
-			requestBuf.WriteString(key)
-			requestBuf.WriteString(": ")
-			requestBuf.WriteString(value)
-			requestBuf.WriteString("\r\n")
-		}
-	}
-
-	// End of headers
-	requestBuf.WriteString("\r\n")
+	rewriter.rewrite(c.Request)
 
 	// Create a reader that first reads from our reconstructed request, then from buffered data, then from the connection
 	multiReader := io.MultiReader(
-		&requestBuf,
+		bytes.NewReader(requestHead(c.Request)),
 		brw.Reader,
 	)
 
@@ -92,6 +80,16 @@ func (r *Inbound) hijackConnectionForForwarding(c *gin.Context) (net.Conn, error
 	return wrappedConn, nil
 }
 
+// newReplayConn wraps a connection whose request head was consumed by
+// http.ReadRequest, replaying the head before the data buffered by reader
+func newReplayConn(conn net.Conn, reader *bufio.Reader, request *http.Request) net.Conn {
+	return &hijackedConnWithRequest{
+		Conn:   conn,
+		reader: bufio.NewReader(io.MultiReader(bytes.NewReader(requestHead(request)), reader)),
+		writer: bufio.NewWriter(conn),
+	}
+}
+
 // hijackedConnWithRequest wraps a hijacked connection with a reconstructed HTTP request
 type hijackedConnWithRequest struct {
 	net.Conn
@@ -117,24 +115,10 @@ func (c *hijackedConnWithRequest) Write(p []byte) (n int, err error) {
 
 // WriteTo implements io.WriterTo for efficient copying
 func (c *hijackedConnWithRequest) WriteTo(w io.Writer) (n int64, err error) {
-	// First write any buffered data
-	if c.reader.Buffered() > 0 {
-		buffered := c.reader.Buffered()
-		buf := make([]byte, buffered)
-		_, err = io.ReadFull(c.reader, buf)
-		if err != nil {
-			return 0, err
-		}
-		written, err := w.Write(buf)
-		if err != nil {
-			return int64(written), err
-		}
-		n = int64(written)
-	}
-
-	// Then copy remaining data from underlying connection
-	copied, err := io.Copy(w, c.Conn)
-	return n + copied, err
+	// The reader replays the reconstructed request and the buffered data
+	// before reading from the underlying connection, even if nothing was
+	// read through it yet
+	return io.Copy(w, c.reader)
 }
 
 // ReadFrom implements io.ReaderFrom for efficient copying
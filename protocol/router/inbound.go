@@ -3,30 +3,47 @@ package router
 import (
 	"bufio"
 	"context"
+	stdTLS "crypto/tls"
+	"io"
 	"net"
 	"net/http"
 	"net/netip"
-	"sort"
+	"path/filepath"
 	"strconv"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sagernet/fswatch"
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/inbound"
+	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/common/pipelistener"
+	"github.com/sagernet/sing-box/common/tls"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
 	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/service"
+	"github.com/sagernet/sing/service/filemanager"
+
+	"golang.org/x/net/http2"
 )
 
+var ConfigureHTTP3ListenerFunc func(udpConn net.PacketConn, handler http.Handler, tlsConfig tls.ServerConfig, logger logger.Logger) (io.Closer, error)
+
 func RegisterInbound(registry *inbound.Registry) {
 	inbound.Register[option.RouterInboundOptions](registry, C.TypeRouter, NewInbound)
 }
 
-var _ adapter.TCPInjectableInbound = (*Inbound)(nil)
+var (
+	_ adapter.TCPInjectableInbound = (*Inbound)(nil)
+	_ adapter.RouterInbound        = (*Inbound)(nil)
+)
 
 type Inbound struct {
 	inbound.Adapter
@@ -36,28 +53,43 @@ type Inbound struct {
 	logger         log.ContextLogger
 
 	// HTTP server components
-	ginEngine  *gin.Engine
-	httpServer *http.Server
+	ginEngine   *gin.Engine
+	httpServer  *http.Server
+	http2Server *http2.Server
+	http2       bool
+	http3       bool
+	h3Server    io.Closer
 
 	// Routing configuration
-	routes   []*compiledRoute
-	fallback *fallbackHandler
+	routeTable    atomic.Pointer[routeTable]
+	inlineRoutes  []option.RouteRule
+	routesWatcher *fswatch.Watcher
+	fallback      *fallbackHandler
+	tlsConfig     tls.ServerConfig
 
 	// Connection management
-	hijackedConns sync.Map
-	maxBodySize   int64
+	hijackedConns        sync.Map
+	maxBodySize          int64
+	acceptProxyProtocol  bool
+	proxyProtocolTrusted []netip.Prefix
+
+	fallbackMetrics routeMetrics
 
 	// Network listener
-	tcpListener net.Listener
-	listenAddr  string
+	tcpListener  net.Listener
+	httpListener *pipelistener.Listener // Feeds the HTTP server when connections are dispatched by loopAccept
+	listenAddr   string
 }
 
 type compiledRoute struct {
+	index         int // Position in the configured routes, shared by HTTP and TLS routes
 	name          string
 	matcher       *routeMatcher
 	targetInbound string
-	stripPrefix   string
+	rewriter      *routeRewriter
 	priority      int
+	access        *routeAccess
+	metrics       *routeMetrics
 }
 
 func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.RouterInboundOptions) (adapter.Inbound, error) {
@@ -65,13 +97,17 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 	gin.SetMode(gin.ReleaseMode)
 
 	inbound := &Inbound{
-		Adapter:        inbound.NewAdapter(C.TypeRouter, tag),
-		ctx:            ctx,
-		router:         router,
-		inboundManager: service.FromContext[adapter.InboundManager](ctx),
-		logger:         logger,
-		ginEngine:      gin.New(),
-		maxBodySize:    int64(options.MaxRequestBodySize),
+		Adapter:              inbound.NewAdapter(C.TypeRouter, tag),
+		ctx:                  ctx,
+		router:               router,
+		inboundManager:       service.FromContext[adapter.InboundManager](ctx),
+		logger:               logger,
+		ginEngine:            gin.New(),
+		maxBodySize:          int64(options.MaxRequestBodySize),
+		acceptProxyProtocol:  options.AcceptProxyProtocol,
+		proxyProtocolTrusted: options.ProxyProtocolTrusted,
+		http2:                options.HTTP2,
+		http3:                options.HTTP3,
 	}
 
 	if inbound.inboundManager == nil {
@@ -79,37 +115,67 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 	}
 
 	// Compile routes
-	for i, routeOpt := range options.Routes {
-		if routeOpt.Name == "" {
-			return nil, E.New("route #", i, " is missing name")
+	inbound.inlineRoutes = options.Routes
+	if options.RoutesPath != "" {
+		routesPath := filemanager.BasePath(ctx, options.RoutesPath)
+		routesPath, _ = filepath.Abs(routesPath)
+		err := inbound.reloadRoutesFile(routesPath)
+		if err != nil {
+			return nil, E.Cause(err, "load routes from ", routesPath)
 		}
-		if routeOpt.Target == "" {
-			return nil, E.New("route ", routeOpt.Name, " is missing target inbound")
+		watcher, err := fswatch.NewWatcher(fswatch.Options{
+			Path: []string{routesPath},
+			Callback: func(path string) {
+				uErr := inbound.reloadRoutesFile(path)
+				if uErr != nil {
+					logger.Error(E.Cause(uErr, "reload routes from ", path))
+				} else {
+					logger.Info("reloaded routes from ", path)
+				}
+			},
+		})
+		if err != nil {
+			return nil, err
 		}
-
-		matcher, err := newRouteMatcher(routeOpt.Match)
+		inbound.routesWatcher = watcher
+	} else {
+		table, err := compileRoutes(options.Routes, nil)
 		if err != nil {
-			return nil, E.Cause(err, "compile route ", routeOpt.Name)
+			return nil, err
 		}
+		inbound.routeTable.Store(table)
+	}
 
-		compiled := &compiledRoute{
-			name:          routeOpt.Name,
-			matcher:       matcher,
-			targetInbound: routeOpt.Target,
-			stripPrefix:   routeOpt.StripPathPrefix,
-			priority:      routeOpt.Priority,
+	// Setup TLS termination for HTTP routes
+	if options.TLS != nil {
+		tlsConfig, err := tls.NewServer(ctx, logger, common.PtrValueOrDefault(options.TLS))
+		if err != nil {
+			return nil, err
+		}
+		inbound.tlsConfig = tlsConfig
+	}
+	if options.HTTP2 || options.HTTP3 {
+		if inbound.tlsConfig == nil {
+			return nil, E.New("TLS is required for http2 and http3")
+		}
+		if options.ListenPort == 0 {
+			return nil, E.New("listen_port is required for http2 and http3")
+		}
+	}
+	if options.AcceptProxyProtocol && options.ListenPort == 0 {
+		return nil, E.New("listen_port is required for accept_proxy_protocol")
+	}
+	if options.HTTP2 {
+		if len(inbound.tlsConfig.NextProtos()) == 0 {
+			inbound.tlsConfig.SetNextProtos([]string{http2.NextProtoTLS, "http/1.1"})
+		} else if !common.Contains(inbound.tlsConfig.NextProtos(), http2.NextProtoTLS) {
+			inbound.tlsConfig.SetNextProtos(append([]string{http2.NextProtoTLS}, inbound.tlsConfig.NextProtos()...))
 		}
-		inbound.routes = append(inbound.routes, compiled)
 	}
-
-	// Sort routes by priority (higher first)
-	sort.Slice(inbound.routes, func(i, j int) bool {
-		return inbound.routes[i].priority > inbound.routes[j].priority
-	})
 
 	// Setup fallback
 	if options.Fallback != nil {
-		fb, err := newFallbackHandler(options.Fallback)
+		fb, err := newFallbackHandler(ctx, logger, options.Fallback)
 		if err != nil {
 			return nil, E.Cause(err, "setup fallback")
 		}
@@ -151,6 +217,13 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 			return ctx
 		},
 	}
+	// HTTP/2 connections are served with ServeConn after our own TLS
+	// handshake. Configuring the server hooks them into graceful shutdown.
+	inbound.http2Server = &http2.Server{}
+	err := http2.ConfigureServer(inbound.httpServer, inbound.http2Server)
+	if err != nil {
+		return nil, E.Cause(err, "configure HTTP/2 server")
+	}
 
 	// Determine listen address - if no port specified, work as internal-only inbound
 	if options.ListenPort > 0 {
@@ -169,7 +242,7 @@ func NewInbound(ctx context.Context, router adapter.Router, logger log.ContextLo
 
 func (r *Inbound) setupGinEngine(options option.RouterInboundOptions) {
 	// Disable gin's default logger and use our own
-	r.ginEngine.Use(gin.Recovery())
+	r.ginEngine.Use(gin.Recovery(), r.logAccess)
 
 	// Set max body size if configured
 	if r.maxBodySize > 0 {
@@ -179,6 +252,17 @@ func (r *Inbound) setupGinEngine(options option.RouterInboundOptions) {
 		})
 	}
 
+	// Advertise HTTP/3 to HTTP/1.1 and HTTP/2 clients
+	if options.HTTP3 {
+		altSvc := `h3=":` + strconv.Itoa(int(options.ListenPort)) + `"; ma=2592000`
+		r.ginEngine.Use(func(c *gin.Context) {
+			if c.Request.ProtoMajor < 3 {
+				c.Header("Alt-Svc", altSvc)
+			}
+			c.Next()
+		})
+	}
+
 	// Handle all requests with our main handler
 	r.ginEngine.NoRoute(r.handleHTTPRequest)
 }
@@ -188,6 +272,33 @@ func (r *Inbound) Start(stage adapter.StartStage) error {
 		return nil
 	}
 
+	if r.fallback.dialer != nil {
+		err := dialer.InitializeDetour(r.fallback.dialer)
+		if err != nil {
+			return E.Cause(err, "initialize fallback detour")
+		}
+	}
+
+	// Targets created after the router are only known now
+	err := r.checkHTTPVersions(r.routeTable.Load())
+	if err != nil {
+		return err
+	}
+
+	if r.tlsConfig != nil {
+		err := r.tlsConfig.Start()
+		if err != nil {
+			return E.Cause(err, "create TLS config")
+		}
+	}
+
+	if r.routesWatcher != nil {
+		err := r.routesWatcher.Start()
+		if err != nil {
+			r.logger.Error(E.Cause(err, "watch routes file"))
+		}
+	}
+
 	// Only create TCP listener if listenAddr is configured
 	if r.listenAddr != "" {
 		listener, err := net.Listen("tcp", r.listenAddr)
@@ -196,14 +307,36 @@ func (r *Inbound) Start(stage adapter.StartStage) error {
 		}
 		r.tcpListener = listener
 
+		// Dispatch connections ourselves if they have to be inspected before
+		// HTTP. Reloaded routes may add TLS routes at any time.
+		httpListener := r.tcpListener
+		if len(r.routeTable.Load().tlsRoutes) > 0 || r.tlsConfig != nil || r.routesWatcher != nil || r.acceptProxyProtocol {
+			r.httpListener = pipelistener.New(16)
+			httpListener = r.httpListener
+			go r.loopAccept()
+		}
+
 		// Start HTTP server in goroutine
 		go func() {
-			err := r.httpServer.Serve(r.tcpListener)
+			err := r.httpServer.Serve(httpListener)
 			if err != nil && err != http.ErrServerClosed {
 				r.logger.Error("HTTP server error: ", err)
 			}
 		}()
 
+		if r.http3 {
+			udpConn, err := net.ListenPacket("udp", r.listenAddr)
+			if err != nil {
+				return E.Cause(err, "listen UDP on ", r.listenAddr)
+			}
+			h3Server, err := ConfigureHTTP3ListenerFunc(udpConn, r.ginEngine, r.tlsConfig, r.logger)
+			if err != nil {
+				udpConn.Close()
+				return E.Cause(err, "create HTTP/3 server")
+			}
+			r.h3Server = h3Server
+		}
+
 		r.logger.Info("router inbound started on ", r.tcpListener.Addr())
 	} else {
 		r.logger.Info("router inbound started in internal-only mode")
@@ -215,6 +348,12 @@ func (r *Inbound) Start(stage adapter.StartStage) error {
 func (r *Inbound) Close() error {
 	var errors []error
 
+	if r.routesWatcher != nil {
+		if err := r.routesWatcher.Close(); err != nil {
+			errors = append(errors, E.Cause(err, "close routes watcher"))
+		}
+	}
+
 	// Shutdown HTTP server gracefully
 	if r.httpServer != nil {
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -224,6 +363,14 @@ func (r *Inbound) Close() error {
 		}
 	}
 
+	if r.h3Server != nil {
+		if err := r.h3Server.Close(); err != nil {
+			errors = append(errors, E.Cause(err, "close HTTP/3 server"))
+		}
+	}
+
+	r.fallback.close()
+
 	// Close all hijacked connections
 	r.hijackedConns.Range(func(key, value interface{}) bool {
 		if conn, ok := value.(net.Conn); ok {
@@ -239,6 +386,12 @@ func (r *Inbound) Close() error {
 		}
 	}
 
+	if r.tlsConfig != nil {
+		if err := r.tlsConfig.Close(); err != nil {
+			errors = append(errors, E.Cause(err, "close TLS config"))
+		}
+	}
+
 	if len(errors) > 0 {
 		return E.Errors(errors...)
 	}
@@ -286,12 +439,29 @@ func (r *Inbound) forwardToInbound(ctx context.Context, conn net.Conn, targetTag
 
 // handleInjectedConnection processes connections injected from other inbounds
 func (r *Inbound) handleInjectedConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
+	// onClose is cleared once it is handed over with the connection, to run
+	// when the forwarded connection closes
 	defer func() {
 		if onClose != nil {
 			onClose(nil)
 		}
 	}()
 
+	table := r.routeTable.Load()
+
+	// Route TLS connections by their ClientHello first
+	if len(table.tlsRoutes) > 0 {
+		var clientHello *stdTLS.ClientHelloInfo
+		conn, clientHello, _ = r.peekClientHello(ctx, conn)
+		if clientHello != nil {
+			if route := table.matchTLSRoute(clientHello); route != nil {
+				r.forwardTLSRoute(ctx, conn, route, clientHello, metadata, onClose)
+				onClose = nil
+				return
+			}
+		}
+	}
+
 	// Read HTTP request from the connection
 	reader := bufio.NewReader(conn)
 	req, err := http.ReadRequest(reader)
@@ -305,7 +475,7 @@ func (r *Inbound) handleInjectedConnection(ctx context.Context, conn net.Conn, m
 
 	// Find matching route
 	var matchedRoute *compiledRoute
-	for _, route := range r.routes {
+	for _, route := range table.routes {
 		if route.matcher.matches(req) {
 			matchedRoute = route
 			r.logger.InfoContext(ctx, "matched route: ", route.name, " for ", req.URL.Path)
@@ -313,45 +483,77 @@ func (r *Inbound) handleInjectedConnection(ctx context.Context, conn net.Conn, m
 		}
 	}
 
+	access := r.newAccessLog(ctx, matchedRoute)
+	access.event.
+		WithSource(metadata.Source).
+		WithRequest(req.Method, req.Host, req.URL.Path)
+
 	// If no route matched, use fallback
 	if matchedRoute == nil {
 		r.logger.DebugContext(ctx, "no route matched for ", req.URL.Path, ", using fallback")
 		// For injected connections with no match, we can't serve static files
 		// So we just close or forward to fallback inbound if configured
 		if r.fallback.fallbackType == "inbound" && r.fallback.targetTag != "" {
-			// Wrap connection with buffered reader to preserve the HTTP request data
-			wrappedConn := &injectedConn{
-				Conn:   conn,
-				reader: reader,
-			}
+			// ReadRequest consumed the request head, so it is written again in
+			// front of the buffered data
+			wrappedConn := newReplayConn(conn, reader, req)
 			metadata.InboundDetour = r.fallback.targetTag
-			if err := r.forwardToInbound(ctx, wrappedConn, r.fallback.targetTag, metadata, nil); err != nil {
+			forwardConn, trackedClose := access.trackConn(wrappedConn, onClose)
+			onClose = nil
+			if err := r.forwardToInbound(ctx, forwardConn, r.fallback.targetTag, metadata, trackedClose); err != nil {
 				r.logger.ErrorContext(ctx, "failed to forward to fallback inbound: ", err)
+				access.fail()
 				conn.Close()
+				trackedClose(nil)
 			}
 		} else {
 			// No valid fallback for injected connections
 			r.logger.WarnContext(ctx, "no fallback available for injected connection, closing")
 			conn.Close()
+			access.finish(0)
 		}
 		return
 	}
 
-	// Forward to target inbound
-	// Wrap connection with buffered reader to preserve the HTTP request data
-	wrappedConn := &injectedConn{
-		Conn:   conn,
-		reader: reader,
+	// Check access before the connection is handed over
+	if !matchedRoute.access.authorize(req) {
+		r.logger.InfoContext(ctx, "unauthorized request for route ", matchedRoute.name, " from ", metadata.Source)
+		writeStatusResponse(conn, matchedRoute.access.authStatusCode, http.Header{
+			"WWW-Authenticate": []string{matchedRoute.access.authenticateHeader()},
+		})
+		conn.Close()
+		access.reject()
+		access.finish(matchedRoute.access.authStatusCode)
+		return
 	}
+	release, ok := matchedRoute.access.acquire(metadata.Source.Addr)
+	if !ok {
+		r.logger.InfoContext(ctx, "rate limit exceeded for route ", matchedRoute.name, " from ", metadata.Source)
+		writeStatusResponse(conn, matchedRoute.access.limitStatusCode, nil)
+		conn.Close()
+		access.reject()
+		access.finish(matchedRoute.access.limitStatusCode)
+		return
+	}
+
+	// Forward to target inbound
+	// ReadRequest consumed the request head, so it is written again in front
+	// of the buffered data, with the route's rewrites applied
+	matchedRoute.rewriter.rewrite(req)
+	wrappedConn := newReplayConn(conn, reader, req)
 
 	// Update metadata
 	metadata.Inbound = r.Tag()
 	metadata.InboundType = r.Type()
 
 	r.logger.InfoContext(ctx, "forwarding injected connection to ", matchedRoute.targetInbound)
-	if err := r.forwardToInbound(ctx, wrappedConn, matchedRoute.targetInbound, metadata, nil); err != nil {
+	forwardConn, trackedClose := access.trackConn(wrappedConn, N.AppendClose(onClose, func(error) { release() }))
+	onClose = nil
+	if err := r.forwardToInbound(ctx, forwardConn, matchedRoute.targetInbound, metadata, trackedClose); err != nil {
 		r.logger.ErrorContext(ctx, "failed to forward to ", matchedRoute.targetInbound, ": ", err)
+		access.fail()
 		conn.Close()
+		trackedClose(nil)
 	}
 }
 
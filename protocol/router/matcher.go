@@ -1,6 +1,7 @@
 package router
 
 import (
+	"crypto/tls"
 	"net/http"
 	"path/filepath"
 	"regexp"
@@ -18,6 +19,8 @@ const (
 	maxHostMatchers   = 50
 	maxMethods        = 10
 	maxHeaderPatterns = 50
+	maxSNIMatchers    = 50
+	maxALPNs          = 10
 )
 
 // routeMatcher compiles and evaluates route matching rules
@@ -27,6 +30,8 @@ type routeMatcher struct {
 	hostPatterns  []string // Wildcard patterns like *.example.com
 	headerRules   map[string][]string // Header name -> patterns
 	methods       map[string]bool
+	sniPatterns   []string // Wildcard patterns matched against the ClientHello server name
+	alpn          map[string]bool
 }
 
 // newRouteMatcher creates a compiled matcher from configuration
@@ -87,9 +92,67 @@ func newRouteMatcher(match option.RouteMatch) (*routeMatcher, error) {
 		matcher.methods[strings.ToUpper(method)] = true
 	}
 
+	// Validate and store TLS criteria
+	if len(match.SNI) > maxSNIMatchers {
+		return nil, E.New("too many SNI matchers (max ", maxSNIMatchers, ")")
+	}
+	matcher.sniPatterns = match.SNI
+	if len(match.ALPN) > maxALPNs {
+		return nil, E.New("too many ALPNs (max ", maxALPNs, ")")
+	}
+	if len(match.ALPN) > 0 {
+		matcher.alpn = make(map[string]bool)
+		for _, protocol := range match.ALPN {
+			matcher.alpn[protocol] = true
+		}
+	}
+	if matcher.isTLS() && (len(match.PathPrefix) > 0 || len(match.PathRegex) > 0 || len(match.Host) > 0 || len(match.Header) > 0 || len(match.Method) > 0) {
+		return nil, E.New("sni and alpn can not be combined with HTTP match criteria")
+	}
+
 	return matcher, nil
 }
 
+// isTLS reports whether the route matches on the TLS ClientHello instead of
+// the HTTP request
+func (m *routeMatcher) isTLS() bool {
+	return len(m.sniPatterns) > 0 || len(m.alpn) > 0
+}
+
+// matchesClientHello evaluates if a TLS ClientHello matches this route
+// Returns true if ALL specified criteria match (AND logic)
+func (m *routeMatcher) matchesClientHello(clientHello *tls.ClientHelloInfo) bool {
+	// 1. SNI check (wildcard matching)
+	if len(m.sniPatterns) > 0 {
+		matched := false
+		for _, pattern := range m.sniPatterns {
+			if matchWildcard(pattern, clientHello.ServerName) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	// 2. ALPN check (any protocol offered by the client)
+	if len(m.alpn) > 0 {
+		matched := false
+		for _, protocol := range clientHello.SupportedProtos {
+			if m.alpn[protocol] {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
 // matches evaluates if an HTTP request matches this route
 // Returns true if ALL specified criteria match (AND logic)
 // Evaluation order: method → path prefix → host → path regex → headers
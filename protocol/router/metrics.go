@@ -0,0 +1,213 @@
+package router
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/bufio"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const accessLogKey = "router.access"
+
+// routeMetrics counts requests and traffic of a route or of the fallback
+type routeMetrics struct {
+	requests atomic.Int64
+	active   atomic.Int64
+	rejected atomic.Int64
+	failed   atomic.Int64
+	upload   atomic.Int64
+	download atomic.Int64
+}
+
+func (m *routeMetrics) snapshot(name string, target string) adapter.RouteMetrics {
+	return adapter.RouteMetrics{
+		Name:     name,
+		Target:   target,
+		Requests: m.requests.Load(),
+		Active:   m.active.Load(),
+		Rejected: m.rejected.Load(),
+		Failed:   m.failed.Load(),
+		Upload:   m.upload.Load(),
+		Download: m.download.Load(),
+	}
+}
+
+// RouterMetrics reports the routes in configured order
+func (r *Inbound) RouterMetrics() *adapter.RouterMetrics {
+	table := r.routeTable.Load()
+	routes := append(append([]*compiledRoute(nil), table.routes...), table.tlsRoutes...)
+	sort.Slice(routes, func(i, j int) bool {
+		return routes[i].index < routes[j].index
+	})
+	return &adapter.RouterMetrics{
+		Routes: common.Map(routes, func(it *compiledRoute) adapter.RouteMetrics {
+			return it.metrics.snapshot(it.name, it.targetInbound)
+		}),
+		Fallback: r.fallbackMetrics.snapshot("fallback", r.fallback.targetTag),
+	}
+}
+
+// accessLog follows a request from route matching until it is done, then
+// emits a structured access log event. Requests served by the HTTP server
+// are done when the handler returns, forwarded connections when they close.
+type accessLog struct {
+	inbound  *Inbound
+	ctx      context.Context
+	metrics  *routeMetrics
+	event    *log.RouterMatchEvent
+	start    time.Time
+	upload   atomic.Int64
+	download atomic.Int64
+	detached bool
+	failed   bool
+	finished atomic.Bool
+}
+
+// newAccessLog starts an access log entry for route, or for the fallback if
+// route is nil. The rule index is the position of the route in the
+// configured routes.
+func (r *Inbound) newAccessLog(ctx context.Context, route *compiledRoute) *accessLog {
+	access := &accessLog{
+		inbound: r,
+		ctx:     ctx,
+		start:   time.Now(),
+	}
+	if route != nil {
+		access.metrics = route.metrics
+		access.event = log.NewRouterMatchEvent(route.index, route.name, "route").
+			WithMatched(true).
+			WithTarget(route.targetInbound)
+	} else {
+		access.metrics = &r.fallbackMetrics
+		access.event = log.NewRouterMatchEvent(-1, "fallback", r.fallback.fallbackType).
+			WithTarget(r.fallback.targetTag)
+	}
+	access.event.WithInbound(r.Tag())
+	access.metrics.requests.Add(1)
+	access.metrics.active.Add(1)
+	return access
+}
+
+// startHTTPAccessLog starts an access log entry for a request served by the
+// HTTP server. logAccess finishes it unless the connection is detached.
+func (r *Inbound) startHTTPAccessLog(c *gin.Context, route *compiledRoute) *accessLog {
+	access := r.newAccessLog(c.Request.Context(), route)
+	access.event.
+		WithSource(M.ParseSocksaddr(c.Request.RemoteAddr)).
+		WithRequest(c.Request.Method, c.Request.Host, c.Request.URL.Path)
+	c.Set(accessLogKey, access)
+	return access
+}
+
+func loadAccessLog(c *gin.Context) *accessLog {
+	if value, loaded := c.Get(accessLogKey); loaded {
+		return value.(*accessLog)
+	}
+	return nil
+}
+
+// logAccess is the gin middleware that counts request bodies and finishes
+// access log entries of requests that were not detached
+func (r *Inbound) logAccess(c *gin.Context) {
+	body := &countingBody{ReadCloser: c.Request.Body}
+	c.Request.Body = body
+	c.Next()
+	access := loadAccessLog(c)
+	if access == nil || access.detached {
+		return
+	}
+	var status int
+	if c.Writer.Written() {
+		status = c.Writer.Status()
+	}
+	access.addTraffic(body.n.Load(), int64(max(c.Writer.Size(), 0)))
+	access.finish(status)
+}
+
+// reject marks the request as rejected by access control
+func (a *accessLog) reject() {
+	a.metrics.rejected.Add(1)
+	a.event.Action = "reject"
+}
+
+// fail marks the request as failed to be forwarded
+func (a *accessLog) fail() {
+	if a != nil {
+		a.failed = true
+	}
+}
+
+func (a *accessLog) addTraffic(upload, download int64) {
+	a.upload.Add(upload)
+	a.download.Add(download)
+	a.metrics.upload.Add(upload)
+	a.metrics.download.Add(download)
+}
+
+// trackConn detaches the entry from the HTTP request: traffic of conn is
+// counted as it flows, and the entry is finished when onClose is called.
+func (a *accessLog) trackConn(conn net.Conn, onClose N.CloseHandlerFunc) (net.Conn, N.CloseHandlerFunc) {
+	if a == nil {
+		return conn, onClose
+	}
+	a.detached = true
+	conn = bufio.NewInt64CounterConn(conn,
+		[]*atomic.Int64{&a.upload, &a.metrics.upload},
+		[]*atomic.Int64{&a.download, &a.metrics.download},
+	)
+	return conn, N.AppendClose(onClose, func(error) {
+		a.finish(0)
+	})
+}
+
+// finish emits the access log event. Only the first call has an effect.
+func (a *accessLog) finish(status int) {
+	if a.finished.Swap(true) {
+		return
+	}
+	a.metrics.active.Add(-1)
+	if a.failed || status >= http.StatusInternalServerError {
+		a.metrics.failed.Add(1)
+	}
+	duration := time.Since(a.start)
+	a.event.
+		WithStatus(status).
+		WithTransferStats(a.upload.Load(), a.download.Load()).
+		WithDuration(duration)
+	message := []any{"access: ", a.event.Action, " ", a.event.Rule}
+	if a.event.Target != "" {
+		message = append(message, " => ", a.event.Target)
+	}
+	if a.event.Method != "" {
+		message = append(message, " ", a.event.Method)
+	}
+	message = append(message, " ", a.event.Host, a.event.Path)
+	if status != 0 {
+		message = append(message, " ", status)
+	}
+	message = append(message, " ", duration.Round(time.Millisecond))
+	log.WithRouterMatchEvent(a.inbound.logger, a.ctx, log.LevelInfo, a.event, message...)
+}
+
+// countingBody counts bytes read from a request body
+type countingBody struct {
+	io.ReadCloser
+	n atomic.Int64
+}
+
+func (b *countingBody) Read(p []byte) (n int, err error) {
+	n, err = b.ReadCloser.Read(p)
+	b.n.Add(int64(n))
+	return
+}
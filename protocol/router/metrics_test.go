@@ -0,0 +1,92 @@
+package router
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/adapter/inbound"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testForwardedConn struct {
+	conn    net.Conn
+	onClose N.CloseHandlerFunc
+}
+
+type testTargetInbound struct {
+	adapter.Inbound
+	conns chan testForwardedConn
+}
+
+func (h *testTargetInbound) NewConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
+	h.conns <- testForwardedConn{conn, onClose}
+}
+
+type testInboundManager struct {
+	adapter.InboundManager
+	inbounds map[string]adapter.Inbound
+}
+
+func (m *testInboundManager) Get(tag string) (adapter.Inbound, bool) {
+	inbound, loaded := m.inbounds[tag]
+	return inbound, loaded
+}
+
+func TestInjectedConnectionLifecycle(t *testing.T) {
+	t.Parallel()
+	table, err := compileRoutes([]option.RouteRule{{
+		Name:      "route",
+		Target:    "target-in",
+		Match:     option.RouteMatch{PathPrefix: []string{"/"}},
+		RateLimit: &option.RouteRateLimitOptions{MaxConnections: 1},
+	}}, nil)
+	require.NoError(t, err)
+	route := table.routes[0]
+	target := &testTargetInbound{conns: make(chan testForwardedConn, 1)}
+	router := &Inbound{
+		Adapter:        inbound.NewAdapter(C.TypeRouter, "router"),
+		inboundManager: &testInboundManager{inbounds: map[string]adapter.Inbound{"target-in": target}},
+		logger:         log.NewNOPFactory().Logger(),
+		fallback:       &fallbackHandler{},
+	}
+	router.routeTable.Store(table)
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	go clientConn.Write([]byte("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+
+	var closed atomic.Int32
+	source := M.ParseSocksaddr("192.0.2.1:10000")
+	router.handleInjectedConnection(context.Background(), serverConn, adapter.InboundContext{Source: source}, func(error) {
+		closed.Add(1)
+	})
+	forwarded := <-target.conns
+	_, err = io.ReadFull(forwarded.conn, make([]byte, len("GET / HTTP/1.1\r\n")))
+	require.NoError(t, err)
+
+	// The request stays active and holds its connection slot until the
+	// forwarded connection closes
+	require.Zero(t, closed.Load())
+	require.Equal(t, int64(1), route.metrics.active.Load())
+	_, ok := route.access.acquire(netip.MustParseAddr("192.0.2.1"))
+	require.False(t, ok)
+
+	forwarded.conn.Close()
+	forwarded.onClose(nil)
+	require.Equal(t, int32(1), closed.Load())
+	require.Zero(t, route.metrics.active.Load())
+	require.Equal(t, int64(1), route.metrics.requests.Load())
+	release, ok := route.access.acquire(netip.MustParseAddr("192.0.2.1"))
+	require.True(t, ok)
+	release()
+}
@@ -0,0 +1,99 @@
+package router
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/ntp"
+)
+
+// proxyProtocolAddrsKey is the context key of the client addresses sent in
+// PROXY protocol headers to the upstream
+type proxyProtocolAddrsKey struct{}
+
+type proxyProtocolAddrs struct {
+	source      M.Socksaddr
+	destination M.Socksaddr
+}
+
+// newReverseProxy creates a reverse proxy to upstream that sends requests
+// through transport. The Host header is rewritten to host, or to the upstream
+// host if empty. WebSocket upgrades are passed through by
+// httputil.ReverseProxy.
+func newReverseProxy(logger log.ContextLogger, upstream *url.URL, host string, transport http.RoundTripper, proxyProtocol uint8) *httputil.ReverseProxy {
+	return &httputil.ReverseProxy{
+		Rewrite: func(request *httputil.ProxyRequest) {
+			request.SetURL(upstream)
+			if host != "" {
+				request.Out.Host = host
+			}
+			if proxyProtocol != 0 {
+				addrs := proxyProtocolAddrs{source: M.ParseSocksaddr(request.In.RemoteAddr)}
+				if localAddr, loaded := request.In.Context().Value(http.LocalAddrContextKey).(net.Addr); loaded {
+					addrs.destination = M.SocksaddrFromNet(localAddr)
+				}
+				request.Out = request.Out.WithContext(context.WithValue(request.Out.Context(), proxyProtocolAddrsKey{}, addrs))
+			}
+		},
+		Transport: transport,
+		ErrorHandler: func(writer http.ResponseWriter, request *http.Request, err error) {
+			logger.ErrorContext(request.Context(), "proxy fallback to ", upstream, ": ", err)
+			writer.WriteHeader(http.StatusBadGateway)
+		},
+	}
+}
+
+// newProxyTransport creates the transport of the reverse proxy, dialing
+// through detour. Idle connections must be closed with the inbound.
+//
+// If proxyProtocol is set, each upstream connection starts with a PROXY
+// protocol header of the client. Connections are then not reused, since
+// they belong to a single client.
+func newProxyTransport(ctx context.Context, detour N.Dialer, proxyProtocol uint8) *http.Transport {
+	return &http.Transport{
+		ForceAttemptHTTP2:   proxyProtocol == 0,
+		DisableKeepAlives:   proxyProtocol != 0,
+		TLSHandshakeTimeout: C.TCPTimeout,
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := detour.DialContext(ctx, network, M.ParseSocksaddr(addr))
+			if err != nil || proxyProtocol == 0 {
+				return conn, err
+			}
+			addrs, _ := ctx.Value(proxyProtocolAddrsKey{}).(proxyProtocolAddrs)
+			err = writeProxyProtocolHeader(conn, proxyProtocol, addrs.source, addrs.destination)
+			if err != nil {
+				conn.Close()
+				return nil, E.Cause(err, "write PROXY protocol header")
+			}
+			return conn, nil
+		},
+		TLSClientConfig: &tls.Config{
+			Time:    ntp.TimeFuncFromContext(ctx),
+			RootCAs: adapter.RootPoolFromContext(ctx),
+		},
+	}
+}
+
+// defaultOutboundDialer dials through the default outbound at the time of
+// each dial, since it may not be available when the inbound is created.
+type defaultOutboundDialer struct {
+	outboundManager adapter.OutboundManager
+}
+
+func (d *defaultOutboundDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	return d.outboundManager.Default().DialContext(ctx, network, destination)
+}
+
+func (d *defaultOutboundDialer) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	return d.outboundManager.Default().ListenPacket(ctx, destination)
+}
@@ -0,0 +1,235 @@
+package router
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+// PROXY protocol, see https://www.haproxy.org/download/2.9/doc/proxy-protocol.txt
+var proxyProtocolV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+const (
+	proxyProtocolV1Prefix    = "PROXY "
+	proxyProtocolV1MaxLength = 107
+
+	proxyProtocolV2CommandLocal = 0x20
+	proxyProtocolV2CommandProxy = 0x21
+	proxyProtocolV2FamilyTCP4   = 0x11
+	proxyProtocolV2FamilyTCP6   = 0x21
+)
+
+// proxyProtocolConn carries the addresses of a PROXY protocol header. Reads
+// start with whatever was buffered after the header.
+type proxyProtocolConn struct {
+	injectedConn
+	source      M.Socksaddr
+	destination M.Socksaddr
+}
+
+func (c *proxyProtocolConn) RemoteAddr() net.Addr {
+	if c.source.IsValid() {
+		return c.source.TCPAddr()
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *proxyProtocolConn) LocalAddr() net.Addr {
+	if c.destination.IsValid() {
+		return c.destination.TCPAddr()
+	}
+	return c.Conn.LocalAddr()
+}
+
+// trustsProxyProtocol reports whether addr may send a PROXY protocol header.
+// Only loopback sources are trusted if no prefixes are configured, since a
+// trusted header lets the peer choose the source address.
+func (r *Inbound) trustsProxyProtocol(addr net.Addr) bool {
+	source := M.SocksaddrFromNet(addr).Unwrap().Addr
+	if len(r.proxyProtocolTrusted) == 0 {
+		return source.IsLoopback()
+	}
+	return common.Any(r.proxyProtocolTrusted, func(it netip.Prefix) bool {
+		return it.Contains(source)
+	})
+}
+
+// readProxyProtocol reads the PROXY protocol header that must start conn
+func (r *Inbound) readProxyProtocol(conn net.Conn) (net.Conn, error) {
+	err := conn.SetReadDeadline(time.Now().Add(C.TCPTimeout))
+	if err != nil {
+		return nil, err
+	}
+	proxyConn, err := readProxyProtocolHeader(conn)
+	if err != nil {
+		return nil, err
+	}
+	return proxyConn, conn.SetReadDeadline(time.Time{})
+}
+
+// readProxyProtocolHeader reads a PROXY protocol v1 or v2 header from conn.
+// The returned connection reports the client addresses of the header, or the
+// addresses of conn for LOCAL and UNKNOWN headers.
+func readProxyProtocolHeader(conn net.Conn) (net.Conn, error) {
+	reader := bufio.NewReader(conn)
+	signature, err := reader.Peek(len(proxyProtocolV2Signature))
+	if err != nil {
+		return nil, E.Cause(err, "read PROXY protocol header")
+	}
+	var source, destination M.Socksaddr
+	switch {
+	case bytes.Equal(signature, proxyProtocolV2Signature):
+		source, destination, err = readProxyProtocolV2(reader)
+	case strings.HasPrefix(string(signature), proxyProtocolV1Prefix):
+		source, destination, err = readProxyProtocolV1(reader)
+	default:
+		return nil, E.New("missing PROXY protocol header")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &proxyProtocolConn{
+		injectedConn: injectedConn{Conn: conn, reader: reader},
+		source:       source,
+		destination:  destination,
+	}, nil
+}
+
+func readProxyProtocolV1(reader *bufio.Reader) (source M.Socksaddr, destination M.Socksaddr, err error) {
+	line, err := reader.ReadSlice('\n')
+	if err != nil || len(line) > proxyProtocolV1MaxLength || !bytes.HasSuffix(line, []byte("\r\n")) {
+		return M.Socksaddr{}, M.Socksaddr{}, E.New("invalid PROXY protocol v1 header")
+	}
+	fields := strings.Split(strings.TrimSuffix(string(line), "\r\n"), " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return M.Socksaddr{}, M.Socksaddr{}, E.New("invalid PROXY protocol v1 header: ", strings.TrimSpace(string(line)))
+	}
+	source, err = parseProxyProtocolV1Address(fields[2], fields[4])
+	if err != nil {
+		return
+	}
+	destination, err = parseProxyProtocolV1Address(fields[3], fields[5])
+	return
+}
+
+func parseProxyProtocolV1Address(address string, port string) (M.Socksaddr, error) {
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		return M.Socksaddr{}, E.Cause(err, "invalid PROXY protocol v1 address")
+	}
+	portNumber, err := strconv.ParseUint(port, 10, 16)
+	if err != nil {
+		return M.Socksaddr{}, E.Cause(err, "invalid PROXY protocol v1 port")
+	}
+	return M.SocksaddrFrom(addr, uint16(portNumber)), nil
+}
+
+func readProxyProtocolV2(reader *bufio.Reader) (source M.Socksaddr, destination M.Socksaddr, err error) {
+	var header [16]byte
+	_, err = io.ReadFull(reader, header[:])
+	if err != nil {
+		return
+	}
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:]))
+	_, err = io.ReadFull(reader, payload)
+	if err != nil {
+		return
+	}
+	switch header[12] {
+	case proxyProtocolV2CommandLocal:
+		return
+	case proxyProtocolV2CommandProxy:
+	default:
+		return M.Socksaddr{}, M.Socksaddr{}, E.New("unsupported PROXY protocol v2 command: ", header[12])
+	}
+	var addressLength int
+	switch header[13] {
+	case proxyProtocolV2FamilyTCP4:
+		addressLength = 4
+	case proxyProtocolV2FamilyTCP6:
+		addressLength = 16
+	default:
+		// UDP, UNIX and unspecified families carry no usable TCP address
+		return
+	}
+	if len(payload) < addressLength*2+4 {
+		return M.Socksaddr{}, M.Socksaddr{}, E.New("short PROXY protocol v2 address block")
+	}
+	sourceAddr, _ := netip.AddrFromSlice(payload[:addressLength])
+	destinationAddr, _ := netip.AddrFromSlice(payload[addressLength : addressLength*2])
+	ports := payload[addressLength*2:]
+	source = M.SocksaddrFrom(sourceAddr, binary.BigEndian.Uint16(ports))
+	destination = M.SocksaddrFrom(destinationAddr, binary.BigEndian.Uint16(ports[2:]))
+	return
+}
+
+// writeProxyProtocolHeader writes a PROXY protocol header of version 1 or 2
+// for a TCP connection from source to destination. If source is not an IP
+// address, an UNKNOWN or LOCAL header is written.
+func writeProxyProtocolHeader(writer io.Writer, version uint8, source M.Socksaddr, destination M.Socksaddr) error {
+	source = source.Unwrap()
+	destination = destination.Unwrap()
+	if source.IsIP() && !destination.IsIP() {
+		if source.IsIPv4() {
+			destination = M.SocksaddrFrom(netip.IPv4Unspecified(), 0)
+		} else {
+			destination = M.SocksaddrFrom(netip.IPv6Unspecified(), 0)
+		}
+	}
+	if source.IsIP() && source.IsIPv4() != destination.IsIPv4() {
+		source = M.SocksaddrFrom(netip.AddrFrom16(source.Addr.As16()), source.Port)
+		destination = M.SocksaddrFrom(netip.AddrFrom16(destination.Addr.As16()), destination.Port)
+	}
+	var header []byte
+	switch version {
+	case 1:
+		if !source.IsIP() {
+			header = []byte("PROXY UNKNOWN\r\n")
+			break
+		}
+		family := "TCP4"
+		if !source.IsIPv4() {
+			family = "TCP6"
+		}
+		header = []byte(strings.Join([]string{
+			"PROXY", family,
+			source.Addr.String(), destination.Addr.String(),
+			strconv.Itoa(int(source.Port)), strconv.Itoa(int(destination.Port)),
+		}, " ") + "\r\n")
+	case 2:
+		header = append(header, proxyProtocolV2Signature...)
+		if !source.IsIP() {
+			header = append(header, proxyProtocolV2CommandLocal, 0, 0, 0)
+			break
+		}
+		family := byte(proxyProtocolV2FamilyTCP4)
+		if !source.IsIPv4() {
+			family = proxyProtocolV2FamilyTCP6
+		}
+		sourceAddr := source.Addr.AsSlice()
+		header = append(header, proxyProtocolV2CommandProxy, family)
+		header = binary.BigEndian.AppendUint16(header, uint16(len(sourceAddr)*2+4))
+		header = append(header, sourceAddr...)
+		header = append(header, destination.Addr.AsSlice()...)
+		header = binary.BigEndian.AppendUint16(header, source.Port)
+		header = binary.BigEndian.AppendUint16(header, destination.Port)
+	default:
+		return E.New("unknown PROXY protocol version: ", version)
+	}
+	_, err := writer.Write(header)
+	return err
+}
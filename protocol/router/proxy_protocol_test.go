@@ -0,0 +1,210 @@
+package router
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+// readTestProxyProtocol reads a PROXY protocol header from data followed by a
+// payload, and checks that the payload is still readable
+func readTestProxyProtocol(t *testing.T, data []byte) (*proxyProtocolConn, error) {
+	clientConn, serverConn := net.Pipe()
+	t.Cleanup(func() {
+		clientConn.Close()
+		serverConn.Close()
+	})
+	go func() {
+		clientConn.Write(append(data, "payload"...))
+		clientConn.Close()
+	}()
+	conn, err := readProxyProtocolHeader(serverConn)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	require.Equal(t, "payload", string(payload))
+	return conn.(*proxyProtocolConn), nil
+}
+
+func proxyProtocolV2Header(command byte, family byte, addresses []byte) []byte {
+	header := append([]byte(nil), proxyProtocolV2Signature...)
+	header = append(header, command, family)
+	header = binary.BigEndian.AppendUint16(header, uint16(len(addresses)))
+	return append(header, addresses...)
+}
+
+func TestReadProxyProtocol(t *testing.T) {
+	t.Parallel()
+	tcp4Addresses := []byte{192, 0, 2, 1, 198, 51, 100, 1, 0x30, 0x39, 0x01, 0xbb}
+	tcp6Addresses := append(append(netip.MustParseAddr("2001:db8::1").AsSlice(), netip.MustParseAddr("2001:db8::2").AsSlice()...), 0x30, 0x39, 0x01, 0xbb)
+	for _, testCase := range []struct {
+		name        string
+		header      []byte
+		source      string
+		destination string
+		err         bool
+	}{
+		{
+			name:        "v1 TCP4",
+			header:      []byte("PROXY TCP4 192.0.2.1 198.51.100.1 12345 443\r\n"),
+			source:      "192.0.2.1:12345",
+			destination: "198.51.100.1:443",
+		},
+		{
+			name:        "v1 TCP6",
+			header:      []byte("PROXY TCP6 2001:db8::1 2001:db8::2 12345 443\r\n"),
+			source:      "[2001:db8::1]:12345",
+			destination: "[2001:db8::2]:443",
+		},
+		{
+			name:   "v1 UNKNOWN",
+			header: []byte("PROXY UNKNOWN 192.0.2.1 198.51.100.1 12345 443\r\n"),
+		},
+		{
+			name:   "v1 over-long line",
+			header: []byte("PROXY TCP6 " + strings.Repeat("f", proxyProtocolV1MaxLength) + "\r\n"),
+			err:    true,
+		},
+		{
+			name:   "v1 missing CRLF",
+			header: []byte("PROXY TCP4 192.0.2.1 198.51.100.1 12345 443\n"),
+			err:    true,
+		},
+		{
+			name:   "v1 invalid port",
+			header: []byte("PROXY TCP4 192.0.2.1 198.51.100.1 123456 443\r\n"),
+			err:    true,
+		},
+		{
+			name:   "v2 LOCAL",
+			header: proxyProtocolV2Header(proxyProtocolV2CommandLocal, 0, nil),
+		},
+		{
+			name:        "v2 PROXY TCP4",
+			header:      proxyProtocolV2Header(proxyProtocolV2CommandProxy, proxyProtocolV2FamilyTCP4, tcp4Addresses),
+			source:      "192.0.2.1:12345",
+			destination: "198.51.100.1:443",
+		},
+		{
+			name:        "v2 PROXY TCP6",
+			header:      proxyProtocolV2Header(proxyProtocolV2CommandProxy, proxyProtocolV2FamilyTCP6, tcp6Addresses),
+			source:      "[2001:db8::1]:12345",
+			destination: "[2001:db8::2]:443",
+		},
+		{
+			// UDP over IPv4 carries no usable TCP address
+			name:   "v2 PROXY UDP4",
+			header: proxyProtocolV2Header(proxyProtocolV2CommandProxy, 0x12, tcp4Addresses),
+		},
+		{
+			name:   "v2 short address block",
+			header: proxyProtocolV2Header(proxyProtocolV2CommandProxy, proxyProtocolV2FamilyTCP6, tcp4Addresses),
+			err:    true,
+		},
+		{
+			name:   "v2 unsupported command",
+			header: proxyProtocolV2Header(0x22, proxyProtocolV2FamilyTCP4, tcp4Addresses),
+			err:    true,
+		},
+		{
+			name:   "missing header",
+			header: []byte("GET / HTTP/1.1\r\n\r\n"),
+			err:    true,
+		},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			conn, err := readTestProxyProtocol(t, testCase.header)
+			if testCase.err {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if testCase.source == "" {
+				require.False(t, conn.source.IsValid())
+				require.False(t, conn.destination.IsValid())
+				require.Equal(t, conn.Conn.RemoteAddr(), conn.RemoteAddr())
+				return
+			}
+			require.Equal(t, testCase.source, conn.source.String())
+			require.Equal(t, testCase.destination, conn.destination.String())
+			require.Equal(t, testCase.source, conn.RemoteAddr().String())
+		})
+	}
+}
+
+func TestReadProxyProtocolTruncated(t *testing.T) {
+	t.Parallel()
+	header := proxyProtocolV2Header(proxyProtocolV2CommandProxy, proxyProtocolV2FamilyTCP4, []byte{192, 0, 2, 1, 198, 51, 100, 1, 0x30, 0x39, 0x01, 0xbb})
+	for _, data := range [][]byte{
+		proxyProtocolV2Signature[:6],
+		header[:14],
+		header[:20],
+		[]byte("PROXY TCP4 192.0.2.1"),
+	} {
+		clientConn, serverConn := net.Pipe()
+		go func() {
+			clientConn.Write(data)
+			clientConn.Close()
+		}()
+		_, err := readProxyProtocolHeader(serverConn)
+		require.Error(t, err)
+		serverConn.Close()
+	}
+}
+
+func TestProxyProtocolRoundTrip(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		source      string
+		destination string
+		// Expected addresses after reading, empty for UNKNOWN and LOCAL headers
+		readSource      string
+		readDestination string
+	}{
+		{"192.0.2.1:12345", "198.51.100.1:443", "192.0.2.1:12345", "198.51.100.1:443"},
+		{"[2001:db8::1]:12345", "[2001:db8::2]:443", "[2001:db8::1]:12345", "[2001:db8::2]:443"},
+		// Mixed families are mapped to IPv6
+		{"192.0.2.1:12345", "[2001:db8::2]:443", "[::ffff:192.0.2.1]:12345", "[2001:db8::2]:443"},
+		// A domain destination is replaced by the unspecified address
+		{"192.0.2.1:12345", "example.com:443", "192.0.2.1:12345", "0.0.0.0:0"},
+		{"example.com:12345", "198.51.100.1:443", "", ""},
+	} {
+		for _, version := range []uint8{1, 2} {
+			var buffer bytes.Buffer
+			err := writeProxyProtocolHeader(&buffer, version, M.ParseSocksaddr(testCase.source), M.ParseSocksaddr(testCase.destination))
+			require.NoError(t, err)
+			conn, err := readTestProxyProtocol(t, buffer.Bytes())
+			require.NoError(t, err, "version ", version, ": ", testCase.source)
+			if testCase.readSource == "" {
+				require.False(t, conn.source.IsValid())
+				continue
+			}
+			require.Equal(t, testCase.readSource, conn.source.String(), "version ", version)
+			require.Equal(t, testCase.readDestination, conn.destination.String(), "version ", version)
+		}
+	}
+	require.Error(t, writeProxyProtocolHeader(io.Discard, 3, M.ParseSocksaddr("192.0.2.1:1"), M.ParseSocksaddr("192.0.2.2:2")))
+}
+
+func TestTrustsProxyProtocol(t *testing.T) {
+	t.Parallel()
+	router := &Inbound{}
+	require.True(t, router.trustsProxyProtocol(M.ParseSocksaddr("127.0.0.1:10000").TCPAddr()))
+	require.True(t, router.trustsProxyProtocol(M.ParseSocksaddr("[::1]:10000").TCPAddr()))
+	require.False(t, router.trustsProxyProtocol(M.ParseSocksaddr("192.0.2.1:10000").TCPAddr()))
+
+	router.proxyProtocolTrusted = []netip.Prefix{netip.MustParsePrefix("192.0.2.0/24")}
+	require.True(t, router.trustsProxyProtocol(M.ParseSocksaddr("192.0.2.1:10000").TCPAddr()))
+	require.True(t, router.trustsProxyProtocol(M.ParseSocksaddr("[::ffff:192.0.2.1]:10000").TCPAddr()))
+	require.False(t, router.trustsProxyProtocol(M.ParseSocksaddr("127.0.0.1:10000").TCPAddr()))
+}
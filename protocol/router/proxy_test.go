@@ -0,0 +1,120 @@
+package router
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/sagernet/sing-box/log"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestProxyFallback(t *testing.T, upstream *httptest.Server, host string) *fallbackHandler {
+	upstreamURL, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+	transport := newProxyTransport(t.Context(), N.SystemDialer, 0)
+	return &fallbackHandler{
+		fallbackType:   "proxy",
+		proxy:          newReverseProxy(log.NewNOPFactory().Logger(), upstreamURL, host, transport, 0),
+		proxyTransport: transport,
+	}
+}
+
+func TestProxyFallbackHost(t *testing.T) {
+	t.Parallel()
+	upstream := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		io.WriteString(writer, request.Host)
+	}))
+	defer upstream.Close()
+
+	for _, host := range []string{"", "www.example.com"} {
+		fallback := newTestProxyFallback(t, upstream, host)
+		front := httptest.NewServer(fallback.proxy)
+		response, err := http.Get(front.URL)
+		require.NoError(t, err)
+		content, err := io.ReadAll(response.Body)
+		response.Body.Close()
+		require.NoError(t, err)
+		if host == "" {
+			host = upstream.Listener.Addr().String()
+		}
+		require.Equal(t, host, string(content))
+		front.Close()
+		fallback.close()
+	}
+}
+
+func TestProxyFallbackWebSocket(t *testing.T) {
+	t.Parallel()
+	upstream := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("Upgrade") != "websocket" {
+			writer.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		conn, bufReader, err := http.NewResponseController(writer).Hijack()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		io.WriteString(conn, "HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+		io.Copy(conn, bufReader)
+	}))
+	defer upstream.Close()
+	fallback := newTestProxyFallback(t, upstream, "")
+	defer fallback.close()
+	front := httptest.NewServer(fallback.proxy)
+	defer front.Close()
+
+	conn, err := net.Dial("tcp", front.Listener.Addr().String())
+	require.NoError(t, err)
+	defer conn.Close()
+	_, err = io.WriteString(conn, "GET /ws HTTP/1.1\r\nHost: example.com\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+	require.NoError(t, err)
+	reader := bufio.NewReader(conn)
+	response, err := http.ReadResponse(reader, nil)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusSwitchingProtocols, response.StatusCode)
+
+	_, err = io.WriteString(conn, "hello")
+	require.NoError(t, err)
+	message := make([]byte, len("hello"))
+	_, err = io.ReadFull(reader, message)
+	require.NoError(t, err)
+	require.Equal(t, "hello", string(message))
+}
+
+func TestProxyFallbackClose(t *testing.T) {
+	t.Parallel()
+	closed := make(chan struct{}, 1)
+	upstream := httptest.NewUnstartedServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {}))
+	upstream.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateClosed {
+			closed <- struct{}{}
+		}
+	}
+	upstream.Start()
+	defer upstream.Close()
+	fallback := newTestProxyFallback(t, upstream, "")
+	front := httptest.NewServer(fallback.proxy)
+	defer front.Close()
+
+	response, err := http.Get(front.URL)
+	require.NoError(t, err)
+	io.Copy(io.Discard, response.Body)
+	response.Body.Close()
+
+	// The idle upstream connection is closed with the fallback
+	select {
+	case <-closed:
+		t.Fatal("upstream connection closed before the fallback")
+	default:
+	}
+	fallback.close()
+	<-closed
+}
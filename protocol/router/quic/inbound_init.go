@@ -0,0 +1,65 @@
+package quic
+
+import (
+	stdTLS "crypto/tls"
+	"io"
+	"net"
+	"net/http"
+
+	"github.com/sagernet/quic-go"
+	"github.com/sagernet/quic-go/http3"
+	"github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing-box/protocol/router"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+)
+
+func init() {
+	router.ConfigureHTTP3ListenerFunc = func(udpConn net.PacketConn, handler http.Handler, tlsConfig tls.ServerConfig, logger logger.Logger) (io.Closer, error) {
+		_, err := tlsConfig.Config()
+		if err != nil {
+			return nil, err
+		}
+		// Negotiate h3 on QUIC while leaving the ALPN of the TCP listener
+		// alone, and pick up certificate reloads for every handshake
+		quicTLSConfig := &stdTLS.Config{
+			GetConfigForClient: func(*stdTLS.ClientHelloInfo) (*stdTLS.Config, error) {
+				config, err := tlsConfig.Config()
+				if err != nil {
+					return nil, err
+				}
+				return http3.ConfigureTLSConfig(config), nil
+			},
+		}
+		quicListener, err := quic.ListenEarly(udpConn, quicTLSConfig, &quic.Config{
+			Allow0RTT: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		h3Server := &http3.Server{
+			Handler: handler,
+		}
+
+		go func() {
+			sErr := h3Server.ServeListener(quicListener)
+			udpConn.Close()
+			if sErr != nil && !E.IsClosedOrCanceled(sErr) {
+				logger.Error("http3 server closed: ", sErr)
+			}
+		}()
+
+		return &http3Closer{server: h3Server, listener: quicListener}, nil
+	}
+}
+
+// http3Closer closes the HTTP/3 server with its QUIC listener
+type http3Closer struct {
+	server   *http3.Server
+	listener io.Closer
+}
+
+func (c *http3Closer) Close() error {
+	return E.Errors(c.server.Close(), c.listener.Close())
+}
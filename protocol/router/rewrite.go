@@ -0,0 +1,141 @@
+package router
+
+import (
+	"bytes"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// routeRewriter modifies a matched request before it is forwarded. A nil
+// routeRewriter leaves requests unchanged.
+type routeRewriter struct {
+	stripPrefix   string
+	pathRegex     *regexp.Regexp
+	pathReplace   string
+	host          string
+	removeHeaders []string
+	setHeaders    map[string]string
+	addHeaders    map[string][]string
+}
+
+func newRouteRewriter(stripPrefix string, options *option.RouteRewriteOptions) (*routeRewriter, error) {
+	if stripPrefix == "" && options == nil {
+		return nil, nil
+	}
+	rewriter := &routeRewriter{
+		stripPrefix: stripPrefix,
+	}
+	if options == nil {
+		return rewriter, nil
+	}
+	if options.PathRegex != "" {
+		if len(options.PathRegex) > maxRegexLength {
+			return nil, E.New("rewrite: path_regex too long (max ", maxRegexLength, " chars)")
+		}
+		pathRegex, err := regexp.Compile(options.PathRegex)
+		if err != nil {
+			return nil, E.Cause(err, "rewrite: invalid path_regex: ", options.PathRegex)
+		}
+		rewriter.pathRegex = pathRegex
+		rewriter.pathReplace = options.PathReplace
+	} else if options.PathReplace != "" {
+		return nil, E.New("rewrite: path_replace requires path_regex")
+	}
+	rewriter.host = options.Host
+	for _, name := range options.RemoveHeader {
+		rewriter.removeHeaders = append(rewriter.removeHeaders, http.CanonicalHeaderKey(name))
+	}
+	if len(options.SetHeader) > 0 {
+		rewriter.setHeaders = make(map[string]string, len(options.SetHeader))
+		for name, value := range options.SetHeader {
+			rewriter.setHeaders[http.CanonicalHeaderKey(name)] = value
+		}
+	}
+	if len(options.AddHeader) > 0 {
+		rewriter.addHeaders = make(map[string][]string, len(options.AddHeader))
+		for name, values := range options.AddHeader {
+			rewriter.addHeaders[http.CanonicalHeaderKey(name)] = values
+		}
+	}
+	_, setHost := rewriter.setHeaders["Host"]
+	_, addHost := rewriter.addHeaders["Host"]
+	if setHost || addHost || common.Contains(rewriter.removeHeaders, "Host") {
+		return nil, E.New("rewrite: use host to override the Host header")
+	}
+	return rewriter, nil
+}
+
+// rewrite applies, in order: strip_path_prefix, the path regex, the host
+// override, then header removal, replacement and addition
+func (w *routeRewriter) rewrite(request *http.Request) {
+	if w == nil {
+		return
+	}
+	path := request.URL.Path
+	if w.stripPrefix != "" && strings.HasPrefix(path, w.stripPrefix) {
+		path = "/" + strings.TrimPrefix(strings.TrimPrefix(path, w.stripPrefix), "/")
+	}
+	if w.pathRegex != nil {
+		path = w.pathRegex.ReplaceAllString(path, w.pathReplace)
+		if !strings.HasPrefix(path, "/") {
+			path = "/" + path
+		}
+	}
+	if path != request.URL.Path {
+		request.URL.Path = path
+		request.URL.RawPath = ""
+		request.RequestURI = request.URL.RequestURI()
+	}
+	if w.host != "" {
+		request.Host = w.host
+	}
+	for _, name := range w.removeHeaders {
+		delete(request.Header, name)
+	}
+	for name, value := range w.setHeaders {
+		request.Header[name] = []string{value}
+	}
+	for name, values := range w.addHeaders {
+		request.Header[name] = append(request.Header[name], values...)
+	}
+}
+
+// requestHead serializes the request line and headers of a request read by
+// the HTTP server, so that the target inbound can read the request again
+func requestHead(request *http.Request) []byte {
+	var head bytes.Buffer
+	head.WriteString(request.Method)
+	head.WriteString(" ")
+	head.WriteString(request.RequestURI)
+	head.WriteString(" ")
+	head.WriteString(request.Proto)
+	head.WriteString("\r\n")
+
+	// The server moves these out of the header map
+	if request.Host != "" {
+		head.WriteString("Host: ")
+		head.WriteString(request.Host)
+		head.WriteString("\r\n")
+	}
+	if len(request.TransferEncoding) > 0 {
+		head.WriteString("Transfer-Encoding: ")
+		head.WriteString(strings.Join(request.TransferEncoding, ", "))
+		head.WriteString("\r\n")
+	}
+
+	for key, values := range request.Header {
+		for _, value := range values {
+			head.WriteString(key)
+			head.WriteString(": ")
+			head.WriteString(value)
+			head.WriteString("\r\n")
+		}
+	}
+	head.WriteString("\r\n")
+	return head.Bytes()
+}
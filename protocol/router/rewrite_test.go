@@ -0,0 +1,139 @@
+package router
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json/badoption"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readTestRequest(t *testing.T, raw string) *http.Request {
+	request, err := http.ReadRequest(bufio.NewReader(strings.NewReader(raw)))
+	require.NoError(t, err)
+	return request
+}
+
+func TestRouteRewriter(t *testing.T) {
+	t.Parallel()
+	rewriter, err := newRouteRewriter("/api", &option.RouteRewriteOptions{
+		PathRegex:    `^/v1/(.*)$`,
+		PathReplace:  "/v2/$1",
+		Host:         "backend.internal",
+		RemoveHeader: badoption.Listable[string]{"x-remove"},
+		SetHeader:    map[string]string{"x-set": "set"},
+		AddHeader:    map[string]badoption.Listable[string]{"x-add": {"added"}},
+	})
+	require.NoError(t, err)
+	request := readTestRequest(t, "GET /api/v1/users?id=1 HTTP/1.1\r\nHost: example.com\r\nX-Remove: 1\r\nX-Set: old\r\nX-Add: first\r\n\r\n")
+	rewriter.rewrite(request)
+	require.Equal(t, "/v2/users", request.URL.Path)
+	require.Equal(t, "/v2/users?id=1", request.RequestURI)
+	require.Equal(t, "backend.internal", request.Host)
+	require.Empty(t, request.Header.Values("X-Remove"))
+	require.Equal(t, []string{"set"}, request.Header.Values("X-Set"))
+	require.Equal(t, []string{"first", "added"}, request.Header.Values("X-Add"))
+
+	// The prefix is only stripped at the start of the path
+	rewriter, err = newRouteRewriter("/api", nil)
+	require.NoError(t, err)
+	request = readTestRequest(t, "GET /apiv2 HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	rewriter.rewrite(request)
+	require.Equal(t, "/v2", request.URL.Path)
+	request = readTestRequest(t, "GET /web/api HTTP/1.1\r\nHost: example.com\r\n\r\n")
+	rewriter.rewrite(request)
+	require.Equal(t, "/web/api", request.URL.Path)
+	require.Equal(t, "/web/api", request.RequestURI)
+
+	var noRewriter *routeRewriter
+	noRewriter.rewrite(request)
+	require.Equal(t, "/web/api", request.URL.Path)
+}
+
+func TestRouteRewriterInvalid(t *testing.T) {
+	t.Parallel()
+	for _, options := range []*option.RouteRewriteOptions{
+		{PathRegex: "("},
+		{PathReplace: "/"},
+		{SetHeader: map[string]string{"host": "example.org"}},
+		{RemoveHeader: badoption.Listable[string]{"Host"}},
+	} {
+		_, err := newRouteRewriter("", options)
+		require.Error(t, err)
+	}
+	rewriter, err := newRouteRewriter("", nil)
+	require.NoError(t, err)
+	require.Nil(t, rewriter)
+}
+
+func TestRequestHead(t *testing.T) {
+	t.Parallel()
+	request := readTestRequest(t, "POST /upload HTTP/1.1\r\nHost: example.com\r\nTransfer-Encoding: chunked\r\nX-Custom: a\r\nX-Custom: b\r\n\r\n5\r\nhello\r\n0\r\n\r\n")
+	rewriter, err := newRouteRewriter("", &option.RouteRewriteOptions{Host: "backend.internal"})
+	require.NoError(t, err)
+	rewriter.rewrite(request)
+
+	// The head read again by the target inbound is the rewritten request
+	body, err := io.ReadAll(request.Body)
+	require.NoError(t, err)
+	replayed := readTestRequest(t, string(requestHead(request))+"5\r\nhello\r\n0\r\n\r\n")
+	require.Equal(t, "POST", replayed.Method)
+	require.Equal(t, "/upload", replayed.RequestURI)
+	require.Equal(t, "backend.internal", replayed.Host)
+	require.Equal(t, []string{"chunked"}, replayed.TransferEncoding)
+	require.Equal(t, []string{"a", "b"}, replayed.Header.Values("X-Custom"))
+	replayedBody, err := io.ReadAll(replayed.Body)
+	require.NoError(t, err)
+	require.True(t, bytes.Equal(body, replayedBody))
+}
+
+func TestReplayConn(t *testing.T) {
+	t.Parallel()
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+	defer serverConn.Close()
+	go clientConn.Write([]byte("GET /fallback HTTP/1.1\r\nHost: example.com\r\n\r\npayload"))
+	reader := bufio.NewReader(serverConn)
+	request, err := http.ReadRequest(reader)
+	require.NoError(t, err)
+
+	// The target inbound reads the consumed head again, then the rest
+	replayReader := bufio.NewReader(newReplayConn(serverConn, reader, request))
+	replayed, err := http.ReadRequest(replayReader)
+	require.NoError(t, err)
+	require.Equal(t, "/fallback", replayed.RequestURI)
+	require.Equal(t, "example.com", replayed.Host)
+	payload := make([]byte, len("payload"))
+	_, err = io.ReadFull(replayReader, payload)
+	require.NoError(t, err)
+	require.Equal(t, "payload", string(payload))
+}
+
+func TestReplayConnWriteTo(t *testing.T) {
+	t.Parallel()
+	clientConn, serverConn := net.Pipe()
+	defer serverConn.Close()
+	go func() {
+		clientConn.Write([]byte("GET /fallback HTTP/1.1\r\nHost: example.com\r\n\r\npayload"))
+		clientConn.Close()
+	}()
+	reader := bufio.NewReader(serverConn)
+	request, err := http.ReadRequest(reader)
+	require.NoError(t, err)
+
+	// Copying before the first read still starts with the consumed head
+	var output bytes.Buffer
+	_, err = newReplayConn(serverConn, reader, request).(io.WriterTo).WriteTo(&output)
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(output.String(), "\r\n\r\npayload"))
+	replayed, err := http.ReadRequest(bufio.NewReader(&output))
+	require.NoError(t, err)
+	require.Equal(t, "/fallback", replayed.RequestURI)
+}
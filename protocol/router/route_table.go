@@ -0,0 +1,148 @@
+package router
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/goccy/go-yaml"
+)
+
+// routeTable is an immutable set of compiled routes sorted by priority.
+// Reloading the routes file swaps the whole table, so a request is always
+// matched against a consistent set of routes.
+type routeTable struct {
+	routes    []*compiledRoute
+	tlsRoutes []*compiledRoute // Matched on the ClientHello before TLS termination
+}
+
+// routesFile is the content of routes_path
+type routesFile struct {
+	Routes []option.RouteRule `json:"routes"`
+}
+
+// compileRoutes compiles routeOptions into a new table. Metrics of routes
+// that keep their name are carried over from previous, which may be nil, so
+// names must be unique if previous is used.
+func compileRoutes(routeOptions []option.RouteRule, previous *routeTable) (*routeTable, error) {
+	previousMetrics := make(map[string]*routeMetrics)
+	if previous != nil {
+		for _, route := range append(append([]*compiledRoute(nil), previous.routes...), previous.tlsRoutes...) {
+			previousMetrics[route.name] = route.metrics
+		}
+	}
+	table := &routeTable{}
+	for i, routeOpt := range routeOptions {
+		if routeOpt.Name == "" {
+			return nil, E.New("route #", i, " is missing name")
+		}
+		if routeOpt.Target == "" {
+			return nil, E.New("route ", routeOpt.Name, " is missing target inbound")
+		}
+
+		matcher, err := newRouteMatcher(routeOpt.Match)
+		if err != nil {
+			return nil, E.Cause(err, "compile route ", routeOpt.Name)
+		}
+
+		access, err := newRouteAccess(routeOpt.Auth, routeOpt.RateLimit)
+		if err != nil {
+			return nil, E.Cause(err, "compile route ", routeOpt.Name)
+		}
+		if matcher.isTLS() && access.hasAuth() {
+			return nil, E.New("route ", routeOpt.Name, ": auth can not be used with sni or alpn match")
+		}
+
+		rewriter, err := newRouteRewriter(routeOpt.StripPathPrefix, routeOpt.Rewrite)
+		if err != nil {
+			return nil, E.Cause(err, "compile route ", routeOpt.Name)
+		}
+		if matcher.isTLS() && routeOpt.Rewrite != nil {
+			return nil, E.New("route ", routeOpt.Name, ": rewrite can not be used with sni or alpn match")
+		}
+
+		metrics := previousMetrics[routeOpt.Name]
+		if metrics == nil {
+			metrics = new(routeMetrics)
+		}
+		compiled := &compiledRoute{
+			index:         i,
+			name:          routeOpt.Name,
+			matcher:       matcher,
+			targetInbound: routeOpt.Target,
+			rewriter:      rewriter,
+			priority:      routeOpt.Priority,
+			access:        access,
+			metrics:       metrics,
+		}
+		if matcher.isTLS() {
+			table.tlsRoutes = append(table.tlsRoutes, compiled)
+		} else {
+			table.routes = append(table.routes, compiled)
+		}
+	}
+
+	// Sort routes by priority (higher first), keeping the configured order otherwise
+	sort.SliceStable(table.routes, func(i, j int) bool {
+		return table.routes[i].priority > table.routes[j].priority
+	})
+	sort.SliceStable(table.tlsRoutes, func(i, j int) bool {
+		return table.tlsRoutes[i].priority > table.tlsRoutes[j].priority
+	})
+	return table, nil
+}
+
+// readRoutesFile reads routes from a JSON file, or a YAML file if path has a
+// .yaml or .yml extension
+func readRoutesFile(path string) ([]option.RouteRule, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		content, err = yaml.YAMLToJSON(content)
+		if err != nil {
+			return nil, E.Cause(err, "decode YAML")
+		}
+	}
+	file, err := json.UnmarshalExtended[routesFile](content)
+	if err != nil {
+		return nil, err
+	}
+	return file.Routes, nil
+}
+
+// reloadRoutesFile compiles the inline routes together with the routes of
+// path and swaps them in. On error the current table is kept. Connections
+// that were already forwarded are not affected.
+func (r *Inbound) reloadRoutesFile(path string) error {
+	fileRoutes, err := readRoutesFile(path)
+	if err != nil {
+		return err
+	}
+	routeOptions := append(append([]option.RouteRule(nil), r.inlineRoutes...), fileRoutes...)
+	// Metrics are carried over across reloads by name
+	names := make(map[string]bool)
+	for _, routeOpt := range routeOptions {
+		if routeOpt.Name != "" && names[routeOpt.Name] {
+			return E.New("duplicate route name: ", routeOpt.Name)
+		}
+		names[routeOpt.Name] = true
+	}
+	table, err := compileRoutes(routeOptions, r.routeTable.Load())
+	if err != nil {
+		return err
+	}
+	err = r.checkHTTPVersions(table)
+	if err != nil {
+		return err
+	}
+	r.routeTable.Store(table)
+	return nil
+}
@@ -0,0 +1,167 @@
+package router
+
+import (
+	"context"
+	stdTLS "crypto/tls"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sagernet/sing-box/common/pipelistener"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func routeNames(routes []*compiledRoute) []string {
+	names := make([]string, 0, len(routes))
+	for _, route := range routes {
+		names = append(names, route.name)
+	}
+	return names
+}
+
+func TestCompileRoutes(t *testing.T) {
+	t.Parallel()
+	table, err := compileRoutes([]option.RouteRule{
+		{Name: "api", Target: "api-in", Match: option.RouteMatch{PathPrefix: []string{"/api"}}},
+		{Name: "tls", Target: "tls-in", Match: option.RouteMatch{SNI: []string{"*.example.com"}}},
+		{Name: "admin", Target: "admin-in", Priority: 10, Match: option.RouteMatch{PathPrefix: []string{"/admin"}}},
+		{Name: "web", Target: "web-in", Match: option.RouteMatch{PathPrefix: []string{"/"}}},
+	}, nil)
+	require.NoError(t, err)
+	// Higher priority first, configured order otherwise
+	require.Equal(t, []string{"admin", "api", "web"}, routeNames(table.routes))
+	require.Equal(t, []string{"tls"}, routeNames(table.tlsRoutes))
+
+	// HTTP and TLS routes share the configured order as index
+	require.Equal(t, []int{2, 0, 3}, []int{table.routes[0].index, table.routes[1].index, table.routes[2].index})
+	route := table.matchTLSRoute(&stdTLS.ClientHelloInfo{ServerName: "www.example.com"})
+	require.Equal(t, 1, route.index)
+	require.Equal(t, "tls-in", route.targetInbound)
+	route = table.matchTLSRoute(&stdTLS.ClientHelloInfo{ServerName: "example.org"})
+	require.Nil(t, route)
+
+	// Metrics are carried over by name
+	table.routes[0].metrics.requests.Add(3)
+	reloaded, err := compileRoutes([]option.RouteRule{
+		{Name: "admin", Target: "admin-in", Match: option.RouteMatch{PathPrefix: []string{"/admin2"}}},
+		{Name: "new", Target: "new-in", Match: option.RouteMatch{PathPrefix: []string{"/new"}}},
+	}, table)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), reloaded.routes[0].metrics.requests.Load())
+	require.Zero(t, reloaded.routes[1].metrics.requests.Load())
+}
+
+func TestCompileRoutesInvalid(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name  string
+		route option.RouteRule
+	}{
+		{"missing name", option.RouteRule{Target: "in"}},
+		{"missing target", option.RouteRule{Name: "route"}},
+		{"invalid regex", option.RouteRule{Name: "route", Target: "in", Match: option.RouteMatch{PathRegex: []string{"("}}}},
+		{"tls auth", option.RouteRule{Name: "route", Target: "in", Match: option.RouteMatch{SNI: []string{"example.com"}}, Auth: &option.RouteAuthOptions{BearerTokens: []string{"token"}}}},
+		{"tls rewrite", option.RouteRule{Name: "route", Target: "in", Match: option.RouteMatch{ALPN: []string{"h2"}}, Rewrite: &option.RouteRewriteOptions{Host: "example.org"}}},
+	} {
+		_, err := compileRoutes([]option.RouteRule{testCase.route}, nil)
+		require.Error(t, err, testCase.name)
+	}
+}
+
+func TestReloadRoutesFile(t *testing.T) {
+	t.Parallel()
+	directory := t.TempDir()
+	inbound := &Inbound{
+		inlineRoutes: []option.RouteRule{
+			{Name: "inline", Target: "inline-in", Match: option.RouteMatch{PathPrefix: []string{"/inline"}}},
+		},
+	}
+	jsonPath := filepath.Join(directory, "routes.json")
+	require.NoError(t, os.WriteFile(jsonPath, []byte(`{"routes":[{"name":"file","target":"file-in","match":{"path_prefix":["/file"]}}]}`), 0o644))
+	require.NoError(t, inbound.reloadRoutesFile(jsonPath))
+	table := inbound.routeTable.Load()
+	require.Equal(t, []string{"inline", "file"}, routeNames(table.routes))
+	table.routes[1].metrics.requests.Add(1)
+
+	yamlPath := filepath.Join(directory, "routes.yaml")
+	require.NoError(t, os.WriteFile(yamlPath, []byte(`
+routes:
+  - name: file
+    target: file-in
+    priority: 1
+    match:
+      path_prefix: [/file]
+  - name: tls
+    target: tls-in
+    match:
+      sni: [example.com]
+`), 0o644))
+	require.NoError(t, inbound.reloadRoutesFile(yamlPath))
+	table = inbound.routeTable.Load()
+	require.Equal(t, []string{"file", "inline"}, routeNames(table.routes))
+	require.Equal(t, []string{"tls"}, routeNames(table.tlsRoutes))
+	require.Equal(t, int64(1), table.routes[0].metrics.requests.Load())
+
+	// An invalid file keeps the current table
+	require.NoError(t, os.WriteFile(jsonPath, []byte(`{"routes":[{"name":"broken"}]}`), 0o644))
+	require.Error(t, inbound.reloadRoutesFile(jsonPath))
+	require.Equal(t, table, inbound.routeTable.Load())
+	require.Error(t, inbound.reloadRoutesFile(filepath.Join(directory, "missing.json")))
+	require.Equal(t, table, inbound.routeTable.Load())
+
+	// Metrics are carried over by name, so names must be unique with a routes file
+	require.NoError(t, os.WriteFile(jsonPath, []byte(`{"routes":[{"name":"inline","target":"file-in","match":{"path_prefix":["/file"]}}]}`), 0o644))
+	require.Error(t, inbound.reloadRoutesFile(jsonPath))
+	require.Equal(t, table, inbound.routeTable.Load())
+}
+
+func TestCompileRoutesDuplicateNames(t *testing.T) {
+	t.Parallel()
+	// Inline routes without a routes file may share names
+	table, err := compileRoutes([]option.RouteRule{
+		{Name: "route", Target: "a-in", Match: option.RouteMatch{PathPrefix: []string{"/a"}}},
+		{Name: "route", Target: "b-in", Match: option.RouteMatch{PathPrefix: []string{"/b"}}},
+	}, nil)
+	require.NoError(t, err)
+	require.Len(t, table.routes, 2)
+}
+
+func TestHandleStreamWithoutTLSRoutes(t *testing.T) {
+	t.Parallel()
+	table, err := compileRoutes([]option.RouteRule{
+		{Name: "route", Target: "in", Match: option.RouteMatch{PathPrefix: []string{"/"}}},
+	}, nil)
+	require.NoError(t, err)
+	inbound := &Inbound{httpListener: pipelistener.New(1)}
+	inbound.routeTable.Store(table)
+	clientConn, serverConn := net.Pipe()
+	defer clientConn.Close()
+
+	// The connection is served without waiting for a ClientHello
+	go inbound.handleStream(context.Background(), serverConn)
+	conn, err := inbound.httpListener.Accept()
+	require.NoError(t, err)
+	require.Equal(t, serverConn, conn)
+}
+
+func TestRouterMetricsOrder(t *testing.T) {
+	t.Parallel()
+	table, err := compileRoutes([]option.RouteRule{
+		{Name: "api", Target: "api-in", Match: option.RouteMatch{PathPrefix: []string{"/api"}}},
+		{Name: "tls", Target: "tls-in", Match: option.RouteMatch{SNI: []string{"*.example.com"}}},
+		{Name: "admin", Target: "admin-in", Priority: 10, Match: option.RouteMatch{PathPrefix: []string{"/admin"}}},
+	}, nil)
+	require.NoError(t, err)
+	inbound := &Inbound{fallback: &fallbackHandler{}}
+	inbound.routeTable.Store(table)
+
+	// Routes are reported in configured order, not in matching order
+	var names []string
+	for _, route := range inbound.RouterMetrics().Routes {
+		names = append(names, route.Name)
+	}
+	require.Equal(t, []string{"api", "tls", "admin"}, names)
+}
@@ -0,0 +1,164 @@
+package router
+
+import (
+	"context"
+	stdTLS "crypto/tls"
+	"io"
+	"net"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/sniff"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"golang.org/x/net/http2"
+)
+
+// recordTypeHandshake is the first byte of a TLS handshake record
+const recordTypeHandshake = 0x16
+
+// loopAccept accepts raw connections when TLS routes, TLS termination or a
+// routes file are configured. ClientHellos matching a TLS route are forwarded untouched to the
+// target inbound, everything else is served by the HTTP server.
+func (r *Inbound) loopAccept() {
+	for {
+		conn, err := r.tcpListener.Accept()
+		if err != nil {
+			if !E.IsClosed(err) {
+				r.logger.Error("accept connection: ", err)
+			}
+			return
+		}
+		go r.handleStream(log.ContextWithNewID(r.ctx), conn)
+	}
+}
+
+// handleStream dispatches an accepted connection by its first bytes
+func (r *Inbound) handleStream(ctx context.Context, conn net.Conn) {
+	if r.acceptProxyProtocol && r.trustsProxyProtocol(conn.RemoteAddr()) {
+		proxyConn, err := r.readProxyProtocol(conn)
+		if err != nil {
+			r.logger.ErrorContext(ctx, E.Cause(err, "process connection from ", conn.RemoteAddr()))
+			conn.Close()
+			return
+		}
+		conn = proxyConn
+	}
+	table := r.routeTable.Load()
+	if len(table.tlsRoutes) == 0 && r.tlsConfig == nil {
+		// Nothing to inspect until a reloaded routes file adds TLS routes
+		r.httpListener.Serve(conn)
+		return
+	}
+	conn, clientHello, isTLS := r.peekClientHello(ctx, conn)
+	if clientHello != nil {
+		if route := table.matchTLSRoute(clientHello); route != nil {
+			metadata := adapter.InboundContext{
+				Inbound:     r.Tag(),
+				InboundType: r.Type(),
+				Source:      M.SocksaddrFromNet(conn.RemoteAddr()).Unwrap(),
+				Destination: M.SocksaddrFromNet(conn.LocalAddr()).Unwrap(),
+			}
+			r.forwardTLSRoute(ctx, conn, route, clientHello, metadata, r.trackHijackedConn(conn))
+			return
+		}
+	}
+	if isTLS {
+		if r.tlsConfig == nil {
+			r.logger.DebugContext(ctx, "no TLS route matched for ", conn.RemoteAddr(), " and TLS is not enabled, closing")
+			conn.Close()
+			return
+		}
+		tlsConn, err := tls.ServerHandshake(ctx, conn, r.tlsConfig)
+		if err != nil {
+			r.logger.ErrorContext(ctx, E.Cause(err, "process connection from ", conn.RemoteAddr(), ": TLS handshake"))
+			conn.Close()
+			return
+		}
+		if tlsConn.ConnectionState().NegotiatedProtocol == http2.NextProtoTLS {
+			r.serveHTTP2(ctx, tlsConn)
+			return
+		}
+		conn = tlsConn
+	}
+	r.httpListener.Serve(conn)
+}
+
+// serveHTTP2 serves an HTTP/2 connection negotiated by TLS ALPN. Requests
+// can not be hijacked here, so routes are dispatched per request.
+func (r *Inbound) serveHTTP2(ctx context.Context, conn net.Conn) {
+	onClose := r.trackHijackedConn(conn)
+	defer onClose(nil)
+	r.http2Server.ServeConn(conn, &http2.ServeConnOpts{
+		Context:    ctx,
+		BaseConfig: r.httpServer,
+		Handler:    r.ginEngine,
+	})
+}
+
+// peekClientHello reads the beginning of conn and parses it as a TLS
+// ClientHello. The returned connection replays the peeked bytes.
+func (r *Inbound) peekClientHello(ctx context.Context, conn net.Conn) (net.Conn, *stdTLS.ClientHelloInfo, bool) {
+	var clientHello *stdTLS.ClientHelloInfo
+	buffer := buf.NewPacket()
+	err := sniff.PeekStream(ctx, &adapter.InboundContext{}, conn, nil, buffer, 0, func(ctx context.Context, metadata *adapter.InboundContext, reader io.Reader) error {
+		var err error
+		clientHello, err = sniff.ReadClientHello(ctx, reader)
+		return err
+	})
+	if buffer.IsEmpty() {
+		buffer.Release()
+		return conn, nil, false
+	}
+	isTLS := buffer.Byte(0) == recordTypeHandshake
+	if err != nil && isTLS {
+		r.logger.DebugContext(ctx, "failed to parse ClientHello from ", conn.RemoteAddr(), ": ", err)
+	}
+	return bufio.NewCachedConn(conn, buffer), clientHello, isTLS
+}
+
+// matchTLSRoute returns the first TLS route matching clientHello
+func (t *routeTable) matchTLSRoute(clientHello *stdTLS.ClientHelloInfo) *compiledRoute {
+	for _, route := range t.tlsRoutes {
+		if route.matcher.matchesClientHello(clientHello) {
+			return route
+		}
+	}
+	return nil
+}
+
+// forwardTLSRoute forwards the raw TLS connection to the route's target inbound
+func (r *Inbound) forwardTLSRoute(ctx context.Context, conn net.Conn, route *compiledRoute, clientHello *stdTLS.ClientHelloInfo, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
+	r.logger.InfoContext(ctx, "matched TLS route: ", route.name, " for SNI ", clientHello.ServerName)
+	access := r.newAccessLog(ctx, route)
+	access.event.
+		WithSource(metadata.Source).
+		WithRequest("", clientHello.ServerName, "")
+	release, ok := route.access.acquire(metadata.Source.Addr)
+	if !ok {
+		r.logger.InfoContext(ctx, "rate limit exceeded for TLS route ", route.name, " from ", metadata.Source)
+		conn.Close()
+		if onClose != nil {
+			onClose(nil)
+		}
+		access.reject()
+		access.finish(0)
+		return
+	}
+	onClose = N.AppendClose(onClose, func(error) { release() })
+	conn, onClose = access.trackConn(conn, onClose)
+	metadata.Protocol = C.ProtocolTLS
+	metadata.Domain = clientHello.ServerName
+	if err := r.forwardToInbound(ctx, conn, route.targetInbound, metadata, onClose); err != nil {
+		r.logger.ErrorContext(ctx, "failed to forward TLS connection to ", route.targetInbound, ": ", err)
+		access.fail()
+		conn.Close()
+		onClose(nil)
+	}
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/outbound"
+	"github.com/sagernet/sing-box/common/connpool"
 	"github.com/sagernet/sing-box/common/dialer"
 	"github.com/sagernet/sing-box/common/mux"
 	C "github.com/sagernet/sing-box/constant"
@@ -35,6 +36,7 @@ type Outbound struct {
 	plugin          sip003.Plugin
 	uotClient       *uot.Client
 	multiplexDialer *mux.Client
+	connPool        *connpool.Pool
 }
 
 func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.ShadowsocksOutboundOptions) (adapter.Outbound, error) {
@@ -48,12 +50,17 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 	if err != nil {
 		return nil, err
 	}
+	outboundDialer, connPool, err := connpool.NewDialer(ctx, logger, outboundDialer, options.ServerOptions.Build(), options.DialerOptions)
+	if err != nil {
+		return nil, err
+	}
 	outbound := &Outbound{
 		Adapter:    outbound.NewAdapterWithDialerOptions(C.TypeShadowsocks, tag, options.Network.Build(), options.DialerOptions),
 		logger:     logger,
 		dialer:     outboundDialer,
 		method:     method,
 		serverAddr: options.ServerOptions.Build(),
+		connPool:   connPool,
 	}
 	if options.Plugin != "" {
 		outbound.plugin, err = sip003.CreatePlugin(ctx, options.Plugin, options.PluginOptions, router, outbound.dialer, outbound.serverAddr)
@@ -128,10 +135,20 @@ func (h *Outbound) InterfaceUpdated() {
 	if h.multiplexDialer != nil {
 		h.multiplexDialer.Reset()
 	}
+	if h.connPool != nil {
+		h.connPool.Reset()
+	}
 }
 
 func (h *Outbound) Close() error {
-	return common.Close(common.PtrOrNil(h.multiplexDialer))
+	return common.Close(common.PtrOrNil(h.multiplexDialer), common.PtrOrNil(h.connPool))
+}
+
+func (h *Outbound) ConnectionPool() adapter.ConnectionPool {
+	if h.connPool == nil {
+		return nil
+	}
+	return h.connPool
 }
 
 var _ N.Dialer = (*shadowsocksDialer)(nil)
@@ -3,6 +3,7 @@ package trojan
 import (
 	"context"
 	"net"
+	"net/http"
 	"os"
 
 	"github.com/sagernet/sing-box/adapter"
@@ -29,6 +30,8 @@ func RegisterInbound(registry *inbound.Registry) {
 
 var _ adapter.TCPInjectableInbound = (*Inbound)(nil)
 
+var _ adapter.HTTPInjectableInbound = (*Inbound)(nil)
+
 type Inbound struct {
 	inbound.Adapter
 	router                   adapter.ConnectionRouterEx
@@ -158,6 +161,14 @@ func (h *Inbound) Close() error {
 	)
 }
 
+func (h *Inbound) HTTPHandler(protoMajor int) (http.Handler, bool) {
+	transport, isHTTP := h.transport.(adapter.V2RayHTTPServerTransport)
+	if !isHTTP || !transport.SupportsHTTPVersion(protoMajor) {
+		return nil, false
+	}
+	return transport, true
+}
+
 func (h *Inbound) NewConnectionEx(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, onClose N.CloseHandlerFunc) {
 	if h.tlsConfig != nil && h.transport == nil {
 		tlsConn, err := tls.ServerHandshake(ctx, conn, h.tlsConfig)
@@ -6,6 +6,7 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/outbound"
+	"github.com/sagernet/sing-box/common/connpool"
 	"github.com/sagernet/sing-box/common/dialer"
 	"github.com/sagernet/sing-box/common/mux"
 	"github.com/sagernet/sing-box/common/tls"
@@ -35,6 +36,7 @@ type Outbound struct {
 	multiplexDialer *mux.Client
 	tlsConfig       tls.Config
 	transport       adapter.V2RayClientTransport
+	connPool        *connpool.Pool
 }
 
 func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.TrojanOutboundOptions) (adapter.Outbound, error) {
@@ -61,6 +63,10 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 			return nil, E.Cause(err, "create client transport: ", options.Transport.Type)
 		}
 	}
+	outbound.connPool, err = connpool.New(ctx, logger, options.DialerOptions, (*trojanDialer)(outbound).createBaseConnection)
+	if err != nil {
+		return nil, err
+	}
 	outbound.multiplexDialer, err = mux.NewClientWithOptions((*trojanDialer)(outbound), logger, common.PtrValueOrDefault(options.Multiplex))
 	if err != nil {
 		return nil, err
@@ -105,18 +111,26 @@ func (h *Outbound) InterfaceUpdated() {
 	if h.multiplexDialer != nil {
 		h.multiplexDialer.Reset()
 	}
+	if h.connPool != nil {
+		h.connPool.Reset()
+	}
 }
 
 func (h *Outbound) Close() error {
-	return common.Close(common.PtrOrNil(h.multiplexDialer), h.transport)
+	return common.Close(common.PtrOrNil(h.multiplexDialer), h.transport, common.PtrOrNil(h.connPool))
+}
+
+func (h *Outbound) ConnectionPool() adapter.ConnectionPool {
+	if h.connPool == nil {
+		return nil
+	}
+	return h.connPool
 }
 
 type trojanDialer Outbound
 
-func (h *trojanDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
-	ctx, metadata := adapter.ExtendContext(ctx)
-	metadata.Outbound = h.Tag()
-	metadata.Destination = destination
+// createBaseConnection creates a TCP+TLS connection (no Trojan handshake yet)
+func (h *trojanDialer) createBaseConnection(ctx context.Context) (net.Conn, error) {
 	var conn net.Conn
 	var err error
 	if h.transport != nil {
@@ -131,6 +145,25 @@ func (h *trojanDialer) DialContext(ctx context.Context, network string, destinat
 		common.Close(conn)
 		return nil, err
 	}
+	return conn, nil
+}
+
+// dialBaseConnection takes a pre-dialed connection from the pool if enabled
+func (h *trojanDialer) dialBaseConnection(ctx context.Context) (net.Conn, error) {
+	if h.connPool != nil {
+		return h.connPool.GetConn(ctx)
+	}
+	return h.createBaseConnection(ctx)
+}
+
+func (h *trojanDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	ctx, metadata := adapter.ExtendContext(ctx)
+	metadata.Outbound = h.Tag()
+	metadata.Destination = destination
+	conn, err := h.dialBaseConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
 	switch N.NetworkName(network) {
 	case N.NetworkTCP:
 		return trojan.NewClientConn(conn, h.key, destination), nil
@@ -0,0 +1,59 @@
+package trojan
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// TestOutboundDial tests that the handshake reaches the server without a
+// connection pool
+func TestOutboundDial(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	received := make(chan int, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		n, _ := conn.Read(make([]byte, 1024))
+		received <- n
+	}()
+	serverAddr := M.SocksaddrFromNet(listener.Addr())
+	outbound, err := NewOutbound(context.Background(), nil, log.NewNOPFactory().Logger(), "test", option.TrojanOutboundOptions{
+		ServerOptions: option.ServerOptions{Server: serverAddr.AddrString(), ServerPort: serverAddr.Port},
+		Password:      "password",
+	})
+	if err != nil {
+		t.Fatalf("NewOutbound failed: %v", err)
+	}
+	defer outbound.(*Outbound).Close()
+	conn, err := outbound.DialContext(context.Background(), N.NetworkTCP, M.ParseSocksaddr("example.com:80"))
+	if err != nil {
+		t.Fatalf("DialContext failed: %v", err)
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte("request"))
+	if err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	select {
+	case n := <-received:
+		if n == 0 {
+			t.Error("Expected the handshake to reach the server")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the server to receive the handshake")
+	}
+}
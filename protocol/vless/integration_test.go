@@ -1,14 +1,11 @@
 package vless
 
 import (
-	"context"
 	"encoding/json"
-	"net"
 	"testing"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
-	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
 )
 
@@ -17,26 +14,6 @@ type mockRouter struct {
 	adapter.Router
 }
 
-// mockContextLogger implements log.ContextLogger for testing
-type mockContextLogger struct {
-	log.ContextLogger
-}
-
-func (m *mockContextLogger) Trace(args ...any)                             {}
-func (m *mockContextLogger) Debug(args ...any)                             {}
-func (m *mockContextLogger) Info(args ...any)                              {}
-func (m *mockContextLogger) Warn(args ...any)                              {}
-func (m *mockContextLogger) Error(args ...any)                             {}
-func (m *mockContextLogger) Fatal(args ...any)                             {}
-func (m *mockContextLogger) Panic(args ...any)                             {}
-func (m *mockContextLogger) TraceContext(ctx context.Context, args ...any) {}
-func (m *mockContextLogger) DebugContext(ctx context.Context, args ...any) {}
-func (m *mockContextLogger) InfoContext(ctx context.Context, args ...any)  {}
-func (m *mockContextLogger) WarnContext(ctx context.Context, args ...any)  {}
-func (m *mockContextLogger) ErrorContext(ctx context.Context, args ...any) {}
-func (m *mockContextLogger) FatalContext(ctx context.Context, args ...any) {}
-func (m *mockContextLogger) PanicContext(ctx context.Context, args ...any) {}
-
 // TestConnectionPoolConfiguration tests that the configuration is properly parsed and pool is initialized
 func TestConnectionPoolConfiguration(t *testing.T) {
 	configJSON := `{
@@ -194,34 +171,3 @@ func TestConnectionPoolWithMultiplex(t *testing.T) {
 		t.Error("Expected Multiplex to be enabled")
 	}
 }
-
-// TestConnectionPoolDefaults tests that default values are applied correctly
-func TestConnectionPoolDefaults(t *testing.T) {
-	ctx := context.Background()
-	createCount := 0
-
-	config := ConnectionPoolConfig{
-		// Only set required fields, let defaults apply
-		CreateConn: func(ctx context.Context) (net.Conn, error) {
-			createCount++
-			return newMockConn(), nil
-		},
-		Logger: &mockContextLogger{},
-	}
-
-	pool := NewConnectionPool(ctx, config)
-	defer pool.Close()
-
-	// Verify defaults were applied
-	if pool.checkInterval != 30*time.Second {
-		t.Errorf("Expected default checkInterval=30s, got %v", pool.checkInterval)
-	}
-
-	if pool.idleTimeout != 5*time.Minute {
-		t.Errorf("Expected default idleTimeout=5m, got %v", pool.idleTimeout)
-	}
-
-	if pool.ensureCreateRate != 1 {
-		t.Errorf("Expected default ensureCreateRate=1, got %d", pool.ensureCreateRate)
-	}
-}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/outbound"
+	"github.com/sagernet/sing-box/common/connpool"
 	"github.com/sagernet/sing-box/common/dialer"
 	"github.com/sagernet/sing-box/common/mux"
 	"github.com/sagernet/sing-box/common/tls"
@@ -38,7 +39,7 @@ type Outbound struct {
 	transport       adapter.V2RayClientTransport
 	packetAddr      bool
 	xudp            bool
-	connPool        *ConnectionPool
+	connPool        *connpool.Pool
 }
 
 func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.VLESSOutboundOptions) (adapter.Outbound, error) {
@@ -82,30 +83,10 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 		return nil, err
 	}
 
-	// Initialize connection pool if configured
-	if options.ConnectionPool != nil {
-		// Validate: TCP Fast Open is incompatible with connection pooling
-		if options.DialerOptions.TCPFastOpen {
-			return nil, E.New("tcp_fast_open is not supported with connection_pool")
-		}
-
-		poolConfig := ConnectionPoolConfig{
-			EnsureIdle:       options.ConnectionPool.EnsureIdleSession,
-			EnsureCreateRate: options.ConnectionPool.EnsureIdleSessionCreateRate,
-			MinIdle:          options.ConnectionPool.MinIdleSession,
-			MinIdleForAge:    options.ConnectionPool.MinIdleSessionForAge,
-			CheckInterval:    options.ConnectionPool.IdleSessionCheckInterval.Build(),
-			IdleTimeout:      options.ConnectionPool.IdleSessionTimeout.Build(),
-			MaxLifetime:      options.ConnectionPool.MaxConnectionLifetime.Build(),
-			LifetimeJitter:   options.ConnectionPool.ConnectionLifetimeJitter.Build(),
-			Heartbeat:        options.ConnectionPool.Heartbeat.Build(),
-			CreateConn:       (*vlessDialer)(outbound).createBaseConnection,
-			Logger:           logger,
-		}
-
-		outbound.connPool = NewConnectionPool(ctx, poolConfig)
+	outbound.connPool, err = connpool.New(ctx, logger, options.DialerOptions, (*vlessDialer)(outbound).createBaseConnection)
+	if err != nil {
+		return nil, err
 	}
-
 	outbound.multiplexDialer, err = mux.NewClientWithOptions((*vlessDialer)(outbound), logger, common.PtrValueOrDefault(options.Multiplex))
 	if err != nil {
 		return nil, err
@@ -156,7 +137,14 @@ func (h *Outbound) InterfaceUpdated() {
 }
 
 func (h *Outbound) Close() error {
-	return common.Close(common.PtrOrNil(h.multiplexDialer), h.transport, h.connPool)
+	return common.Close(common.PtrOrNil(h.multiplexDialer), h.transport, common.PtrOrNil(h.connPool))
+}
+
+func (h *Outbound) ConnectionPool() adapter.ConnectionPool {
+	if h.connPool == nil {
+		return nil
+	}
+	return h.connPool
 }
 
 type vlessDialer Outbound
@@ -165,7 +153,6 @@ type vlessDialer Outbound
 func (h *vlessDialer) createBaseConnection(ctx context.Context) (net.Conn, error) {
 	var conn net.Conn
 	var err error
-
 	if h.transport != nil {
 		conn, err = h.transport.DialContext(ctx)
 	} else {
@@ -174,29 +161,29 @@ func (h *vlessDialer) createBaseConnection(ctx context.Context) (net.Conn, error
 			conn, err = tls.ClientHandshake(ctx, conn, h.tlsConfig)
 		}
 	}
+	if err != nil {
+		common.Close(conn)
+		return nil, err
+	}
+	return conn, nil
+}
 
-	return conn, err
+// dialBaseConnection takes a pre-dialed connection from the pool if enabled
+func (h *vlessDialer) dialBaseConnection(ctx context.Context) (net.Conn, error) {
+	if h.connPool != nil {
+		return h.connPool.GetConn(ctx)
+	}
+	return h.createBaseConnection(ctx)
 }
 
 func (h *vlessDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
 	ctx, metadata := adapter.ExtendContext(ctx)
 	metadata.Outbound = h.Tag()
 	metadata.Destination = destination
-	var conn net.Conn
-	var err error
-
-	// Use pool if available
-	if h.connPool != nil {
-		conn, err = h.connPool.GetConn(ctx)
-	} else {
-		conn, err = h.createBaseConnection(ctx)
-	}
-
+	conn, err := h.dialBaseConnection(ctx)
 	if err != nil {
 		return nil, err
 	}
-
-	// Perform VLESS protocol handshake
 	switch N.NetworkName(network) {
 	case N.NetworkTCP:
 		h.logger.InfoContext(ctx, "outbound connection to ", destination)
@@ -227,18 +214,8 @@ func (h *vlessDialer) ListenPacket(ctx context.Context, destination M.Socksaddr)
 	ctx, metadata := adapter.ExtendContext(ctx)
 	metadata.Outbound = h.Tag()
 	metadata.Destination = destination
-	var conn net.Conn
-	var err error
-	if h.transport != nil {
-		conn, err = h.transport.DialContext(ctx)
-	} else {
-		conn, err = h.dialer.DialContext(ctx, N.NetworkTCP, h.serverAddr)
-		if err == nil && h.tlsConfig != nil {
-			conn, err = tls.ClientHandshake(ctx, conn, h.tlsConfig)
-		}
-	}
+	conn, err := h.dialBaseConnection(ctx)
 	if err != nil {
-		common.Close(conn)
 		return nil, err
 	}
 	if h.xudp {
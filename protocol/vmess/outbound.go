@@ -6,6 +6,7 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/adapter/outbound"
+	"github.com/sagernet/sing-box/common/connpool"
 	"github.com/sagernet/sing-box/common/dialer"
 	"github.com/sagernet/sing-box/common/mux"
 	"github.com/sagernet/sing-box/common/tls"
@@ -36,6 +37,7 @@ type Outbound struct {
 	multiplexDialer *mux.Client
 	tlsConfig       tls.Config
 	transport       adapter.V2RayClientTransport
+	connPool        *connpool.Pool
 	packetAddr      bool
 	xudp            bool
 }
@@ -63,6 +65,10 @@ func NewOutbound(ctx context.Context, router adapter.Router, logger log.ContextL
 			return nil, E.Cause(err, "create client transport: ", options.Transport.Type)
 		}
 	}
+	outbound.connPool, err = connpool.New(ctx, logger, options.DialerOptions, (*vmessDialer)(outbound).createBaseConnection)
+	if err != nil {
+		return nil, err
+	}
 	outbound.multiplexDialer, err = mux.NewClientWithOptions((*vmessDialer)(outbound), logger, common.PtrValueOrDefault(options.Multiplex))
 	if err != nil {
 		return nil, err
@@ -108,10 +114,20 @@ func (h *Outbound) InterfaceUpdated() {
 	if h.multiplexDialer != nil {
 		h.multiplexDialer.Reset()
 	}
+	if h.connPool != nil {
+		h.connPool.Reset()
+	}
 }
 
 func (h *Outbound) Close() error {
-	return common.Close(common.PtrOrNil(h.multiplexDialer), h.transport)
+	return common.Close(common.PtrOrNil(h.multiplexDialer), h.transport, common.PtrOrNil(h.connPool))
+}
+
+func (h *Outbound) ConnectionPool() adapter.ConnectionPool {
+	if h.connPool == nil {
+		return nil
+	}
+	return h.connPool
 }
 
 func (h *Outbound) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
@@ -146,10 +162,8 @@ func (h *Outbound) ListenPacket(ctx context.Context, destination M.Socksaddr) (n
 
 type vmessDialer Outbound
 
-func (h *vmessDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
-	ctx, metadata := adapter.ExtendContext(ctx)
-	metadata.Outbound = h.Tag()
-	metadata.Destination = destination
+// createBaseConnection creates a TCP+TLS connection (no VMess handshake yet)
+func (h *vmessDialer) createBaseConnection(ctx context.Context) (net.Conn, error) {
 	var conn net.Conn
 	var err error
 	if h.transport != nil {
@@ -164,6 +178,25 @@ func (h *vmessDialer) DialContext(ctx context.Context, network string, destinati
 		common.Close(conn)
 		return nil, err
 	}
+	return conn, nil
+}
+
+// dialBaseConnection takes a pre-dialed connection from the pool if enabled
+func (h *vmessDialer) dialBaseConnection(ctx context.Context) (net.Conn, error) {
+	if h.connPool != nil {
+		return h.connPool.GetConn(ctx)
+	}
+	return h.createBaseConnection(ctx)
+}
+
+func (h *vmessDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	ctx, metadata := adapter.ExtendContext(ctx)
+	metadata.Outbound = h.Tag()
+	metadata.Destination = destination
+	conn, err := h.dialBaseConnection(ctx)
+	if err != nil {
+		return nil, err
+	}
 	switch N.NetworkName(network) {
 	case N.NetworkTCP:
 		return h.client.DialEarlyConn(conn, destination), nil
@@ -178,16 +211,7 @@ func (h *vmessDialer) ListenPacket(ctx context.Context, destination M.Socksaddr)
 	ctx, metadata := adapter.ExtendContext(ctx)
 	metadata.Outbound = h.Tag()
 	metadata.Destination = destination
-	var conn net.Conn
-	var err error
-	if h.transport != nil {
-		conn, err = h.transport.DialContext(ctx)
-	} else {
-		conn, err = h.dialer.DialContext(ctx, N.NetworkTCP, h.serverAddr)
-		if err == nil && h.tlsConfig != nil {
-			conn, err = tls.ClientHandshake(ctx, conn, h.tlsConfig)
-		}
-	}
+	conn, err := h.dialBaseConnection(ctx)
 	if err != nil {
 		return nil, err
 	}
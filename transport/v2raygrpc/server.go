@@ -3,6 +3,7 @@ package v2raygrpc
 import (
 	"context"
 	"net"
+	"net/http"
 	"os"
 	"strings"
 	"time"
@@ -23,7 +24,7 @@ import (
 	"google.golang.org/grpc/peer"
 )
 
-var _ adapter.V2RayServerTransport = (*Server)(nil)
+var _ adapter.V2RayHTTPServerTransport = (*Server)(nil)
 
 type Server struct {
 	ctx     context.Context
@@ -79,6 +80,17 @@ func (s *Server) Tun(server GunService_TunServer) error {
 func (s *Server) mustEmbedUnimplementedGunServiceServer() {
 }
 
+// ServeHTTP serves gRPC requests accepted by another HTTP/2 server
+func (s *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	s.server.ServeHTTP(writer, request)
+}
+
+// SupportsHTTPVersion reports true for HTTP/2 only, grpc-go rejects other
+// versions
+func (s *Server) SupportsHTTPVersion(protoMajor int) bool {
+	return protoMajor == 2
+}
+
 func (s *Server) Network() []string {
 	return []string{N.NetworkTCP}
 }
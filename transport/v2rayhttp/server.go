@@ -27,7 +27,7 @@ import (
 	"golang.org/x/net/http2/h2c"
 )
 
-var _ adapter.V2RayServerTransport = (*Server)(nil)
+var _ adapter.V2RayHTTPServerTransport = (*Server)(nil)
 
 type Server struct {
 	ctx        context.Context
@@ -156,6 +156,12 @@ func (s *Server) invalidRequest(writer http.ResponseWriter, request *http.Reques
 	s.logger.ErrorContext(request.Context(), E.Cause(err, "process connection from ", request.RemoteAddr))
 }
 
+// SupportsHTTPVersion reports true for HTTP/2 and HTTP/3, whose streams are
+// served without hijacking
+func (s *Server) SupportsHTTPVersion(protoMajor int) bool {
+	return protoMajor == 2 || protoMajor == 3
+}
+
 func (s *Server) Network() []string {
 	return []string{N.NetworkTCP}
 }
@@ -17,6 +17,7 @@ import (
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common/buf"
 	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 
@@ -37,6 +38,12 @@ type Client struct {
 func NewClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, options option.V2RayXHTTPOptions, tlsConfig tls.Config) (*Client, error) {
 	options = *normalizeConfig(&options)
 
+	switch options.Mode {
+	case modeAuto, modePacketUp, modeStreamUp, modeStreamOne:
+	default:
+		return nil, E.New("unsupported xhttp mode: ", options.Mode)
+	}
+
 	client := &Client{
 		ctx:        ctx,
 		dialer:     dialer,
@@ -46,7 +53,7 @@ func NewClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, opt
 	}
 
 	// Create Xmux manager
-	client.xmuxMgr = NewXmuxManager(options.Xmux, nil, func() *XmuxClient {
+	client.xmuxMgr = NewXmuxManager(options.Xmux, logger.NOP(), func() *XmuxClient {
 		return &XmuxClient{
 			httpClient: client.createHTTPClient(),
 		}
@@ -88,12 +95,6 @@ func (c *Client) createHTTPClient() *http.Client {
 }
 
 func (c *Client) DialContext(ctx context.Context) (net.Conn, error) {
-	// Generate session UUID
-	sessionID := c.generateSessionID()
-
-	// Build request URL
-	requestURL := c.buildRequestURL(sessionID)
-
 	// Get Xmux client from pool
 	xmuxClient := c.xmuxMgr.GetXmuxClient(ctx)
 	xmuxClient.OpenUsage.Add(1)
@@ -113,15 +114,114 @@ func (c *Client) DialContext(ctx context.Context) (net.Conn, error) {
 		},
 	}
 
-	// Start GET request for downloading
-	go c.handleDownload(ctx, requestURL, downloadWriter, xmuxClient)
+	switch c.config.Mode {
+	case modeStreamOne:
+		// A single POST request without session carries both directions
+		go c.handleStreamOne(ctx, c.buildRequestURL(""), uploadReader, downloadWriter, xmuxClient)
+	case modeStreamUp:
+		requestURL := c.buildRequestURL(c.generateSessionID())
+
+		// Start GET request for downloading
+		go c.handleDownload(ctx, requestURL, downloadWriter, xmuxClient)
 
-	// Start POST goroutine for uploading (packet-up mode)
-	go c.handleUpload(ctx, requestURL, uploadReader, xmuxClient)
+		// Start a single streaming POST request for uploading
+		go c.handleStreamUpload(ctx, requestURL, uploadReader, xmuxClient)
+	default:
+		requestURL := c.buildRequestURL(c.generateSessionID())
+
+		// Start GET request for downloading
+		go c.handleDownload(ctx, requestURL, downloadWriter, xmuxClient)
+
+		// Start POST goroutine for uploading (packet-up mode)
+		go c.handleUpload(ctx, requestURL, uploadReader, xmuxClient)
+	}
 
 	return conn, nil
 }
 
+func (c *Client) handleStreamOne(ctx context.Context, requestURL string, reader *io.PipeReader, writer *io.PipeWriter, xmuxClient *XmuxClient) {
+	defer writer.Close()
+
+	// Create POST request streaming the upload
+	req, err := http.NewRequestWithContext(ctx, "POST", c.addPaddingParameter(requestURL), reader)
+	if err != nil {
+		err = E.Cause(err, "failed to create POST request")
+		reader.CloseWithError(err)
+		writer.CloseWithError(err)
+		return
+	}
+
+	// Add custom headers
+	for key, values := range c.config.Headers.Build() {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	// Decrement request counter
+	xmuxClient.LeftRequests.Add(-1)
+
+	// Send request
+	resp, err := xmuxClient.httpClient.Do(req)
+	if err != nil {
+		err = E.Cause(err, "failed to send POST request")
+		reader.CloseWithError(err)
+		writer.CloseWithError(err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		err = E.New("unexpected status code: ", resp.StatusCode)
+		reader.CloseWithError(err)
+		writer.CloseWithError(err)
+		return
+	}
+
+	// Copy response body to writer
+	_, err = io.Copy(writer, resp.Body)
+	if err != nil {
+		writer.CloseWithError(E.Cause(err, "failed to read response"))
+		return
+	}
+}
+
+func (c *Client) handleStreamUpload(ctx context.Context, baseURL string, reader *io.PipeReader, xmuxClient *XmuxClient) {
+	// Create POST request streaming the upload
+	req, err := http.NewRequestWithContext(ctx, "POST", c.addPaddingParameter(baseURL), reader)
+	if err != nil {
+		reader.CloseWithError(E.Cause(err, "failed to create POST request"))
+		return
+	}
+
+	// Add custom headers
+	for key, values := range c.config.Headers.Build() {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	// Decrement request counter
+	xmuxClient.LeftRequests.Add(-1)
+
+	// Send request
+	resp, err := xmuxClient.httpClient.Do(req)
+	if err != nil {
+		reader.CloseWithError(E.Cause(err, "failed to send POST request"))
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		reader.CloseWithError(E.New("unexpected POST status: ", resp.StatusCode))
+		return
+	}
+
+	// The server ends the response with the session, closing the response
+	// early would abort the upload over HTTP/1.1
+	_, _ = io.Copy(io.Discard, resp.Body)
+}
+
 func (c *Client) handleDownload(ctx context.Context, baseURL string, writer *io.PipeWriter, xmuxClient *XmuxClient) {
 	defer writer.Close()
 
@@ -175,10 +275,12 @@ func (c *Client) handleUpload(ctx context.Context, baseURL string, reader *io.Pi
 	maxChunkSize := int(getNormalizedValue(c.config.ScMaxEachPostBytes))
 
 	for {
-		// Read chunk from upload pipe
-		chunk := buf.New()
-		_, err := chunk.ReadFullFrom(reader, maxChunkSize)
+		// Read chunk from upload pipe, sending what is available up to the
+		// maximum size of a POST
+		chunk := buf.NewSize(maxChunkSize)
+		_, err := chunk.ReadOnceFrom(reader)
 		if err != nil {
+			chunk.Release()
 			if err != io.EOF {
 				reader.CloseWithError(E.Cause(err, "failed to read upload data"))
 			}
@@ -209,7 +311,7 @@ func (c *Client) handleUpload(ctx context.Context, baseURL string, reader *io.Pi
 		}
 
 		// Send POST request
-		go func(data *buf.Buffer, url string) {
+		go func(data *buf.Buffer, url string, xmuxClient *XmuxClient) {
 			defer data.Release()
 
 			req, err := http.NewRequestWithContext(ctx, "POST", url, data)
@@ -237,7 +339,7 @@ func (c *Client) handleUpload(ctx context.Context, baseURL string, reader *io.Pi
 				reader.CloseWithError(E.New("unexpected POST status: ", resp.StatusCode))
 				return
 			}
-		}(chunk, requestURL)
+		}(chunk, requestURL, xmuxClient)
 	}
 }
 
@@ -2,8 +2,17 @@ package v2rayxhttp
 
 import (
 	"math/rand"
+	"time"
 
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json/badoption"
+)
+
+const (
+	modeAuto      = "auto"
+	modePacketUp  = "packet-up"
+	modeStreamUp  = "stream-up"
+	modeStreamOne = "stream-one"
 )
 
 // getNormalizedValue returns a random value within the range, or the From value if From == To
@@ -49,6 +58,15 @@ func getDefaultScMaxBufferedPosts() int32 {
 	return 30
 }
 
+// getDefaultScSessionIdleTimeout returns default idle session timeout (30s)
+func getDefaultScSessionIdleTimeout() time.Duration {
+	return 30 * time.Second
+}
+
+// minScSessionIdleTimeout bounds the idle session timeout, as idle sessions
+// are reaped every half of it
+const minScSessionIdleTimeout = 100 * time.Millisecond
+
 // normalizeConfig fills in default values for missing configuration options
 func normalizeConfig(opts *option.V2RayXHTTPOptions) *option.V2RayXHTTPOptions {
 	if opts == nil {
@@ -57,7 +75,7 @@ func normalizeConfig(opts *option.V2RayXHTTPOptions) *option.V2RayXHTTPOptions {
 
 	// Set default mode if not specified
 	if opts.Mode == "" {
-		opts.Mode = "auto"
+		opts.Mode = modeAuto
 	}
 
 	// Set default path if not specified
@@ -90,6 +108,13 @@ func normalizeConfig(opts *option.V2RayXHTTPOptions) *option.V2RayXHTTPOptions {
 		opts.ScMaxBufferedPosts = getDefaultScMaxBufferedPosts()
 	}
 
+	// Set default idle session timeout if not specified
+	if opts.ScSessionIdleTimeout == 0 {
+		opts.ScSessionIdleTimeout = badoption.Duration(getDefaultScSessionIdleTimeout())
+	} else if opts.ScSessionIdleTimeout > 0 && opts.ScSessionIdleTimeout.Build() < minScSessionIdleTimeout {
+		opts.ScSessionIdleTimeout = badoption.Duration(minScSessionIdleTimeout)
+	}
+
 	// Normalize Xmux config
 	if opts.Xmux == nil {
 		opts.Xmux = &option.V2RayXHTTPXmuxConfig{}
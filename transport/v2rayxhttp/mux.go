@@ -6,6 +6,7 @@ import (
 	"math"
 	"math/big"
 	"net/http"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -41,6 +42,7 @@ func (c *XmuxClient) Close() {
 
 // XmuxManager manages a pool of XmuxClients for connection reuse
 type XmuxManager struct {
+	access      sync.Mutex
 	config      *option.V2RayXHTTPXmuxConfig
 	logger      logger.ContextLogger
 	concurrency int32
@@ -94,6 +96,9 @@ func (m *XmuxManager) newXmuxClient() *XmuxClient {
 
 // GetXmuxClient retrieves or creates an XmuxClient from the pool
 func (m *XmuxManager) GetXmuxClient(ctx context.Context) *XmuxClient {
+	m.access.Lock()
+	defer m.access.Unlock()
+
 	// Clean up invalid connections
 	for i := 0; i < len(m.xmuxClients); {
 		xmuxClient := m.xmuxClients[i]
@@ -162,6 +167,8 @@ func (m *XmuxManager) GetXmuxClient(ctx context.Context) *XmuxClient {
 
 // Close closes all connections in the pool
 func (m *XmuxManager) Close() {
+	m.access.Lock()
+	defer m.access.Unlock()
 	for _, client := range m.xmuxClients {
 		client.Close()
 	}
@@ -2,6 +2,7 @@ package v2rayxhttp
 
 import (
 	"context"
+	"errors"
 	"io"
 	"net"
 	"net/http"
@@ -16,6 +17,7 @@ import (
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/logger"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
@@ -25,7 +27,7 @@ import (
 	"golang.org/x/net/http2/h2c"
 )
 
-var _ adapter.V2RayServerTransport = (*Server)(nil)
+var _ adapter.V2RayHTTPServerTransport = (*Server)(nil)
 
 type Server struct {
 	ctx            context.Context
@@ -42,13 +44,16 @@ type Server struct {
 
 func NewServer(ctx context.Context, logger logger.ContextLogger, options option.V2RayXHTTPOptions, tlsConfig tls.ServerConfig, handler adapter.V2RayServerTransportHandler) (*Server, error) {
 	options = *normalizeConfig(&options)
+	if options.ScSessionIdleTimeout < 0 {
+		return nil, E.New("negative sc_session_idle_timeout")
+	}
 
 	server := &Server{
 		ctx:            ctx,
 		logger:         logger,
 		tlsConfig:      tlsConfig,
 		handler:        handler,
-		sessionManager: newSessionManager(),
+		sessionManager: newSessionManager(ctx, int(options.ScMaxIdleSessions), int(options.ScMaxBufferedPosts), options.ScSessionIdleTimeout.Build()),
 		config:         &options,
 		h2Server:       &http2.Server{},
 	}
@@ -118,8 +123,8 @@ func (s *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
-	// Handle GET request (download)
-	if request.Method == "GET" {
+	// Handle GET request (download), or POST request without session (stream-one)
+	if request.Method == "GET" || request.Method == "POST" {
 		s.handleDownload(writer, request, sessionID, remoteAddr)
 		return
 	}
@@ -131,22 +136,38 @@ func (s *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 
 func (s *Server) handleUpload(writer http.ResponseWriter, request *http.Request, sessionID string, parts []string, remoteAddr M.Socksaddr) {
 	// Get or create session
-	session := s.sessionManager.getOrCreateSession(sessionID, int(s.config.ScMaxBufferedPosts))
+	session, err := s.sessionManager.getOrCreateSession(sessionID)
+	if err != nil {
+		s.logger.WarnContext(s.ctx, "xhttp: failed to create session", "sessionID", sessionID, "error", err)
+		writer.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+
+	// Limit concurrent upload requests of the session
+	uploads := session.uploads.Add(1)
+	defer session.uploads.Add(-1)
+	if s.config.ScMaxConcurrentUploads > 0 && uploads > s.config.ScMaxConcurrentUploads {
+		s.logger.WarnContext(s.ctx, "xhttp: too many concurrent uploads", "sessionID", sessionID, "uploads", uploads)
+		writer.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+
+	// Without a sequence number, the request body is the upload stream (stream-up mode)
+	if len(parts) < 2 || parts[1] == "" {
+		s.handleStreamUpload(writer, request, session)
+		return
+	}
 
 	// Extract sequence number from path
-	seq := uint64(0)
-	if len(parts) > 1 && parts[1] != "" {
-		var err error
-		seq, err = strconv.ParseUint(parts[1], 10, 64)
-		if err != nil {
-			s.logger.ErrorContext(s.ctx, "xhttp: invalid sequence number", "seq", parts[1], "error", err)
-			writer.WriteHeader(http.StatusBadRequest)
-			return
-		}
+	seq, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		s.logger.ErrorContext(s.ctx, "xhttp: invalid sequence number", "seq", parts[1], "error", err)
+		writer.WriteHeader(http.StatusBadRequest)
+		return
 	}
 
 	// Read payload from request body
-	maxBytes := int64(getNormalizedValue(s.config.ScMaxEachPostBytes))
+	maxBytes := int64(s.config.ScMaxEachPostBytes.To)
 	payload, err := io.ReadAll(io.LimitReader(request.Body, maxBytes+1))
 	if err != nil {
 		s.logger.ErrorContext(s.ctx, "xhttp: failed to read upload payload", "error", err)
@@ -165,55 +186,67 @@ func (s *Server) handleUpload(writer http.ResponseWriter, request *http.Request,
 		Payload: payload,
 		Seq:     seq,
 	})
-
 	if err != nil {
 		s.logger.ErrorContext(s.ctx, "xhttp: failed to push packet", "error", err)
-		writer.WriteHeader(http.StatusInternalServerError)
+		if errors.Is(err, errUploadBufferFull) {
+			writer.WriteHeader(http.StatusTooManyRequests)
+		} else {
+			writer.WriteHeader(http.StatusInternalServerError)
+		}
 		return
 	}
 
 	writer.WriteHeader(http.StatusOK)
 }
 
-func (s *Server) handleDownload(writer http.ResponseWriter, request *http.Request, sessionID string, remoteAddr M.Socksaddr) {
-	var session *httpSession
-	if sessionID != "" {
-		// Get existing session
-		var ok bool
-		session, ok = s.sessionManager.getSession(sessionID)
-		if !ok {
-			s.logger.ErrorContext(s.ctx, "xhttp: session not found", "sessionID", sessionID)
-			writer.WriteHeader(http.StatusNotFound)
-			return
-		}
-
-		// Mark session as fully connected
-		close(session.isFullyConnected)
+// handleStreamUpload passes the request body to the session as its upload
+// stream, and keeps the request open until the session ends
+func (s *Server) handleStreamUpload(writer http.ResponseWriter, request *http.Request, session *httpSession) {
+	// HTTP/1.1 closes the request body once the response is written otherwise
+	http.NewResponseController(writer).EnableFullDuplex()
 
-		// Session will be deleted when connection closes
-		defer s.sessionManager.deleteSession(sessionID)
+	err := session.uploadQueue.Push(Packet{
+		Reader: request.Body,
+	})
+	if err != nil {
+		s.logger.ErrorContext(s.ctx, "xhttp: failed to push upload stream", "error", err)
+		writer.WriteHeader(http.StatusConflict)
+		return
 	}
 
-	// Set response headers
-	writer.Header().Set("X-Accel-Buffering", "no")
-	writer.Header().Set("Cache-Control", "no-store")
+	s.writeResponseHeader(writer)
 
-	if !s.config.NoGRPCHeader {
-		writer.Header().Set("Content-Type", "text/event-stream")
+	select {
+	case <-request.Context().Done():
+	case <-session.done:
 	}
+}
 
-	// Add custom headers
-	for key, values := range s.config.Headers.Build() {
-		for _, value := range values {
-			writer.Header().Set(key, value)
+func (s *Server) handleDownload(writer http.ResponseWriter, request *http.Request, sessionID string, remoteAddr M.Socksaddr) {
+	var session *httpSession
+	if sessionID != "" {
+		// Get or create the session, uploads may arrive later
+		var err error
+		session, err = s.sessionManager.connectSession(sessionID)
+		if err != nil {
+			s.logger.ErrorContext(s.ctx, "xhttp: failed to connect session", "sessionID", sessionID, "error", err)
+			if errors.Is(err, errSessionConnected) {
+				writer.WriteHeader(http.StatusConflict)
+			} else {
+				writer.WriteHeader(http.StatusTooManyRequests)
+			}
+			return
 		}
-	}
 
-	writer.WriteHeader(http.StatusOK)
-	if flusher, ok := writer.(http.Flusher); ok {
-		flusher.Flush()
+		// Session will be deleted when connection closes
+		defer s.sessionManager.deleteSession(sessionID, session)
+	} else {
+		// The request body is the upload stream (stream-one mode)
+		http.NewResponseController(writer).EnableFullDuplex()
 	}
 
+	s.writeResponseHeader(writer)
+
 	// Create HTTP server connection wrapper
 	httpSC := &httpServerConn{
 		ResponseWriter: writer,
@@ -246,6 +279,34 @@ func (s *Server) handleDownload(writer http.ResponseWriter, request *http.Reques
 	httpSC.Close()
 }
 
+// writeResponseHeader writes and flushes the headers of a streaming response
+func (s *Server) writeResponseHeader(writer http.ResponseWriter) {
+	writer.Header().Set("X-Accel-Buffering", "no")
+	writer.Header().Set("Cache-Control", "no-store")
+
+	if !s.config.NoGRPCHeader {
+		writer.Header().Set("Content-Type", "text/event-stream")
+	}
+
+	// Add custom headers
+	for key, values := range s.config.Headers.Build() {
+		for _, value := range values {
+			writer.Header().Set(key, value)
+		}
+	}
+
+	writer.WriteHeader(http.StatusOK)
+	if flusher, ok := writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// SupportsHTTPVersion reports true for HTTP/2 and HTTP/3, xhttp streams are
+// plain request and response bodies
+func (s *Server) SupportsHTTPVersion(protoMajor int) bool {
+	return protoMajor == 2 || protoMajor == 3
+}
+
 func (s *Server) Network() []string {
 	return []string{N.NetworkTCP}
 }
@@ -271,6 +332,7 @@ func (s *Server) ServePacket(listener net.PacketConn) error {
 }
 
 func (s *Server) Close() error {
+	s.sessionManager.Close()
 	return common.Close(common.PtrOrNil(s.httpServer))
 }
 
@@ -0,0 +1,207 @@
+package v2rayxhttp
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json/badoption"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+type echoHandler struct{}
+
+func (h *echoHandler) NewConnectionEx(ctx context.Context, conn net.Conn, source M.Socksaddr, destination M.Socksaddr, onClose N.CloseHandlerFunc) {
+	io.Copy(conn, conn)
+	conn.Close()
+}
+
+func startTestServer(t *testing.T, options option.V2RayXHTTPOptions) (*Server, M.Socksaddr) {
+	server, err := NewServer(context.Background(), logger.NOP(), options, nil, &echoHandler{})
+	require.NoError(t, err)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go server.Serve(listener)
+	t.Cleanup(func() {
+		server.Close()
+	})
+	return server, M.SocksaddrFromNet(listener.Addr())
+}
+
+func newTestClient(t *testing.T, serverAddr M.Socksaddr, mode string) *Client {
+	client, err := NewClient(context.Background(), N.SystemDialer, serverAddr, option.V2RayXHTTPOptions{
+		Mode:                 mode,
+		ScMaxEachPostBytes:   &option.V2RayXHTTPRangeConfig{From: 4096, To: 4096},
+		ScMinPostsIntervalMs: &option.V2RayXHTTPRangeConfig{From: 5, To: 5},
+	}, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		client.Close()
+	})
+	return client
+}
+
+func testEcho(t *testing.T, client *Client) {
+	conn, err := client.DialContext(context.Background())
+	require.NoError(t, err)
+	defer conn.Close()
+	// Deadlines are not supported by the transport
+	timer := time.AfterFunc(10*time.Second, func() {
+		conn.Close()
+	})
+	defer timer.Stop()
+	// Larger than a POST, so that packet-up sends packets that may be reordered
+	payload := make([]byte, 64*1024)
+	_, err = rand.Read(payload)
+	require.NoError(t, err)
+	writeErr := make(chan error, 1)
+	go func() {
+		for i := 0; i < len(payload); i += 4096 {
+			_, err := conn.Write(payload[i : i+4096])
+			if err != nil {
+				writeErr <- err
+				return
+			}
+		}
+		writeErr <- nil
+	}()
+	response := make([]byte, len(payload))
+	_, err = io.ReadFull(conn, response)
+	require.NoError(t, err)
+	require.NoError(t, <-writeErr)
+	require.Equal(t, payload, response)
+}
+
+func TestModes(t *testing.T) {
+	t.Parallel()
+	_, serverAddr := startTestServer(t, option.V2RayXHTTPOptions{})
+	// A single server handles every mode at the same time
+	var wg sync.WaitGroup
+	for _, mode := range []string{modeAuto, modePacketUp, modeStreamUp, modeStreamOne} {
+		client := newTestClient(t, serverAddr, mode)
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				testEcho(t, client)
+			}()
+		}
+	}
+	wg.Wait()
+}
+
+func TestUnsupportedMode(t *testing.T) {
+	t.Parallel()
+	_, err := NewClient(context.Background(), N.SystemDialer, M.ParseSocksaddr("127.0.0.1:80"), option.V2RayXHTTPOptions{
+		Mode: "stream-down",
+	}, nil)
+	require.Error(t, err)
+}
+
+func testRequest(t *testing.T, method string, url string, body io.Reader) int {
+	request, err := http.NewRequest(method, url+"?x_padding="+string(bytes.Repeat([]byte("X"), 100)), body)
+	require.NoError(t, err)
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	response.Body.Close()
+	return response.StatusCode
+}
+
+func TestIdleSessions(t *testing.T) {
+	t.Parallel()
+	server, serverAddr := startTestServer(t, option.V2RayXHTTPOptions{
+		ScMaxIdleSessions:    2,
+		ScSessionIdleTimeout: badoption.Duration(200 * time.Millisecond),
+	})
+	baseURL := "http://" + serverAddr.String() + "/"
+	require.Equal(t, http.StatusOK, testRequest(t, "POST", baseURL+"session1/0", bytes.NewReader([]byte("data"))))
+	require.Equal(t, http.StatusOK, testRequest(t, "POST", baseURL+"session2/0", bytes.NewReader([]byte("data"))))
+	// Uploads to an existing session are not limited
+	require.Equal(t, http.StatusOK, testRequest(t, "POST", baseURL+"session1/1", bytes.NewReader([]byte("data"))))
+	require.Equal(t, http.StatusTooManyRequests, testRequest(t, "POST", baseURL+"session3/0", bytes.NewReader([]byte("data"))))
+
+	// Sessions never connected by a download request are reaped
+	require.Eventually(t, func() bool {
+		server.sessionManager.access.Lock()
+		defer server.sessionManager.access.Unlock()
+		return len(server.sessionManager.sessions) == 0 && server.sessionManager.idleSessions == 0
+	}, time.Second, 10*time.Millisecond)
+	require.Equal(t, http.StatusOK, testRequest(t, "POST", baseURL+"session3/0", bytes.NewReader([]byte("data"))))
+}
+
+func TestSessionIdleTimeout(t *testing.T) {
+	t.Parallel()
+	_, err := NewServer(context.Background(), logger.NOP(), option.V2RayXHTTPOptions{
+		ScSessionIdleTimeout: badoption.Duration(-time.Second),
+	}, nil, &echoHandler{})
+	require.Error(t, err)
+
+	// Timeouts too short for the reap interval are raised to the minimum
+	server, err := NewServer(context.Background(), logger.NOP(), option.V2RayXHTTPOptions{
+		ScSessionIdleTimeout: badoption.Duration(time.Nanosecond),
+	}, nil, &echoHandler{})
+	require.NoError(t, err)
+	defer server.Close()
+	require.Equal(t, minScSessionIdleTimeout, server.sessionManager.idleTimeout)
+}
+
+func TestConcurrentUploads(t *testing.T) {
+	t.Parallel()
+	_, serverAddr := startTestServer(t, option.V2RayXHTTPOptions{
+		ScMaxConcurrentUploads: 1,
+	})
+	baseURL := "http://" + serverAddr.String() + "/session/"
+	// A stream upload stays in flight until the session ends
+	uploadReader, uploadWriter := io.Pipe()
+	defer uploadWriter.Close()
+	go http.Post(baseURL+"?x_padding="+string(bytes.Repeat([]byte("X"), 100)), "", uploadReader)
+	require.Eventually(t, func() bool {
+		return testRequest(t, "POST", baseURL+"0", bytes.NewReader([]byte("data"))) == http.StatusTooManyRequests
+	}, time.Second, 10*time.Millisecond)
+	uploadWriter.Close()
+}
+
+func TestUploadQueueBuffer(t *testing.T) {
+	t.Parallel()
+	queue := NewUploadQueue(2)
+	// Packets out of order are buffered until the missing one arrives
+	require.NoError(t, queue.Push(Packet{Payload: []byte("world"), Seq: 1}))
+	require.NoError(t, queue.Push(Packet{Payload: []byte("hello "), Seq: 0}))
+	require.ErrorIs(t, queue.Push(Packet{Payload: []byte("!"), Seq: 2}), errUploadBufferFull)
+	posts, bufferedBytes := queue.buffered()
+	require.Equal(t, 2, posts)
+	require.Equal(t, 11, bufferedBytes)
+
+	buffer := make([]byte, 3)
+	n, err := queue.Read(buffer)
+	require.NoError(t, err)
+	require.Equal(t, "hel", string(buffer[:n]))
+	posts, bufferedBytes = queue.buffered()
+	require.Equal(t, 2, posts)
+	require.Equal(t, 8, bufferedBytes)
+
+	// Reading a whole packet frees a post
+	n, err = queue.Read(buffer)
+	require.NoError(t, err)
+	require.Equal(t, "lo ", string(buffer[:n]))
+	require.NoError(t, queue.Push(Packet{Payload: []byte("!"), Seq: 2}))
+
+	response, err := io.ReadAll(io.LimitReader(queue, 6))
+	require.NoError(t, err)
+	require.Equal(t, "world!", string(response))
+	posts, bufferedBytes = queue.buffered()
+	require.Zero(t, posts)
+	require.Zero(t, bufferedBytes)
+	require.NoError(t, queue.Close())
+}
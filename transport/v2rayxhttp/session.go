@@ -1,85 +1,161 @@
 package v2rayxhttp
 
 import (
+	"context"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+var (
+	errTooManyIdleSessions = E.New("too many idle sessions")
+	errSessionConnected    = E.New("session already connected")
 )
 
 // httpSession represents a single xhttp session with upload queue and connection status
 type httpSession struct {
-	uploadQueue      *uploadQueue
-	isFullyConnected chan struct{} // closed when GET request arrives
-}
+	uploadQueue *uploadQueue
+	uploads     atomic.Int32  // Upload requests in flight
+	done        chan struct{} // closed when the session is deleted
 
-// sessionManager manages HTTP sessions with automatic cleanup
-type sessionManager struct {
-	sessions sync.Map // map[string]*httpSession
-	mu       sync.Mutex
+	// Protected by the access of the session manager
+	connected  bool // A download request arrived
+	lastActive time.Time
 }
 
-func newSessionManager() *sessionManager {
-	return &sessionManager{}
+// sessionManager manages HTTP sessions. Sessions that are not connected by a
+// download request are idle, and reaped after idleTimeout without uploads.
+type sessionManager struct {
+	ctx              context.Context
+	cancel           context.CancelFunc
+	access           sync.Mutex
+	sessions         map[string]*httpSession
+	idleSessions     int
+	maxIdleSessions  int
+	maxBufferedPosts int
+	idleTimeout      time.Duration
 }
 
-// getOrCreateSession retrieves an existing session or creates a new one with TTL
-func (sm *sessionManager) getOrCreateSession(sessionID string, maxBufferedPosts int) *httpSession {
-	// Fast path: check if session already exists
-	if sessionAny, ok := sm.sessions.Load(sessionID); ok {
-		return sessionAny.(*httpSession)
+func newSessionManager(ctx context.Context, maxIdleSessions int, maxBufferedPosts int, idleTimeout time.Duration) *sessionManager {
+	ctx, cancel := context.WithCancel(ctx)
+	sm := &sessionManager{
+		ctx:              ctx,
+		cancel:           cancel,
+		sessions:         make(map[string]*httpSession),
+		maxIdleSessions:  maxIdleSessions,
+		maxBufferedPosts: maxBufferedPosts,
+		idleTimeout:      idleTimeout,
 	}
+	go sm.loopReap()
+	return sm
+}
 
-	// Slow path: create new session with lock
-	sm.mu.Lock()
-	defer sm.mu.Unlock()
+// getOrCreateSession retrieves an existing session or creates a new idle one
+func (sm *sessionManager) getOrCreateSession(sessionID string) (*httpSession, error) {
+	sm.access.Lock()
+	defer sm.access.Unlock()
+	return sm.getOrCreateSession0(sessionID)
+}
 
-	// Double-check after acquiring lock
-	if sessionAny, ok := sm.sessions.Load(sessionID); ok {
-		return sessionAny.(*httpSession)
+func (sm *sessionManager) getOrCreateSession0(sessionID string) (*httpSession, error) {
+	now := time.Now()
+	session, loaded := sm.sessions[sessionID]
+	if loaded {
+		session.lastActive = now
+		return session, nil
+	}
+	if sm.maxIdleSessions > 0 && sm.idleSessions >= sm.maxIdleSessions {
+		return nil, errTooManyIdleSessions
+	}
+	session = &httpSession{
+		uploadQueue: NewUploadQueue(sm.maxBufferedPosts),
+		done:        make(chan struct{}),
+		lastActive:  now,
 	}
+	sm.sessions[sessionID] = session
+	sm.idleSessions++
+	return session, nil
+}
 
-	// Create new session
-	session := &httpSession{
-		uploadQueue:      NewUploadQueue(maxBufferedPosts),
-		isFullyConnected: make(chan struct{}),
+// connectSession marks the session as connected by its download request,
+// which may arrive before any upload
+func (sm *sessionManager) connectSession(sessionID string) (*httpSession, error) {
+	sm.access.Lock()
+	defer sm.access.Unlock()
+	session, err := sm.getOrCreateSession0(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.connected {
+		return nil, errSessionConnected
 	}
+	session.connected = true
+	sm.idleSessions--
+	return session, nil
+}
 
-	sm.sessions.Store(sessionID, session)
+// deleteSession removes a session from the manager
+func (sm *sessionManager) deleteSession(sessionID string, session *httpSession) {
+	sm.access.Lock()
+	if sm.sessions[sessionID] != session {
+		sm.access.Unlock()
+		return
+	}
+	sm.deleteSession0(sessionID, session)
+	sm.access.Unlock()
+	session.uploadQueue.Close()
+}
 
-	// Start cleanup timer (30 seconds)
-	shouldDelete := make(chan struct{})
-	go func() {
-		time.Sleep(30 * time.Second)
-		close(shouldDelete)
-	}()
+func (sm *sessionManager) deleteSession0(sessionID string, session *httpSession) {
+	delete(sm.sessions, sessionID)
+	if !session.connected {
+		sm.idleSessions--
+	}
+	close(session.done)
+}
 
-	// Cleanup goroutine: delete session if not fully connected within 30s
-	go func() {
+func (sm *sessionManager) loopReap() {
+	ticker := time.NewTicker(sm.idleTimeout / 2)
+	defer ticker.Stop()
+	for {
 		select {
-		case <-shouldDelete:
-			// Session expired, delete it
-			sm.sessions.Delete(sessionID)
-			session.uploadQueue.Close()
-		case <-session.isFullyConnected:
-			// Session fully connected, keep it alive
+		case <-sm.ctx.Done():
+			return
+		case <-ticker.C:
+			sm.reap()
 		}
-	}()
-
-	return session
+	}
 }
 
-// getSession retrieves an existing session
-func (sm *sessionManager) getSession(sessionID string) (*httpSession, bool) {
-	sessionAny, ok := sm.sessions.Load(sessionID)
-	if !ok {
-		return nil, false
+// reap deletes idle sessions without uploads for longer than idleTimeout
+func (sm *sessionManager) reap() {
+	var reaped []*httpSession
+	sm.access.Lock()
+	now := time.Now()
+	for sessionID, session := range sm.sessions {
+		if !session.connected && now.Sub(session.lastActive) > sm.idleTimeout {
+			sm.deleteSession0(sessionID, session)
+			reaped = append(reaped, session)
+		}
+	}
+	sm.access.Unlock()
+	for _, session := range reaped {
+		session.uploadQueue.Close()
 	}
-	return sessionAny.(*httpSession), true
 }
 
-// deleteSession removes a session from the manager
-func (sm *sessionManager) deleteSession(sessionID string) {
-	if sessionAny, ok := sm.sessions.LoadAndDelete(sessionID); ok {
-		session := sessionAny.(*httpSession)
+func (sm *sessionManager) Close() {
+	sm.cancel()
+	var sessions []*httpSession
+	sm.access.Lock()
+	for sessionID, session := range sm.sessions {
+		sm.deleteSession0(sessionID, session)
+		sessions = append(sessions, session)
+	}
+	sm.access.Unlock()
+	for _, session := range sessions {
 		session.uploadQueue.Close()
 	}
 }
@@ -18,6 +18,8 @@ type Packet struct {
 	Seq     uint64
 }
 
+var errUploadBufferFull = E.New("upload buffer full")
+
 type uploadQueue struct {
 	reader          io.ReadCloser
 	nomore          bool
@@ -27,11 +29,18 @@ type uploadQueue struct {
 	nextSeq         uint64
 	closed          bool
 	maxPackets      int
+
+	// Posts pushed and not read yet, either in pushedPackets or in the
+	// reorder heap, and the size of their remaining payloads. Protected by
+	// writeCloseMutex.
+	bufferedPosts int
+	bufferedBytes int
 }
 
 func NewUploadQueue(maxPackets int) *uploadQueue {
 	return &uploadQueue{
-		pushedPackets: make(chan Packet, maxPackets),
+		// One more for the reader of a stream upload
+		pushedPackets: make(chan Packet, maxPackets+1),
 		heap:          uploadHeap{},
 		nextSeq:       0,
 		closed:        false,
@@ -51,11 +60,33 @@ func (h *uploadQueue) Push(p Packet) error {
 	}
 	if p.Reader != nil {
 		h.nomore = true
+	} else {
+		// Reject instead of blocking, the buffered posts never exceed the
+		// capacity of pushedPackets
+		if h.bufferedPosts >= h.maxPackets {
+			return E.Extend(errUploadBufferFull, h.bufferedPosts, " posts, ", h.bufferedBytes, " bytes")
+		}
+		h.bufferedPosts++
+		h.bufferedBytes += len(p.Payload)
 	}
 	h.pushedPackets <- p
 	return nil
 }
 
+// release accounts posts and payload bytes consumed by Read
+func (h *uploadQueue) release(posts int, bytes int) {
+	h.writeCloseMutex.Lock()
+	h.bufferedPosts -= posts
+	h.bufferedBytes -= bytes
+	h.writeCloseMutex.Unlock()
+}
+
+func (h *uploadQueue) buffered() (posts int, bytes int) {
+	h.writeCloseMutex.Lock()
+	defer h.writeCloseMutex.Unlock()
+	return h.bufferedPosts, h.bufferedBytes
+}
+
 func (h *uploadQueue) Close() error {
 	h.writeCloseMutex.Lock()
 	defer h.writeCloseMutex.Unlock()
@@ -68,7 +99,7 @@ func (h *uploadQueue) Close() error {
 			select {
 			case p := <-h.pushedPackets:
 				if p.Reader != nil {
-					h.reader = p.Reader
+					p.Reader.Close()
 				}
 			default:
 				break f
@@ -83,11 +114,14 @@ func (h *uploadQueue) Close() error {
 }
 
 func (h *uploadQueue) Read(b []byte) (int, error) {
-	if h.reader != nil {
-		return h.reader.Read(b)
+	h.writeCloseMutex.Lock()
+	reader, closed := h.reader, h.closed
+	h.writeCloseMutex.Unlock()
+	if reader != nil {
+		return reader.Read(b)
 	}
 
-	if h.closed {
+	if closed {
 		return 0, io.EOF
 	}
 
@@ -97,8 +131,15 @@ func (h *uploadQueue) Read(b []byte) (int, error) {
 			return 0, io.EOF
 		}
 		if packet.Reader != nil {
+			h.writeCloseMutex.Lock()
+			if h.closed {
+				h.writeCloseMutex.Unlock()
+				packet.Reader.Close()
+				return 0, io.EOF
+			}
 			h.reader = packet.Reader
-			return h.reader.Read(b)
+			h.writeCloseMutex.Unlock()
+			return packet.Reader.Read(b)
 		}
 		heap.Push(&h.heap, packet)
 	}
@@ -115,13 +156,21 @@ func (h *uploadQueue) Read(b []byte) (int, error) {
 				// partial read
 				packet.Payload = packet.Payload[n:]
 				heap.Push(&h.heap, packet)
+				h.release(0, n)
 			} else {
 				h.nextSeq = packet.Seq + 1
+				h.release(1, n)
 			}
 
 			return n, nil
 		}
 
+		// duplicated packet
+		if packet.Seq < h.nextSeq {
+			h.release(1, len(packet.Payload))
+			continue
+		}
+
 		// misordered packet
 		if packet.Seq > h.nextSeq {
 			if len(h.heap) > h.maxPackets {